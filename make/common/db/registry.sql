@@ -46,7 +46,7 @@ create table user (
  username varchar(32),
 # 11 bytes is reserved for marking the deleted users.
  email varchar(255),
- password varchar(40) NOT NULL,
+ password varchar(128) NOT NULL,
  realname varchar (20) NOT NULL,
  comment varchar (30),
  deleted tinyint (1) DEFAULT 0 NOT NULL,
@@ -55,6 +55,7 @@ create table user (
  sysadmin_flag tinyint (1),
  creation_time timestamp,
  update_time timestamp,
+ auth_source varchar(20) DEFAULT NULL,
  primary key (user_id),
  UNIQUE (username),
  UNIQUE (email)
@@ -67,9 +68,10 @@ insert into user (username, email, password, realname, comment, deleted, sysadmi
 create table project (
  project_id int NOT NULL AUTO_INCREMENT,
  owner_id int NOT NULL,
- # The max length of name controlled by API is 30, 
+ # The max length of name controlled by API is 200, as the projects may be
+ # nested, e.g. org/team, each segment is still limited to 30,
  # and 11 is reserved for marking the deleted project.
- name varchar (41) NOT NULL,
+ name varchar (255) NOT NULL,
  creation_time timestamp,
  update_time timestamp,
  deleted tinyint (1) DEFAULT 0 NOT NULL,
@@ -99,7 +101,8 @@ insert into project_member (project_id, user_id, role, creation_time, update_tim
 
 create table access_log (
  log_id int NOT NULL AUTO_INCREMENT,
- user_id int NOT NULL,
+ user_id int,
+ username varchar(255),
  project_id int NOT NULL,
  repo_name varchar (256), 
  repo_tag varchar (128),
@@ -172,6 +175,163 @@ create table replication_job (
  INDEX poid_uptime (policy_id, update_time)
  );
  
+create table job (
+ id int NOT NULL AUTO_INCREMENT,
+ kind varchar(64) NOT NULL,
+ status varchar(64) NOT NULL,
+ parameters text,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ INDEX kind_status (kind, status)
+ );
+
+create table job_event (
+ id int NOT NULL AUTO_INCREMENT,
+ kind varchar(64) NOT NULL,
+ job_id int NOT NULL,
+ from_state varchar(64) NOT NULL,
+ to_state varchar(64) NOT NULL,
+ message varchar(1024),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ INDEX kind_job (kind, job_id)
+ );
+
+create table replication_drift_report (
+ id int NOT NULL AUTO_INCREMENT,
+ policy_id int NOT NULL,
+ items longtext,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ INDEX drift_policy (policy_id)
+ );
+
+create table refresh_token (
+ id int NOT NULL AUTO_INCREMENT,
+ user_id int NOT NULL,
+ token_hash varchar(64) NOT NULL,
+ client_id varchar(256),
+ service varchar(256),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ last_used_time timestamp default CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ UNIQUE (token_hash),
+ INDEX refresh_token_user (user_id)
+ );
+
+create table token_audit (
+ id int NOT NULL AUTO_INCREMENT,
+ username varchar(255),
+ ip varchar(64),
+ service varchar(256),
+ scope varchar(1024),
+ granted_actions varchar(256),
+ op_time timestamp default CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ INDEX token_audit_optime (op_time)
+ );
+
+create table oidc_user (
+ id int NOT NULL AUTO_INCREMENT,
+ user_id int NOT NULL,
+ issuer varchar(255) NOT NULL,
+ subject varchar(255) NOT NULL,
+ secret_hash varchar(64),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ UNIQUE (user_id),
+ INDEX oidc_user_subject (subject)
+ );
+
+create table account_lockout (
+ id int NOT NULL AUTO_INCREMENT,
+ username varchar(255) NOT NULL,
+ failures int NOT NULL DEFAULT 0,
+ first_failure datetime NOT NULL,
+ locked_until datetime NOT NULL,
+ PRIMARY KEY (id),
+ UNIQUE (username)
+ );
+
+create table lockout_audit (
+ id int NOT NULL AUTO_INCREMENT,
+ username varchar(255),
+ operation varchar(32),
+ operator varchar(255),
+ op_time timestamp default CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ INDEX lockout_audit_optime (op_time)
+ );
+
+create table user_mfa (
+ id int NOT NULL AUTO_INCREMENT,
+ user_id int NOT NULL,
+ secret varchar(255) NOT NULL,
+ enabled tinyint(1) NOT NULL DEFAULT 0,
+ last_step bigint NOT NULL DEFAULT 0,
+ recovery_codes varchar(1024),
+ cli_secret_hash varchar(64),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ UNIQUE (user_id)
+ );
+
+create table password_history (
+ id int NOT NULL AUTO_INCREMENT,
+ user_id int NOT NULL,
+ password_hash varchar(128) NOT NULL,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ INDEX password_history_user (user_id)
+ );
+
+create table robot (
+ id int NOT NULL AUTO_INCREMENT,
+ name varchar(255) NOT NULL,
+ description varchar(1024),
+ project_id int NOT NULL,
+ secret_hash varchar(64) NOT NULL,
+ access varchar(16) NOT NULL,
+ creator_id int NOT NULL,
+ expires_at bigint NOT NULL DEFAULT 0,
+ revoked tinyint(1) NOT NULL DEFAULT 0,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ UNIQUE (name),
+ INDEX robot_project (project_id)
+ );
+
+create table access_token (
+ id int NOT NULL AUTO_INCREMENT,
+ user_id int NOT NULL,
+ name varchar(255) NOT NULL,
+ token_hash varchar(64) NOT NULL,
+ scope varchar(16) NOT NULL,
+ expires_at bigint NOT NULL DEFAULT 0,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ last_used_time timestamp default CURRENT_TIMESTAMP on update CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ UNIQUE (token_hash),
+ UNIQUE (user_id, name)
+ );
+
+create table user_session (
+ id int NOT NULL AUTO_INCREMENT,
+ user_id int NOT NULL,
+ session_hash varchar(64) NOT NULL,
+ ip varchar(64),
+ user_agent varchar(512),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ last_seen_time timestamp default CURRENT_TIMESTAMP,
+ PRIMARY KEY (id),
+ UNIQUE (session_hash),
+ INDEX user_session_user (user_id)
+ );
+
 create table properties (
  k varchar(64) NOT NULL,
  v varchar(128) NOT NULL,
@@ -182,4 +342,4 @@ CREATE TABLE IF NOT EXISTS `alembic_version` (
     `version_num` varchar(32) NOT NULL
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;
 
-insert into alembic_version values ('0.4.0');
+insert into alembic_version values ('0.4.15');
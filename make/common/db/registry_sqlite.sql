@@ -43,7 +43,7 @@ create table user (
  11 bytes is reserved for marking the deleted users.
 */
  email varchar(255),
- password varchar(40) NOT NULL,
+ password varchar(128) NOT NULL,
  realname varchar (20) NOT NULL,
  comment varchar (30),
  deleted tinyint (1) DEFAULT 0 NOT NULL,
@@ -52,6 +52,7 @@ create table user (
  sysadmin_flag tinyint (1),
  creation_time timestamp,
  update_time timestamp,
+ auth_source varchar(20) DEFAULT NULL,
  UNIQUE (username),
  UNIQUE (email)
 );
@@ -64,10 +65,11 @@ create table project (
  project_id INTEGER PRIMARY KEY,
  owner_id int NOT NULL,
 /*
- The max length of name controlled by API is 30, 
+ The max length of name controlled by API is 200, as the projects may be
+ nested, e.g. org/team, each segment is still limited to 30,
  and 11 is reserved for marking the deleted project.
 */
- name varchar (41) NOT NULL,
+ name varchar (255) NOT NULL,
  creation_time timestamp,
  update_time timestamp,
  deleted tinyint (1) DEFAULT 0 NOT NULL,
@@ -96,7 +98,8 @@ insert into project_member (project_id, user_id, role, creation_time, update_tim
 
 create table access_log (
  log_id INTEGER PRIMARY KEY,
- user_id int NOT NULL,
+ user_id int,
+ username varchar(255),
  project_id int NOT NULL,
  repo_name varchar (256), 
  repo_tag varchar (128),
@@ -168,6 +171,160 @@ create table replication_job (
 CREATE INDEX policy ON replication_job (policy_id);
 CREATE INDEX poid_uptime ON replication_job (policy_id, update_time);
  
+create table job (
+ id INTEGER PRIMARY KEY,
+ kind varchar(64) NOT NULL,
+ status varchar(64) NOT NULL,
+ parameters text,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP
+ );
+
+CREATE INDEX kind_status ON job (kind, status);
+
+create table job_event (
+ id INTEGER PRIMARY KEY,
+ kind varchar(64) NOT NULL,
+ job_id int NOT NULL,
+ from_state varchar(64) NOT NULL,
+ to_state varchar(64) NOT NULL,
+ message varchar(1024),
+ creation_time timestamp default CURRENT_TIMESTAMP
+ );
+
+CREATE INDEX kind_job ON job_event (kind, job_id);
+
+create table replication_drift_report (
+ id INTEGER PRIMARY KEY,
+ policy_id int NOT NULL,
+ items text,
+ creation_time timestamp default CURRENT_TIMESTAMP
+ );
+
+CREATE INDEX drift_policy ON replication_drift_report (policy_id);
+
+create table refresh_token (
+ id INTEGER PRIMARY KEY,
+ user_id int NOT NULL,
+ token_hash varchar(64) NOT NULL,
+ client_id varchar(256),
+ service varchar(256),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ last_used_time timestamp default CURRENT_TIMESTAMP,
+ UNIQUE (token_hash)
+ );
+
+CREATE INDEX refresh_token_user ON refresh_token (user_id);
+
+create table token_audit (
+ id INTEGER PRIMARY KEY,
+ username varchar(255),
+ ip varchar(64),
+ service varchar(256),
+ scope varchar(1024),
+ granted_actions varchar(256),
+ op_time timestamp default CURRENT_TIMESTAMP
+ );
+
+CREATE INDEX token_audit_optime ON token_audit (op_time);
+
+create table oidc_user (
+ id INTEGER PRIMARY KEY,
+ user_id int NOT NULL,
+ issuer varchar(255) NOT NULL,
+ subject varchar(255) NOT NULL,
+ secret_hash varchar(64),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP,
+ UNIQUE (user_id)
+ );
+
+CREATE INDEX oidc_user_subject ON oidc_user (subject);
+
+create table account_lockout (
+ id INTEGER PRIMARY KEY,
+ username varchar(255) NOT NULL,
+ failures int NOT NULL DEFAULT 0,
+ first_failure datetime NOT NULL,
+ locked_until datetime NOT NULL,
+ UNIQUE (username)
+ );
+
+create table lockout_audit (
+ id INTEGER PRIMARY KEY,
+ username varchar(255),
+ operation varchar(32),
+ operator varchar(255),
+ op_time timestamp default CURRENT_TIMESTAMP
+ );
+
+CREATE INDEX lockout_audit_optime ON lockout_audit (op_time);
+
+create table user_mfa (
+ id INTEGER PRIMARY KEY,
+ user_id int NOT NULL,
+ secret varchar(255) NOT NULL,
+ enabled tinyint(1) NOT NULL DEFAULT 0,
+ last_step bigint NOT NULL DEFAULT 0,
+ recovery_codes varchar(1024),
+ cli_secret_hash varchar(64),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP,
+ UNIQUE (user_id)
+ );
+
+create table password_history (
+ id INTEGER PRIMARY KEY,
+ user_id int NOT NULL,
+ password_hash varchar(128) NOT NULL,
+ creation_time timestamp default CURRENT_TIMESTAMP
+ );
+
+CREATE INDEX password_history_user ON password_history (user_id);
+
+create table robot (
+ id INTEGER PRIMARY KEY,
+ name varchar(255) NOT NULL,
+ description varchar(1024),
+ project_id int NOT NULL,
+ secret_hash varchar(64) NOT NULL,
+ access varchar(16) NOT NULL,
+ creator_id int NOT NULL,
+ expires_at bigint NOT NULL DEFAULT 0,
+ revoked tinyint(1) NOT NULL DEFAULT 0,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ update_time timestamp default CURRENT_TIMESTAMP,
+ UNIQUE (name)
+ );
+
+CREATE INDEX robot_project ON robot (project_id);
+
+create table access_token (
+ id INTEGER PRIMARY KEY,
+ user_id int NOT NULL,
+ name varchar(255) NOT NULL,
+ token_hash varchar(64) NOT NULL,
+ scope varchar(16) NOT NULL,
+ expires_at bigint NOT NULL DEFAULT 0,
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ last_used_time timestamp default CURRENT_TIMESTAMP,
+ UNIQUE (token_hash),
+ UNIQUE (user_id, name)
+ );
+
+create table user_session (
+ id INTEGER PRIMARY KEY,
+ user_id int NOT NULL,
+ session_hash varchar(64) NOT NULL,
+ ip varchar(64),
+ user_agent varchar(512),
+ creation_time timestamp default CURRENT_TIMESTAMP,
+ last_seen_time timestamp default CURRENT_TIMESTAMP,
+ UNIQUE (session_hash)
+ );
+
+CREATE INDEX user_session_user ON user_session (user_id);
+
 create table properties (
  k varchar(64) NOT NULL,
  v varchar(128) NOT NULL,
@@ -17,6 +17,7 @@ services:
     volumes:
       - /data/registry:/storage:z
       - ./common/config/registry/:/etc/registry/:z
+      - /data/ui/certs:/etc/registry/certs:z
     networks:
       - harbor
     environment:
@@ -75,6 +76,8 @@ services:
     volumes:
       - ./common/config/ui/app.conf:/etc/ui/app.conf:z
       - ./common/config/ui/private_key.pem:/etc/ui/private_key.pem:z
+      - /data/ui/keys:/etc/ui/keys:z
+      - /data/ui/certs:/etc/ui/certs:z
       - /data/secretkey:/etc/ui/key:z
       - /data/ca_download/:/etc/ui/ca/:z
     networks:
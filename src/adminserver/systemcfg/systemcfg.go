@@ -45,12 +45,15 @@ var (
 		common.LDAPSearchPwd,
 		common.MySQLPassword,
 		common.AdminInitialPassword,
+		common.OIDCClientSecret,
+		common.ProxyAuthSecret,
 	}
 
 	// all configurations need read from environment variables
 	allEnvs = map[string]interface{}{
 		common.ExtEndpoint: "EXT_ENDPOINT",
 		common.AUTHMode:    "AUTH_MODE",
+		common.AUTHChain:   "AUTH_CHAIN",
 		common.SelfRegistration: &parser{
 			env:   "SELF_REGISTRATION",
 			parse: parseStringToBool,
@@ -116,6 +119,60 @@ var (
 			env:   "WITH_NOTARY",
 			parse: parseStringToBool,
 		},
+		common.TokenAuditAccessLog: &parser{
+			env:   "TOKEN_AUDIT_ACCESS_LOG",
+			parse: parseStringToBool,
+		},
+		common.OIDCEndpoint:         "OIDC_ENDPOINT",
+		common.OIDCClientID:         "OIDC_CLIENT_ID",
+		common.OIDCClientSecret:     "OIDC_CLIENT_SECRET",
+		common.OIDCScope:            "OIDC_SCOPE",
+		common.OIDCGroupsClaim:      "OIDC_GROUPS_CLAIM",
+		common.OIDCGroupRoleMapping: "OIDC_GROUP_ROLE_MAPPING",
+		common.OIDCVerifyCert: &parser{
+			env:   "OIDC_VERIFY_CERT",
+			parse: parseStringToBool,
+		},
+		common.ProxyAuthTrustedCIDRs:     "PROXY_AUTH_TRUSTED_CIDRS",
+		common.ProxyAuthSecret:           "PROXY_AUTH_SECRET",
+		common.ProxyAuthGroupRoleMapping: "PROXY_AUTH_GROUP_ROLE_MAPPING",
+		common.ProxyAuthTokenService: &parser{
+			env:   "PROXY_AUTH_TOKEN_SERVICE",
+			parse: parseStringToBool,
+		},
+		common.LockoutThreshold: &parser{
+			env:   "LOCKOUT_THRESHOLD",
+			parse: parseStringToInt,
+		},
+		common.LockoutWindow: &parser{
+			env:   "LOCKOUT_WINDOW",
+			parse: parseStringToInt,
+		},
+		common.LockoutDuration: &parser{
+			env:   "LOCKOUT_DURATION",
+			parse: parseStringToInt,
+		},
+		common.PasswordHashIterations: &parser{
+			env:   "PASSWORD_HASH_ITERATIONS",
+			parse: parseStringToInt,
+		},
+		common.MFAEnforceAdmin: &parser{
+			env:   "MFA_ENFORCE_ADMIN",
+			parse: parseStringToBool,
+		},
+		common.PasswordMinLength: &parser{
+			env:   "PASSWORD_MIN_LENGTH",
+			parse: parseStringToInt,
+		},
+		common.PasswordRequiredClasses: "PASSWORD_REQUIRED_CLASSES",
+		common.PasswordHistory: &parser{
+			env:   "PASSWORD_HISTORY",
+			parse: parseStringToInt,
+		},
+		common.PasswordMaxAge: &parser{
+			env:   "PASSWORD_MAX_AGE",
+			parse: parseStringToInt,
+		},
 	}
 
 	// configurations need read from environment variables
@@ -18,13 +18,20 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 
 	"github.com/astaxie/beego/validation"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/ui/auth"
+	"github.com/vmware/harbor/src/ui/auth/mfa"
+	"github.com/vmware/harbor/src/ui/auth/pat"
+	"github.com/vmware/harbor/src/ui/auth/proxy"
+	"github.com/vmware/harbor/src/ui/auth/robot"
+	"github.com/vmware/harbor/src/ui/service/token"
 
 	"github.com/astaxie/beego"
 )
@@ -34,9 +41,41 @@ const (
 	maxPageSize     int64 = 500
 )
 
+var enrollmentPathRe = regexp.MustCompile(`^/api/users/(current|[0-9]+)/mfa(/[a-z_]+)?/?$`)
+
+// enrollmentAllowed returns whether the user who must enroll the second
+// factor can access the API, i.e. the APIs of the enrollment and getting the
+// current user
+func enrollmentAllowed(r *http.Request) bool {
+	if r.Method == http.MethodGet && strings.TrimSuffix(r.URL.Path, "/") == "/api/users/current" {
+		return true
+	}
+	return enrollmentPathRe.MatchString(r.URL.Path)
+}
+
+var passwordPathRe = regexp.MustCompile(`^/api/users/[0-9]+/password/?$`)
+
+// passwordChangeAllowed returns whether the user whose password has expired
+// can access the API, i.e. the APIs of changing the password and getting the
+// current user
+func passwordChangeAllowed(r *http.Request) bool {
+	if r.Method == http.MethodGet && strings.TrimSuffix(r.URL.Path, "/") == "/api/users/current" {
+		return true
+	}
+	return r.Method == http.MethodPut && passwordPathRe.MatchString(r.URL.Path)
+}
+
+// readOnlyAllowed returns whether the request can be sent with a read-only
+// personal access token
+func readOnlyAllowed(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
 // BaseAPI wraps common methods for controllers to host API
 type BaseAPI struct {
 	beego.Controller
+	// accessToken is the personal access token authenticating the request
+	accessToken *models.AccessToken
 }
 
 // Render returns nil as it won't render template
@@ -107,9 +146,28 @@ func (b *BaseAPI) ValidateUser() int {
 // It returns the user ID, whether need further verification(when the id is from session) and if the action is successful
 func (b *BaseAPI) GetUserIDForRequest() (int, bool, bool) {
 	username, password, ok := b.Ctx.Request.BasicAuth()
+	if ok && robot.IsRobot(username) {
+		// the robot accounts are not users, see Robot
+		log.Debugf("Request with the basic auth of robot account %s", username)
+		return 0, false, false
+	}
 	if ok {
+		user, t, err := pat.Authenticate(username, password)
+		if err != nil {
+			log.Errorf("Error while authenticating the personal access token of %s: %v", username, err)
+		}
+		if user != nil {
+			if t.ReadOnly() && !readOnlyAllowed(b.Ctx.Request) {
+				log.Warningf("read-only personal access token %d of %s can not be used to %s %s",
+					t.ID, username, b.Ctx.Request.Method, b.Ctx.Request.URL.Path)
+				return 0, false, false
+			}
+			// the session is not set, so the scope of the token is not extended by it
+			b.accessToken = t
+			return user.UserID, false, true
+		}
 		log.Infof("Requst with Basic Authentication header, username: %s", username)
-		user, err := auth.Login(models.AuthModel{
+		user, err = auth.LoginCLI(models.AuthModel{
 			Principal: username,
 			Password:  password,
 		})
@@ -118,14 +176,44 @@ func (b *BaseAPI) GetUserIDForRequest() (int, bool, bool) {
 			user = nil
 		}
 		if user != nil {
-			b.SetSession("userId", user.UserID)
-			b.SetSession("username", user.Username)
+			// The session is not set, as only the sessions of the web login
+			// are tracked and can be revoked.
 			// User login successfully no further check required.
 			return user.UserID, false, true
 		}
 	}
+	user, changed, err := proxy.UserFromRequest(b.Ctx.Request)
+	if err != nil {
+		log.Errorf("Error while getting the user from the proxy headers: %v", err)
+	}
+	if user != nil {
+		if changed {
+			token.InvalidateUserPermissions(user.Username)
+		}
+		return user.UserID, false, true
+	}
 	sessionUserID, ok := b.GetSession("userId").(int)
 	if ok {
+		valid, err := auth.SessionValid(b.StartSession().SessionID(), sessionUserID)
+		if err != nil {
+			log.Errorf("Error while checking the session of user %d: %v", sessionUserID, err)
+			return 0, false, false
+		}
+		if !valid {
+			log.Infof("the session of user %d is revoked or not tracked, destroying it", sessionUserID)
+			b.DestroySession()
+			return 0, false, false
+		}
+		if enrolling, _ := b.GetSession(mfa.SessionKey).(bool); enrolling &&
+			!enrollmentAllowed(b.Ctx.Request) {
+			log.Debugf("user %d must enroll the second factor before accessing %s", sessionUserID, b.Ctx.Request.URL.Path)
+			return 0, false, false
+		}
+		if expired, _ := b.GetSession(auth.PasswordExpiredSessionKey).(bool); expired &&
+			!passwordChangeAllowed(b.Ctx.Request) {
+			log.Debugf("user %d must change the expired password before accessing %s", sessionUserID, b.Ctx.Request.URL.Path)
+			return 0, false, false
+		}
 		// The ID is from session
 		return sessionUserID, true, true
 	}
@@ -133,6 +221,29 @@ func (b *BaseAPI) GetUserIDForRequest() (int, bool, bool) {
 	return 0, false, false
 }
 
+// AccessToken returns the personal access token which authenticates the
+// request, it is set by GetUserIDForRequest. Nil is returned if the request
+// is not authenticated by a token.
+func (b *BaseAPI) AccessToken() *models.AccessToken {
+	return b.accessToken
+}
+
+// Robot returns the robot account which authenticates the request with the
+// basic auth, nil is returned if the request is not sent by a valid robot
+// account. The robot accounts can only access the APIs which check it.
+func (b *BaseAPI) Robot() *models.Robot {
+	name, secret, ok := b.Ctx.Request.BasicAuth()
+	if !ok || !robot.IsRobot(name) {
+		return nil
+	}
+	r, err := robot.Authenticate(name, secret)
+	if err != nil {
+		log.Errorf("Error while authenticating robot account %s: %v", name, err)
+		return nil
+	}
+	return r
+}
+
 // Redirect does redirection to resource URI with http header status code.
 func (b *BaseAPI) Redirect(statusCode int, resouceID string) {
 	requestURI := b.Ctx.Request.RequestURI
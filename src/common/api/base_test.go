@@ -12,3 +12,77 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEnrollmentAllowed(t *testing.T) {
+	cases := []struct {
+		method  string
+		path    string
+		allowed bool
+	}{
+		{http.MethodGet, "/api/users/current", true},
+		{http.MethodPut, "/api/users/current", false},
+		{http.MethodGet, "/api/users/current/mfa", true},
+		{http.MethodPost, "/api/users/3/mfa", true},
+		{http.MethodPost, "/api/users/3/mfa/activate", true},
+		{http.MethodGet, "/api/users/3", false},
+		{http.MethodGet, "/api/projects", false},
+		{http.MethodGet, "/api/users/3/mfa/../../../projects", false},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, c.path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if allowed := enrollmentAllowed(req); allowed != c.allowed {
+			t.Errorf("unexpected result for %s %s: %t != %t", c.method, c.path, allowed, c.allowed)
+		}
+	}
+}
+
+func TestPasswordChangeAllowed(t *testing.T) {
+	cases := []struct {
+		method  string
+		path    string
+		allowed bool
+	}{
+		{http.MethodGet, "/api/users/current", true},
+		{http.MethodPut, "/api/users/3/password", true},
+		{http.MethodGet, "/api/users/3/password", false},
+		{http.MethodPut, "/api/users/current/password", false},
+		{http.MethodGet, "/api/projects", false},
+		{http.MethodPut, "/api/users/3/password/../../4/sysadmin", false},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(c.method, c.path, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if allowed := passwordChangeAllowed(req); allowed != c.allowed {
+			t.Errorf("unexpected result for %s %s: %t != %t", c.method, c.path, allowed, c.allowed)
+		}
+	}
+}
+
+func TestReadOnlyAllowed(t *testing.T) {
+	cases := map[string]bool{
+		http.MethodGet:    true,
+		http.MethodHead:   true,
+		http.MethodPost:   false,
+		http.MethodPut:    false,
+		http.MethodDelete: false,
+	}
+	for method, allowed := range cases {
+		req, err := http.NewRequest(method, "/api/projects", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		if readOnlyAllowed(req) != allowed {
+			t.Errorf("unexpected result for %s: %t != %t", method, !allowed, allowed)
+		}
+	}
+}
@@ -18,6 +18,8 @@ package common
 const (
 	DBAuth              = "db_auth"
 	LDAPAuth            = "ldap_auth"
+	OIDCAuth            = "oidc_auth"
+	ProxyAuth           = "proxy_auth"
 	ProCrtRestrEveryone = "everyone"
 	ProCrtRestrAdmOnly  = "adminonly"
 	LDAPScopeBase       = "1"
@@ -60,4 +62,26 @@ const (
 	AdminInitialPassword       = "admin_initial_password"
 	AdmiralEndpoint            = "admiral_url"
 	WithNotary                 = "with_notary"
+	TokenAuditAccessLog        = "token_audit_access_log"
+	AUTHChain                  = "auth_chain"
+	OIDCEndpoint               = "oidc_endpoint"
+	OIDCClientID               = "oidc_client_id"
+	OIDCClientSecret           = "oidc_client_secret"
+	OIDCScope                  = "oidc_scope"
+	OIDCGroupsClaim            = "oidc_groups_claim"
+	OIDCGroupRoleMapping       = "oidc_group_role_mapping"
+	OIDCVerifyCert             = "oidc_verify_cert"
+	ProxyAuthTrustedCIDRs      = "proxy_auth_trusted_cidrs"
+	ProxyAuthSecret            = "proxy_auth_secret"
+	ProxyAuthGroupRoleMapping  = "proxy_auth_group_role_mapping"
+	ProxyAuthTokenService      = "proxy_auth_token_service"
+	LockoutThreshold           = "lockout_threshold"
+	LockoutWindow              = "lockout_window"
+	LockoutDuration            = "lockout_duration"
+	PasswordHashIterations     = "password_hash_iterations"
+	MFAEnforceAdmin            = "mfa_enforce_admin"
+	PasswordMinLength          = "password_min_length"
+	PasswordRequiredClasses    = "password_required_classes"
+	PasswordHistory            = "password_history"
+	PasswordMaxAge             = "password_max_age"
 )
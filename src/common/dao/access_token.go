@@ -0,0 +1,85 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddAccessToken persists a personal access token, the TokenHash must be set
+func AddAccessToken(token *models.AccessToken) (int64, error) {
+	now := time.Now()
+	token.CreationTime = now
+	token.LastUsedTime = now
+	return GetOrmer().Insert(token)
+}
+
+// GetAccessTokenByHash returns the personal access token whose hash is the
+// one provided, nil is returned if it does not exist
+func GetAccessTokenByHash(hash string) (*models.AccessToken, error) {
+	token := &models.AccessToken{
+		TokenHash: hash,
+	}
+	err := GetOrmer().Read(token, "TokenHash")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// AccessTokenNameExists returns whether the user has a personal access token
+// with the name
+func AccessTokenNameExists(userID int, name string) bool {
+	return GetOrmer().QueryTable(new(models.AccessToken)).
+		Filter("UserID", userID).Filter("Name", name).Exist()
+}
+
+// UpdateAccessTokenLastUsedTime updates the last used time of the personal
+// access token to now
+func UpdateAccessTokenLastUsedTime(id int64) error {
+	token := &models.AccessToken{
+		ID:           id,
+		LastUsedTime: time.Now(),
+	}
+	_, err := GetOrmer().Update(token, "LastUsedTime")
+	return err
+}
+
+// GetAccessTokensByUser returns the personal access tokens of the user
+func GetAccessTokensByUser(userID int) ([]*models.AccessToken, error) {
+	tokens := []*models.AccessToken{}
+	_, err := GetOrmer().QueryTable(new(models.AccessToken)).
+		Filter("UserID", userID).OrderBy("-CreationTime").All(&tokens)
+	return tokens, err
+}
+
+// DeleteAccessToken revokes the personal access token of the user, the
+// number of tokens deleted is returned
+func DeleteAccessToken(userID int, id int64) (int64, error) {
+	return GetOrmer().QueryTable(new(models.AccessToken)).
+		Filter("UserID", userID).Filter("ID", id).Delete()
+}
+
+// DeleteAccessTokensByUser revokes all personal access tokens of the user
+func DeleteAccessTokensByUser(userID int) (int64, error) {
+	return GetOrmer().QueryTable(new(models.AccessToken)).
+		Filter("UserID", userID).Delete()
+}
@@ -53,7 +53,7 @@ func GetTotalOfAccessLogs(query models.AccessLog) (int64, error) {
 		sql = `select count(*) from access_log al 
 			left join tenx_users u 
 			on al.user_id = u.user_id 
-			where al.project_id = ? and u.user_name like ? `
+			where al.project_id = ? and coalesce(u.user_name, al.username) like ? `
 		queryParam = append(queryParam, "%"+escape(query.Username)+"%")
 	}
 
@@ -71,7 +71,7 @@ func GetAccessLogs(query models.AccessLog, limit, offset int64) ([]models.Access
 	o := GetOrmer()
 
 	queryParam := []interface{}{}
-	sql := `select al.log_id, u.user_name as username, al.repo_name, 
+	sql := `select al.log_id, coalesce(u.user_name, al.username) as username, al.repo_name, 
 			al.repo_tag, al.operation, al.op_time 
 		from access_log al 
 		left join tenx_users u 
@@ -80,7 +80,7 @@ func GetAccessLogs(query models.AccessLog, limit, offset int64) ([]models.Access
 	queryParam = append(queryParam, query.ProjectID)
 
 	if query.Username != "" {
-		sql += ` and u.user_name like ? `
+		sql += ` and coalesce(u.user_name, al.username) like ? `
 		queryParam = append(queryParam, "%"+escape(query.Username)+"%")
 	}
 
@@ -144,10 +144,11 @@ func genFilterClauses(query models.AccessLog, queryParam *[]interface{}) string
 // AccessLog ...
 func AccessLog(username, projectName, repoName, repoTag, action string) error {
 	o := GetOrmer()
-	sql := "insert into  access_log (user_id, project_id, repo_name, repo_tag, operation, op_time) " +
-		"select (select user_id as user_id from tenx_users where user_name=?), " +
+	// the name is recorded as well, as the operators such as robot accounts are not users
+	sql := "insert into  access_log (user_id, username, project_id, repo_name, repo_tag, operation, op_time) " +
+		"select (select user_id as user_id from tenx_users where user_name=?), ?, " +
 		"(select project_id as project_id from project where name=?), ?, ?, ?, ? "
-	_, err := o.Raw(sql, username, projectName, repoName, repoTag, action, time.Now()).Exec()
+	_, err := o.Raw(sql, username, username, projectName, repoName, repoTag, action, time.Now()).Exec()
 
 	if err != nil {
 		log.Errorf("error in AccessLog: %v ", err)
@@ -165,9 +166,10 @@ func GetRecentLogs(userID, linesNum int, startTime, endTime string) ([]models.Ac
 	}
 
 	queryParam := []interface{}{}
-	sql := `select log_id, access_log.user_id, project_id, repo_name, repo_tag, GUID, operation, op_time, user_name 
+	sql := `select log_id, access_log.user_id, project_id, repo_name, repo_tag, GUID, operation, op_time, 
+			coalesce(user_name, access_log.username) as user_name 
 		from access_log 
-		join tenx_users 
+		left join tenx_users 
 		on access_log.user_id=tenx_users.user_id `
 
 	hasWhere := false
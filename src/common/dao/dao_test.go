@@ -17,6 +17,7 @@ package dao
 import (
 	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -364,6 +365,42 @@ func TestLoginByUserName(t *testing.T) {
 	}
 }
 
+func TestLoginUpgradesLegacyHash(t *testing.T) {
+	user, err := GetUser(models.User{Username: username})
+	if err != nil || user == nil {
+		t.Fatalf("failed to get user %s: %v", username, err)
+	}
+	// the MD5 of the password
+	if _, err = GetOrmer().Raw(`update tenx_users set password = ? where user_id = ?`,
+		"1a64a010767f0725fb52111b0a9e9f84", user.UserID).Exec(); err != nil {
+		t.Fatalf("failed to set the legacy hash: %v", err)
+	}
+
+	loginUser, err := LoginByDb(models.AuthModel{
+		Principal: username,
+		Password:  password,
+	})
+	if err != nil || loginUser == nil {
+		t.Fatalf("failed to login with the legacy hash: %v", err)
+	}
+
+	hash, _, err := getPasswordHash(user.UserID)
+	if err != nil {
+		t.Fatalf("failed to get the password hash: %v", err)
+	}
+	if !strings.HasPrefix(hash, "pbkdf2_sha256$") {
+		t.Errorf("the legacy hash should be upgraded: %s", hash)
+	}
+
+	loginUser, err = LoginByDb(models.AuthModel{
+		Principal: username,
+		Password:  password,
+	})
+	if err != nil || loginUser == nil {
+		t.Errorf("failed to login with the upgraded hash: %v", err)
+	}
+}
+
 func TestLoginByEmail(t *testing.T) {
 
 	userQuery := models.User{
@@ -1668,3 +1705,576 @@ func TestDeleteRepository(t *testing.T) {
 		t.Errorf("repository is not nil after deletion, repository: %+v", repository)
 	}
 }
+
+func TestDriftReport(t *testing.T) {
+	id, err := AddDriftReport(models.DriftReport{
+		PolicyID: policyID,
+		ItemList: []*models.DriftItem{
+			&models.DriftItem{
+				Repository:  "library/ubuntu",
+				Tag:         "14.04",
+				Type:        models.DriftMissingTag,
+				LocalDigest: "sha256:1",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("failed to add drift report: %v", err)
+	}
+
+	report, err := GetDriftReport(id)
+	if err != nil {
+		t.Fatalf("failed to get drift report %d: %v", id, err)
+	}
+	if report == nil || len(report.ItemList) != 1 || report.ItemList[0].Tag != "14.04" {
+		t.Fatalf("unexpected drift report: %+v", report)
+	}
+
+	reports, total, err := GetDriftReportsByPolicy(policyID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to get drift reports of policy %d: %v", policyID, err)
+	}
+	if total != 1 || len(reports) != 1 || reports[0].ID != id {
+		t.Fatalf("unexpected drift reports: total %d, %+v", total, reports)
+	}
+}
+
+func TestJobEvents(t *testing.T) {
+	var jid int64 = 10000
+	for _, e := range []models.JobEvent{
+		models.JobEvent{Kind: models.JobKindReplication, JobID: jid, FromState: models.JobPending, ToState: models.JobRunning},
+		models.JobEvent{Kind: models.JobKindReplication, JobID: jid, FromState: models.JobRunning, ToState: "initialize", Message: "timeout"},
+		models.JobEvent{Kind: "gc", JobID: jid, FromState: models.JobPending, ToState: models.JobRunning},
+	} {
+		if _, err := AddJobEvent(e); err != nil {
+			t.Fatalf("failed to add job event: %v", err)
+		}
+	}
+
+	events, err := GetJobEvents(models.JobKindReplication, jid)
+	if err != nil {
+		t.Fatalf("failed to get events of job %d: %v", jid, err)
+	}
+	if len(events) != 2 || events[0].ToState != models.JobRunning || events[1].Message != "timeout" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+
+	if err = DeleteJobEvents(models.JobKindReplication, jid); err != nil {
+		t.Fatalf("failed to delete events of job %d: %v", jid, err)
+	}
+	events, err = GetJobEvents(models.JobKindReplication, jid)
+	if err != nil {
+		t.Fatalf("failed to get events of job %d: %v", jid, err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("unexpected events after deletion: %+v", events)
+	}
+
+	events, err = GetJobEvents("gc", jid)
+	if err != nil {
+		t.Fatalf("failed to get events of job %d: %v", jid, err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("events of other kinds should not be deleted: %+v", events)
+	}
+	if err = DeleteJobEvents("gc", jid); err != nil {
+		t.Fatalf("failed to delete events of job %d: %v", jid, err)
+	}
+}
+
+func TestGenericJob(t *testing.T) {
+	id, err := AddJob(models.Job{
+		Kind:       "gc",
+		Parameters: `{"dry_run":true}`,
+	})
+	if err != nil {
+		t.Fatalf("failed to add job: %v", err)
+	}
+
+	job, err := GetJob(id)
+	if err != nil {
+		t.Fatalf("failed to get job %d: %v", id, err)
+	}
+	if job == nil || job.Kind != "gc" || job.Status != models.JobPending {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	if err = UpdateJobStatus(id, models.JobRunning); err != nil {
+		t.Fatalf("failed to update status of job %d: %v", id, err)
+	}
+	if err = ResetRunningGenericJobs(); err != nil {
+		t.Fatalf("failed to reset running jobs: %v", err)
+	}
+
+	jobs, err := GetJobsByStatus(models.JobPending)
+	if err != nil {
+		t.Fatalf("failed to get jobs by status: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != id {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+
+	jobs, total, err := FilterJobs("gc", "", 10, 0)
+	if err != nil {
+		t.Fatalf("failed to filter jobs: %v", err)
+	}
+	if total != 1 || len(jobs) != 1 {
+		t.Fatalf("unexpected jobs: total %d, %+v", total, jobs)
+	}
+
+	if err = UpdateJobStatus(100000, models.JobRunning); err == nil {
+		t.Errorf("expected error when updating status of nonexistent job")
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	token := &models.RefreshToken{
+		UserID:    1,
+		TokenHash: "hash-of-refresh-token",
+		ClientID:  "docker",
+		Service:   "harbor-registry",
+	}
+	id, err := AddRefreshToken(token)
+	if err != nil {
+		t.Fatalf("failed to add refresh token: %v", err)
+	}
+
+	rt, err := GetRefreshTokenByHash("hash-of-refresh-token")
+	if err != nil {
+		t.Fatalf("failed to get refresh token: %v", err)
+	}
+	if rt == nil || rt.ID != id || rt.UserID != 1 {
+		t.Fatalf("unexpected refresh token: %+v", rt)
+	}
+
+	if err = UpdateRefreshTokenLastUsedTime(id); err != nil {
+		t.Fatalf("failed to update last used time of refresh token: %v", err)
+	}
+
+	tokens, err := GetRefreshTokensByUser(1)
+	if err != nil {
+		t.Fatalf("failed to get refresh tokens: %v", err)
+	}
+	if len(tokens) != 1 {
+		t.Fatalf("unexpected length of refresh tokens: %d != 1", len(tokens))
+	}
+
+	n, err := DeleteRefreshTokensByUser(1)
+	if err != nil {
+		t.Fatalf("failed to delete refresh tokens: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("unexpected number of deleted refresh tokens: %d != 1", n)
+	}
+
+	rt, err = GetRefreshTokenByHash("hash-of-refresh-token")
+	if err != nil {
+		t.Fatalf("failed to get refresh token: %v", err)
+	}
+	if rt != nil {
+		t.Errorf("the refresh token should be revoked")
+	}
+}
+
+func TestTokenAudit(t *testing.T) {
+	audit := &models.TokenAudit{
+		Username:       "tester",
+		IP:             "10.0.0.1",
+		Service:        "harbor-registry",
+		Scope:          "repository:library/ubuntu:pull,push",
+		GrantedActions: "pull",
+	}
+	id, err := AddTokenAudit(audit)
+	if err != nil {
+		t.Fatalf("failed to add token audit: %v", err)
+	}
+	defer GetOrmer().QueryTable(new(models.TokenAudit)).Filter("ID", id).Delete()
+
+	audits, total, err := FilterTokenAudits("test", "harbor-registry", nil, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to filter token audits: %v", err)
+	}
+	if total != 1 || len(audits) != 1 || audits[0].Scope != audit.Scope {
+		t.Fatalf("unexpected token audits: %d %+v", total, audits)
+	}
+
+	_, total, err = FilterTokenAudits("", "harbor-notary", nil, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to filter token audits: %v", err)
+	}
+	if total != 0 {
+		t.Errorf("unexpected total of token audits: %d != 0", total)
+	}
+}
+
+func TestNestedProjects(t *testing.T) {
+	parent := models.Project{
+		OwnerID: currentUser.UserID,
+		Name:    "nested",
+	}
+	parentID, err := AddProject(parent)
+	if err != nil {
+		t.Fatalf("failed to add project %s: %v", parent.Name, err)
+	}
+	defer DeleteProject(parentID)
+
+	child := models.Project{
+		OwnerID: currentUser.UserID,
+		Name:    "nested/team",
+	}
+	childID, err := AddProject(child)
+	if err != nil {
+		t.Fatalf("failed to add project %s: %v", child.Name, err)
+	}
+	defer DeleteProject(childID)
+
+	project, rest := ParseRepository("nested/team/app")
+	if project != "nested/team" || rest != "app" {
+		t.Errorf("unexpected result of parsing repository: %s %s", project, rest)
+	}
+	project, rest = ParseRepository("nested/app")
+	if project != "nested" || rest != "app" {
+		t.Errorf("unexpected result of parsing repository: %s %s", project, rest)
+	}
+
+	children, err := GetChildProjects("nested")
+	if err != nil {
+		t.Fatalf("failed to get child projects: %v", err)
+	}
+	if len(children) != 1 || children[0].Name != "nested/team" {
+		t.Errorf("unexpected child projects: %+v", children)
+	}
+
+	// the role on the parent is inherited once the user is not a member of
+	// the child directly
+	if err = DeleteProjectMember(childID, currentUser.UserID); err != nil {
+		t.Fatalf("failed to delete project member: %v", err)
+	}
+	permission, err := GetPermission(currentUser.Username, "nested/team")
+	if err != nil {
+		t.Fatalf("failed to get permission: %v", err)
+	}
+	if permission != "MDRWS" {
+		t.Errorf("unexpected permission: %s != MDRWS", permission)
+	}
+	roles, err := GetUserProjectRolesWithInheritance(currentUser.UserID, childID)
+	if err != nil {
+		t.Fatalf("failed to get roles: %v", err)
+	}
+	if len(roles) != 1 || roles[0].RoleID != models.PROJECTADMIN {
+		t.Errorf("unexpected roles: %+v", roles)
+	}
+}
+
+func TestOIDCUser(t *testing.T) {
+	oidcUser := &models.OIDCUser{
+		UserID:  currentUser.UserID,
+		Issuer:  "https://sso.example.com",
+		Subject: "subject-1",
+	}
+	if _, err := AddOIDCUser(oidcUser); err != nil {
+		t.Fatalf("failed to add OIDC user: %v", err)
+	}
+	defer DeleteOIDCUser(currentUser.UserID)
+
+	u, err := GetOIDCUserBySubject("https://sso.example.com", "subject-1")
+	if err != nil {
+		t.Fatalf("failed to get OIDC user by subject: %v", err)
+	}
+	if u == nil || u.UserID != currentUser.UserID {
+		t.Fatalf("unexpected OIDC user: %+v", u)
+	}
+
+	u, err = GetOIDCUserBySubject("https://another.example.com", "subject-1")
+	if err != nil {
+		t.Fatalf("failed to get OIDC user by subject: %v", err)
+	}
+	if u != nil {
+		t.Errorf("the subject of another issuer should not be found: %+v", u)
+	}
+
+	if err = UpdateOIDCUserSecretHash(currentUser.UserID, "hash"); err != nil {
+		t.Fatalf("failed to update secret hash: %v", err)
+	}
+	u, err = GetOIDCUserByUserID(currentUser.UserID)
+	if err != nil {
+		t.Fatalf("failed to get OIDC user by user ID: %v", err)
+	}
+	if u == nil || u.SecretHash != "hash" {
+		t.Errorf("unexpected OIDC user: %+v", u)
+	}
+}
+
+func TestAccountLockout(t *testing.T) {
+	username := "lockout-tester"
+	defer UnlockAccount(username)
+
+	for i := 0; i < 2; i++ {
+		locked, err := AddLoginFailure(username, 3, time.Minute, time.Minute)
+		if err != nil {
+			t.Fatalf("failed to add login failure: %v", err)
+		}
+		if locked {
+			t.Fatalf("the account should not be locked after %d failures", i+1)
+		}
+	}
+	locked, err := AddLoginFailure(username, 3, time.Minute, time.Minute)
+	if err != nil {
+		t.Fatalf("failed to add login failure: %v", err)
+	}
+	if !locked {
+		t.Fatalf("the account should be locked after 3 failures")
+	}
+	if locked, err = IsAccountLocked(username); err != nil || !locked {
+		t.Fatalf("the account should be locked: %v", err)
+	}
+
+	lockouts, err := ListLockedAccounts()
+	if err != nil {
+		t.Fatalf("failed to list locked accounts: %v", err)
+	}
+	if len(lockouts) != 1 || lockouts[0].Username != username {
+		t.Errorf("unexpected locked accounts: %+v", lockouts)
+	}
+
+	// the failures of a locked account are not counted
+	if locked, err = AddLoginFailure(username, 3, time.Minute, time.Minute); err != nil || locked {
+		t.Errorf("the failure of a locked account should be ignored: %v", err)
+	}
+
+	if locked, err = UnlockAccount(username); err != nil || !locked {
+		t.Fatalf("failed to unlock the account: %v", err)
+	}
+	if locked, err = IsAccountLocked(username); err != nil || locked {
+		t.Errorf("the account should be unlocked: %v", err)
+	}
+}
+
+func TestLockoutAudit(t *testing.T) {
+	id, err := AddLockoutAudit(&models.LockoutAudit{
+		Username:  "tester",
+		Operation: models.LockoutOpUnlock,
+		Operator:  "admin",
+	})
+	if err != nil {
+		t.Fatalf("failed to add lockout audit: %v", err)
+	}
+	defer GetOrmer().QueryTable(new(models.LockoutAudit)).Filter("ID", id).Delete()
+
+	audits, total, err := FilterLockoutAudits("test", models.LockoutOpUnlock, nil, nil, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to filter lockout audits: %v", err)
+	}
+	if total != 1 || len(audits) != 1 || audits[0].Operator != "admin" {
+		t.Fatalf("unexpected lockout audits: %d %+v", total, audits)
+	}
+}
+
+func TestUserMFA(t *testing.T) {
+	_, err := AddUserMFA(&models.UserMFA{
+		UserID: currentUser.UserID,
+		Secret: "encrypted",
+	})
+	if err != nil {
+		t.Fatalf("failed to add user MFA: %v", err)
+	}
+	defer DeleteUserMFA(currentUser.UserID)
+
+	mfa, err := GetEnabledUserMFAByUsername(currentUser.Username)
+	if err != nil {
+		t.Fatalf("failed to get user MFA: %v", err)
+	}
+	if mfa != nil {
+		t.Errorf("the pending user MFA should not be returned")
+	}
+
+	enabled, err := EnableUserMFA(currentUser.UserID, 100, "h1,h2", "secret-hash")
+	if err != nil || !enabled {
+		t.Fatalf("failed to enable user MFA: %v", err)
+	}
+	mfa, err = GetEnabledUserMFAByUsername(currentUser.Username)
+	if err != nil {
+		t.Fatalf("failed to get user MFA: %v", err)
+	}
+	if mfa == nil || mfa.LastStep != 100 || mfa.CLISecretHash != "secret-hash" {
+		t.Fatalf("unexpected user MFA: %+v", mfa)
+	}
+
+	// the steps not later than the last one are replayed
+	for step, accepted := range map[int64]bool{100: false, 99: false} {
+		ok, err := UpdateUserMFALastStep(currentUser.UserID, step)
+		if err != nil || ok != accepted {
+			t.Errorf("unexpected result of updating step %d: %t %v", step, ok, err)
+		}
+	}
+	if ok, err := UpdateUserMFALastStep(currentUser.UserID, 101); err != nil || !ok {
+		t.Errorf("the later step should be accepted: %v", err)
+	}
+
+	if ok, err := UpdateUserMFARecoveryCodes(currentUser.UserID, "h1", "h2"); err != nil || ok {
+		t.Errorf("the recovery codes changed meanwhile should not be replaced: %v", err)
+	}
+	if ok, err := UpdateUserMFARecoveryCodes(currentUser.UserID, "h1,h2", "h2"); err != nil || !ok {
+		t.Errorf("failed to replace the recovery codes: %v", err)
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	defer DeletePasswordHistory(currentUser.UserID)
+	for i := 0; i < MaxPasswordHistory+2; i++ {
+		if err := AddPasswordHistory(currentUser.UserID, "hash-"+strconv.Itoa(i)); err != nil {
+			t.Fatalf("failed to add password history: %v", err)
+		}
+	}
+
+	history, err := GetPasswordHistory(currentUser.UserID, MaxPasswordHistory+2)
+	if err != nil {
+		t.Fatalf("failed to get password history: %v", err)
+	}
+	if len(history) != MaxPasswordHistory {
+		t.Fatalf("unexpected length of password history: %d != %d", len(history), MaxPasswordHistory)
+	}
+	if history[0].PasswordHash != "hash-"+strconv.Itoa(MaxPasswordHistory+1) {
+		t.Errorf("the latest password should be returned first: %s", history[0].PasswordHash)
+	}
+
+	if _, err = GetPasswordChangeTime(currentUser.UserID); err != nil {
+		t.Errorf("failed to get the password change time: %v", err)
+	}
+}
+
+func TestRobot(t *testing.T) {
+	robot := &models.Robot{
+		Name:       "robot$" + currentProject.Name + "+test",
+		ProjectID:  currentProject.ProjectID,
+		SecretHash: "secret-hash",
+		Access:     models.RobotAccessPull,
+		CreatorID:  currentUser.UserID,
+	}
+	id, err := AddRobot(robot)
+	if err != nil {
+		t.Fatalf("failed to add robot account: %v", err)
+	}
+	defer GetOrmer().Delete(&models.Robot{ID: id})
+
+	r, err := GetRobotByName(robot.Name)
+	if err != nil {
+		t.Fatalf("failed to get robot account: %v", err)
+	}
+	if r == nil || r.ID != id || r.Revoked {
+		t.Fatalf("unexpected robot account: %+v", r)
+	}
+
+	robots, err := ListRobots(currentProject.ProjectID)
+	if err != nil {
+		t.Fatalf("failed to list robot accounts: %v", err)
+	}
+	if len(robots) != 1 {
+		t.Errorf("unexpected number of robot accounts: %d", len(robots))
+	}
+
+	revoked, err := RevokeRobotsByProject(currentProject.ProjectID)
+	if err != nil {
+		t.Fatalf("failed to revoke robot accounts: %v", err)
+	}
+	if len(revoked) != 1 || revoked[0].Name != robot.Name {
+		t.Errorf("unexpected revoked robot accounts: %+v", revoked)
+	}
+	r, err = GetRobot(id)
+	if err != nil {
+		t.Fatalf("failed to get robot account: %v", err)
+	}
+	if r == nil || !r.Revoked {
+		t.Errorf("the robot account should be revoked: %+v", r)
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	id, err := AddAccessToken(&models.AccessToken{
+		UserID:    currentUser.UserID,
+		Name:      "ci",
+		TokenHash: "token-hash",
+		Scope:     models.AccessTokenScopeRead,
+	})
+	if err != nil {
+		t.Fatalf("failed to add personal access token: %v", err)
+	}
+	defer DeleteAccessTokensByUser(currentUser.UserID)
+
+	if !AccessTokenNameExists(currentUser.UserID, "ci") {
+		t.Errorf("the name of the token should exist")
+	}
+	token, err := GetAccessTokenByHash("token-hash")
+	if err != nil {
+		t.Fatalf("failed to get personal access token: %v", err)
+	}
+	if token == nil || token.ID != id || !token.ReadOnly() {
+		t.Fatalf("unexpected personal access token: %+v", token)
+	}
+	if err = UpdateAccessTokenLastUsedTime(id); err != nil {
+		t.Errorf("failed to update the last used time: %v", err)
+	}
+
+	// the tokens of other users can not be revoked
+	n, err := DeleteAccessToken(currentUser.UserID+1, id)
+	if err != nil || n != 0 {
+		t.Errorf("unexpected result of revoking the token of another user: %d %v", n, err)
+	}
+	n, err = DeleteAccessToken(currentUser.UserID, id)
+	if err != nil || n != 1 {
+		t.Errorf("failed to revoke personal access token: %d %v", n, err)
+	}
+	tokens, err := GetAccessTokensByUser(currentUser.UserID)
+	if err != nil || len(tokens) != 0 {
+		t.Errorf("unexpected tokens after revocation: %+v %v", tokens, err)
+	}
+}
+
+func TestUserSession(t *testing.T) {
+	id, err := AddUserSession(&models.UserSession{
+		UserID:      currentUser.UserID,
+		SessionHash: "session-hash",
+		IP:          "10.0.0.1",
+		UserAgent:   "Mozilla/5.0",
+	})
+	if err != nil {
+		t.Fatalf("failed to add user session: %v", err)
+	}
+	_, err = AddUserSession(&models.UserSession{
+		UserID:      currentUser.UserID,
+		SessionHash: "another-session-hash",
+	})
+	if err != nil {
+		t.Fatalf("failed to add user session: %v", err)
+	}
+	defer DeleteUserSessions(currentUser.UserID, "")
+
+	session, err := GetUserSessionByHash("session-hash")
+	if err != nil {
+		t.Fatalf("failed to get user session: %v", err)
+	}
+	if session == nil || session.ID != id || session.IP != "10.0.0.1" {
+		t.Fatalf("unexpected user session: %+v", session)
+	}
+	if err = UpdateUserSessionLastSeenTime(id); err != nil {
+		t.Errorf("failed to update the last seen time: %v", err)
+	}
+
+	// the excepted session is kept
+	n, err := DeleteUserSessions(currentUser.UserID, "session-hash")
+	if err != nil || n != 1 {
+		t.Errorf("unexpected result of revoking sessions: %d %v", n, err)
+	}
+	sessions, err := GetUserSessions(currentUser.UserID)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != id {
+		t.Errorf("unexpected sessions: %+v %v", sessions, err)
+	}
+
+	n, err = DeleteUserSession(currentUser.UserID, id)
+	if err != nil || n != 1 {
+		t.Errorf("failed to revoke user session: %d %v", n, err)
+	}
+	if _, err = DeleteStaleUserSessions(time.Now()); err != nil {
+		t.Errorf("failed to delete stale sessions: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"encoding/json"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddDriftReport persists a drift report, the item list is stored as JSON
+func AddDriftReport(report models.DriftReport) (int64, error) {
+	if report.ItemList == nil {
+		report.ItemList = []*models.DriftItem{}
+	}
+	b, err := json.Marshal(report.ItemList)
+	if err != nil {
+		return 0, err
+	}
+	report.Items = string(b)
+	return GetOrmer().Insert(&report)
+}
+
+// GetDriftReport returns the drift report with the item list populated,
+// nil is returned if the report does not exist
+func GetDriftReport(id int64) (*models.DriftReport, error) {
+	r := models.DriftReport{ID: id}
+	err := GetOrmer().Read(&r)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = genItemListForDriftReport(&r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetDriftReportsByPolicy returns the drift reports of a policy, the newest first.
+// The item lists are not populated.
+func GetDriftReportsByPolicy(policyID int64, limit, offset int64) ([]*models.DriftReport, int64, error) {
+	reports := []*models.DriftReport{}
+	qs := GetOrmer().QueryTable(new(models.DriftReport)).Filter("PolicyID", policyID)
+	total, err := qs.Count()
+	if err != nil {
+		return reports, 0, err
+	}
+	_, err = qs.OrderBy("-CreationTime", "-ID").Limit(limit).Offset(offset).All(&reports, "ID", "PolicyID", "CreationTime")
+	return reports, total, err
+}
+
+func genItemListForDriftReport(r *models.DriftReport) error {
+	r.ItemList = []*models.DriftItem{}
+	if len(r.Items) == 0 {
+		return nil
+	}
+	return json.Unmarshal([]byte(r.Items), &r.ItemList)
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddJob adds a job of generic kind, the status is set to pending if it is empty
+func AddJob(job models.Job) (int64, error) {
+	if len(job.Status) == 0 {
+		job.Status = models.JobPending
+	}
+	return GetOrmer().Insert(&job)
+}
+
+// GetJob returns nil if the job does not exist
+func GetJob(id int64) (*models.Job, error) {
+	j := models.Job{ID: id}
+	err := GetOrmer().Read(&j)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// FilterJobs filters jobs of generic kinds by kind and status, the
+// most recently updated jobs come first
+func FilterJobs(kind, status string, limit, offset int64) ([]*models.Job, int64, error) {
+	jobs := []*models.Job{}
+	qs := GetOrmer().QueryTable(new(models.Job))
+	if len(kind) != 0 {
+		qs = qs.Filter("Kind", kind)
+	}
+	if len(status) != 0 {
+		qs = qs.Filter("Status", status)
+	}
+
+	total, err := qs.Count()
+	if err != nil {
+		return jobs, 0, err
+	}
+
+	_, err = qs.OrderBy("-UpdateTime").Limit(limit).Offset(offset).All(&jobs)
+	return jobs, total, err
+}
+
+// GetJobsByStatus returns the jobs of generic kinds whose status is one of the statuses
+func GetJobsByStatus(status ...string) ([]*models.Job, error) {
+	jobs := []*models.Job{}
+	var t []interface{}
+	for _, s := range status {
+		t = append(t, interface{}(s))
+	}
+	_, err := GetOrmer().QueryTable(new(models.Job)).Filter("status__in", t...).All(&jobs)
+	return jobs, err
+}
+
+// UpdateJobStatus ...
+func UpdateJobStatus(id int64, status string) error {
+	j := models.Job{
+		ID:         id,
+		Status:     status,
+		UpdateTime: time.Now(),
+	}
+	num, err := GetOrmer().Update(&j, "Status", "UpdateTime")
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return fmt.Errorf("job %d not found", id)
+	}
+	return nil
+}
+
+// ResetRunningGenericJobs updates the status of all running jobs of generic kinds to pending
+func ResetRunningGenericJobs() error {
+	sql := fmt.Sprintf("update job set status = '%s', update_time = ? where status = '%s'", models.JobPending, models.JobRunning)
+	_, err := GetOrmer().Raw(sql, time.Now()).Exec()
+	return err
+}
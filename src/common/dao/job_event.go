@@ -0,0 +1,39 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddJobEvent ...
+func AddJobEvent(event models.JobEvent) (int64, error) {
+	return GetOrmer().Insert(&event)
+}
+
+// GetJobEvents returns the events of a job in the order they happened
+func GetJobEvents(kind string, jobID int64) ([]*models.JobEvent, error) {
+	events := []*models.JobEvent{}
+	_, err := GetOrmer().QueryTable(new(models.JobEvent)).
+		Filter("Kind", kind).Filter("JobID", jobID).OrderBy("ID").All(&events)
+	return events, err
+}
+
+// DeleteJobEvents removes all the events of a job
+func DeleteJobEvents(kind string, jobID int64) error {
+	_, err := GetOrmer().QueryTable(new(models.JobEvent)).
+		Filter("Kind", kind).Filter("JobID", jobID).Delete()
+	return err
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddLoginFailure counts a login failure of the username, the failures
+// earlier than the window are discarded. Once the failures reach the
+// threshold the account is locked for the duration and true is returned.
+// The failures are counted by conditional updates so the UI instances
+// sharing the database count them correctly.
+func AddLoginFailure(username string, threshold int, window, duration time.Duration) (bool, error) {
+	now := time.Now()
+	unlocked := func() orm.QuerySeter {
+		return GetOrmer().QueryTable(new(models.AccountLockout)).
+			Filter("Username", username).
+			Filter("LockedUntil__lte", now)
+	}
+
+	// restart counting if the first failure is out of the window
+	n, err := unlocked().Filter("FirstFailure__lt", now.Add(-window)).
+		Update(orm.Params{
+			"Failures":     1,
+			"FirstFailure": now,
+		})
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		n, err = unlocked().Update(orm.Params{
+			"Failures": orm.ColValue(orm.ColAdd, 1),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+	if n == 0 {
+		if _, err = GetOrmer().Insert(&models.AccountLockout{
+			Username:     username,
+			Failures:     1,
+			FirstFailure: now,
+			LockedUntil:  now,
+		}); err != nil {
+			// the record is inserted by another instance or the account
+			// is locked already
+			exist := GetOrmer().QueryTable(new(models.AccountLockout)).
+				Filter("Username", username).Exist()
+			if exist {
+				return false, nil
+			}
+			return false, err
+		}
+	}
+
+	n, err = unlocked().Filter("Failures__gte", threshold).
+		Update(orm.Params{
+			"Failures":    0,
+			"LockedUntil": now.Add(duration),
+		})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// IsAccountLocked returns whether the account of the username is locked
+func IsAccountLocked(username string) (bool, error) {
+	n, err := GetOrmer().QueryTable(new(models.AccountLockout)).
+		Filter("Username", username).
+		Filter("LockedUntil__gt", time.Now()).Count()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ClearLoginFailures removes the login failures of the username if the
+// account is not locked
+func ClearLoginFailures(username string) error {
+	_, err := GetOrmer().QueryTable(new(models.AccountLockout)).
+		Filter("Username", username).
+		Filter("LockedUntil__lte", time.Now()).Delete()
+	return err
+}
+
+// UnlockAccount removes the lockout and the login failures of the username,
+// it returns whether the account was locked
+func UnlockAccount(username string) (bool, error) {
+	locked, err := IsAccountLocked(username)
+	if err != nil {
+		return false, err
+	}
+	_, err = GetOrmer().QueryTable(new(models.AccountLockout)).
+		Filter("Username", username).Delete()
+	if err != nil {
+		return false, err
+	}
+	return locked, nil
+}
+
+// ListLockedAccounts returns the accounts which are locked currently, the
+// latest to be unlocked first
+func ListLockedAccounts() ([]*models.AccountLockout, error) {
+	lockouts := []*models.AccountLockout{}
+	_, err := GetOrmer().QueryTable(new(models.AccountLockout)).
+		Filter("LockedUntil__gt", time.Now()).
+		OrderBy("-LockedUntil").All(&lockouts)
+	return lockouts, err
+}
+
+// AddLockoutAudit persists a record of the account being locked or unlocked
+func AddLockoutAudit(audit *models.LockoutAudit) (int64, error) {
+	audit.OpTime = time.Now()
+	return GetOrmer().Insert(audit)
+}
+
+// FilterLockoutAudits returns the records which match the conditions, the newest first
+func FilterLockoutAudits(username, operation string, startTime, endTime *time.Time,
+	limit, offset int64) ([]*models.LockoutAudit, int64, error) {
+	audits := []*models.LockoutAudit{}
+
+	qs := GetOrmer().QueryTable(new(models.LockoutAudit))
+	if len(username) != 0 {
+		qs = qs.Filter("Username__icontains", username)
+	}
+	if len(operation) != 0 {
+		qs = qs.Filter("Operation", operation)
+	}
+	if startTime != nil {
+		qs = qs.Filter("OpTime__gte", startTime)
+	}
+	if endTime != nil {
+		qs = qs.Filter("OpTime__lte", endTime)
+	}
+
+	total, err := qs.Count()
+	if err != nil {
+		return audits, 0, err
+	}
+
+	_, err = qs.OrderBy("-OpTime", "-ID").Limit(limit).Offset(offset).All(&audits)
+	if err != nil {
+		return audits, 0, err
+	}
+	return audits, total, nil
+}
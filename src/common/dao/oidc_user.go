@@ -0,0 +1,81 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddOIDCUser links the user to the subject of the OpenID Connect provider
+func AddOIDCUser(oidcUser *models.OIDCUser) (int64, error) {
+	now := time.Now()
+	oidcUser.CreationTime = now
+	oidcUser.UpdateTime = now
+	return GetOrmer().Insert(oidcUser)
+}
+
+// GetOIDCUserBySubject returns the OIDC user of the subject issued by the
+// issuer, nil is returned if it does not exist
+func GetOIDCUserBySubject(issuer, subject string) (*models.OIDCUser, error) {
+	oidcUser := &models.OIDCUser{
+		Issuer:  issuer,
+		Subject: subject,
+	}
+	err := GetOrmer().Read(oidcUser, "Issuer", "Subject")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return oidcUser, nil
+}
+
+// GetOIDCUserByUserID returns the OIDC user linked to the user, nil is
+// returned if it does not exist
+func GetOIDCUserByUserID(userID int) (*models.OIDCUser, error) {
+	oidcUser := &models.OIDCUser{
+		UserID: userID,
+	}
+	err := GetOrmer().Read(oidcUser, "UserID")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return oidcUser, nil
+}
+
+// UpdateOIDCUserSecretHash replaces the hash of the CLI secret of the user
+func UpdateOIDCUserSecretHash(userID int, secretHash string) error {
+	_, err := GetOrmer().QueryTable(new(models.OIDCUser)).
+		Filter("UserID", userID).
+		Update(orm.Params{
+			"SecretHash": secretHash,
+			"UpdateTime": time.Now(),
+		})
+	return err
+}
+
+// DeleteOIDCUser removes the link of the user to the OpenID Connect provider
+func DeleteOIDCUser(userID int) error {
+	_, err := GetOrmer().QueryTable(new(models.OIDCUser)).
+		Filter("UserID", userID).Delete()
+	return err
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// MaxPasswordHistory is the count of the password hashes kept for each user
+const MaxPasswordHistory = 24
+
+// AddPasswordHistory records the hash of the new password of the user, the
+// records beyond MaxPasswordHistory are removed
+func AddPasswordHistory(userID int, hash string) error {
+	if _, err := GetOrmer().Insert(&models.PasswordHistory{
+		UserID:       userID,
+		PasswordHash: hash,
+		CreationTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+
+	var stale []*models.PasswordHistory
+	if _, err := GetOrmer().QueryTable(new(models.PasswordHistory)).
+		Filter("UserID", userID).
+		OrderBy("-ID").
+		Offset(MaxPasswordHistory).
+		All(&stale, "ID"); err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	ids := []int64{}
+	for _, h := range stale {
+		ids = append(ids, h.ID)
+	}
+	_, err := GetOrmer().QueryTable(new(models.PasswordHistory)).
+		Filter("ID__in", ids).Delete()
+	return err
+}
+
+// GetPasswordHistory returns the last n password hashes of the user, the
+// latest first
+func GetPasswordHistory(userID, n int) ([]*models.PasswordHistory, error) {
+	history := []*models.PasswordHistory{}
+	_, err := GetOrmer().QueryTable(new(models.PasswordHistory)).
+		Filter("UserID", userID).
+		OrderBy("-ID").
+		Limit(n).
+		All(&history)
+	return history, err
+}
+
+// GetPasswordChangeTime returns when the password of the user is changed.
+// The users whose passwords are set before the history is introduced have no
+// record, their current passwords are recorded as changed now.
+func GetPasswordChangeTime(userID int) (time.Time, error) {
+	history, err := GetPasswordHistory(userID, 1)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if len(history) > 0 {
+		return history[0].CreationTime, nil
+	}
+
+	hash, _, err := getPasswordHash(userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if err = AddPasswordHistory(userID, hash); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now(), nil
+}
+
+// DeletePasswordHistory removes the password history of the user
+func DeletePasswordHistory(userID int) error {
+	_, err := GetOrmer().QueryTable(new(models.PasswordHistory)).
+		Filter("UserID", userID).Delete()
+	return err
+}
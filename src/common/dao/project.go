@@ -18,8 +18,11 @@ import (
 	"github.com/vmware/harbor/src/common/models"
 
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
 )
 
@@ -49,12 +52,62 @@ func AddProject(project models.Project) (int64, error) {
 		return projectID, err
 	}
 
+	if err = reassignRepositories(projectID, project.Name); err != nil {
+		return projectID, err
+	}
+
 	accessLog := models.AccessLog{UserID: project.OwnerID, ProjectID: projectID, RepoName: project.Name + "/", RepoTag: "N/A", GUID: "N/A", Operation: "create", OpTime: time.Now()}
 	err = AddAccessLog(accessLog)
 
 	return projectID, err
 }
 
+// reassignRepositories moves the repositories under the nested project from
+// its ancestors to it, as the project is the longest prefix of them now
+func reassignRepositories(projectID int64, name string) error {
+	parents := utils.ParentProjects(name)
+	if len(parents) == 0 {
+		return nil
+	}
+
+	params := []interface{}{projectID, name + "/", name + "0"}
+	for _, parent := range parents {
+		params = append(params, parent)
+	}
+	sql := `update repository set project_id = ?
+		where name > ? and name < ? and project_id in (
+			select project_id from project where deleted = 0 and name in (` +
+		strings.TrimSuffix(strings.Repeat("?,", len(parents)), ",") + `))`
+	_, err := GetOrmer().Raw(sql, params...).Exec()
+	return err
+}
+
+// ParseRepository splits a repository into two parts: project and rest, the
+// project is the existing one whose name is the longest prefix of the
+// repository in path segments, e.g. "org/team" for "org/team/app" if project
+// "org/team" exists, otherwise "org".
+func ParseRepository(repository string) (project, rest string) {
+	return utils.ResolveProject(repository, func(name string) bool {
+		exist, err := ProjectExists(name)
+		if err != nil {
+			log.Errorf("Error occurred in ProjectExists: %v", err)
+			return false
+		}
+		return exist
+	})
+}
+
+// GetChildProjects returns the descendants of the project, e.g. "org/team"
+// and "org/team/app" for "org"
+func GetChildProjects(name string) ([]models.Project, error) {
+	projects := []models.Project{}
+	// all names prefixed with "name/" sort between "name/" and "name0"
+	_, err := GetOrmer().Raw(`select * from project
+		where deleted = 0 and name > ? and name < ? order by name`,
+		name+"/", name+"0").QueryRows(&projects)
+	return projects, err
+}
+
 // IsProjectPublic ...
 func IsProjectPublic(projectName string) bool {
 	project, err := GetProjectByName(projectName)
@@ -130,8 +183,24 @@ func GetProjectByName(name string) (*models.Project, error) {
 	return &p[0], nil
 }
 
-// GetPermission gets roles that the user has according to the project.
+// GetPermission gets roles that the user has according to the project. If the
+// user is not a member of the project, the roles on the nearest ancestor of
+// the nested project are inherited.
 func GetPermission(username, projectName string) (string, error) {
+	names := append([]string{projectName}, utils.ParentProjects(projectName)...)
+	for _, name := range names {
+		permission, err := getPermission(username, name)
+		if err != nil {
+			return "", err
+		}
+		if len(permission) > 0 {
+			return permission, nil
+		}
+	}
+	return "", nil
+}
+
+func getPermission(username, projectName string) (string, error) {
 	o := GetOrmer()
 
 	sql := `select r.role_code from role as r
@@ -183,29 +252,32 @@ func SearchProjects(userID int) ([]models.Project, error) {
 		return nil, err
 	}
 
+	// the nested projects which inherit the membership from their ancestors
+	relevant, err := getUserRelevantProjects(userID)
+	if err != nil {
+		return nil, err
+	}
+	exist := map[int64]bool{}
+	for _, p := range projects {
+		exist[p.ProjectID] = true
+	}
+	for _, p := range relevant {
+		if !exist[p.ProjectID] {
+			projects = append(projects, p)
+		}
+	}
+
 	return projects, nil
 }
 
 //GetTotalOfUserRelevantProjects returns the total count of
 // user relevant projects
 func GetTotalOfUserRelevantProjects(userID int, projectName string) (int64, error) {
-	o := GetOrmer()
-	sql := `select count(*) from project p 
-			left join project_member pm 
-			on p.project_id = pm.project_id
-	 		where p.deleted = 0 and pm.user_id= ?`
-
-	queryParam := []interface{}{}
-	queryParam = append(queryParam, userID)
-	if projectName != "" {
-		sql += " and p.name like ? "
-		queryParam = append(queryParam, "%"+escape(projectName)+"%")
+	projects, err := getUserProjects(userID, projectName)
+	if err != nil {
+		return 0, err
 	}
-
-	var total int64
-	err := o.Raw(sql, queryParam).QueryRow(&total)
-
-	return total, err
+	return int64(len(projects)), nil
 }
 
 // GetUserRelevantProjects returns the user relevant projects
@@ -240,22 +312,15 @@ func GetProjects(name string, args ...int64) ([]models.Project, error) {
 func getProjects(userID int, name string, args ...int64) ([]models.Project, error) {
 	projects := []models.Project{}
 
-	o := GetOrmer()
-	sql := ""
-	queryParam := []interface{}{}
-
 	if userID != 0 { //get user's projects
-		sql = `select distinct p.project_id, p.owner_id, p.name, 
-					p.creation_time, p.update_time, p.public, pm.role role 
-			from project p 
-			left join project_member pm 
-			on p.project_id = pm.project_id
-	 		where p.deleted = 0 and pm.user_id= ?`
-		queryParam = append(queryParam, userID)
-	} else { // get all projects
-		sql = `select * from project p where p.deleted = 0 `
+		return getUserProjects(userID, name, args...)
 	}
 
+	o := GetOrmer()
+	queryParam := []interface{}{}
+	// get all projects
+	sql := `select * from project p where p.deleted = 0 `
+
 	if name != "" {
 		sql += ` and p.name like ? `
 		queryParam = append(queryParam, "%"+escape(name)+"%")
@@ -281,6 +346,94 @@ func getProjects(userID int, name string, args ...int64) ([]models.Project, erro
 	return projects, err
 }
 
+// getUserProjects filters the user relevant projects by name and publicity,
+// and paginates them in the order of name
+// args[0]: public, args[1]: limit, args[2]: offset
+func getUserProjects(userID int, name string, args ...int64) ([]models.Project, error) {
+	relevant, err := getUserRelevantProjects(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var public *int64
+	limit, offset := int64(-1), int64(0)
+	switch len(args) {
+	case 1:
+		public = &args[0]
+	case 2:
+		limit, offset = args[0], args[1]
+	case 3:
+		public = &args[0]
+		limit, offset = args[1], args[2]
+	}
+
+	projects := []models.Project{}
+	for _, p := range relevant {
+		if len(name) > 0 && !strings.Contains(strings.ToLower(p.Name), strings.ToLower(name)) {
+			continue
+		}
+		if public != nil && int64(p.Public) != *public {
+			continue
+		}
+		projects = append(projects, p)
+	}
+	sort.Sort(&models.ProjectSorter{Projects: projects})
+
+	if offset >= int64(len(projects)) {
+		return []models.Project{}, nil
+	}
+	projects = projects[offset:]
+	if limit >= 0 && limit < int64(len(projects)) {
+		projects = projects[:limit]
+	}
+	return projects, nil
+}
+
+// getUserRelevantProjects returns the projects the user is a member of and
+// their descendants which inherit the membership, the role of the user on an
+// inherited project is the one on its nearest ancestor
+func getUserRelevantProjects(userID int) ([]models.Project, error) {
+	sql := `select distinct p.project_id, p.owner_id, p.name, 
+				p.creation_time, p.update_time, p.public, pm.role role 
+		from project p 
+		left join project_member pm 
+		on p.project_id = pm.project_id
+		where p.deleted = 0 and pm.user_id= ?`
+	direct := []models.Project{}
+	if _, err := GetOrmer().Raw(sql, userID).QueryRows(&direct); err != nil {
+		return nil, err
+	}
+
+	roles := map[string]int{}
+	exist := map[int64]bool{}
+	for _, p := range direct {
+		roles[p.Name] = p.Role
+		exist[p.ProjectID] = true
+	}
+
+	projects := append([]models.Project{}, direct...)
+	for _, p := range direct {
+		children, err := GetChildProjects(p.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, child := range children {
+			if exist[child.ProjectID] {
+				continue
+			}
+			exist[child.ProjectID] = true
+			for _, parent := range utils.ParentProjects(child.Name) {
+				if role, ok := roles[parent]; ok {
+					child.Role = role
+					break
+				}
+			}
+			projects = append(projects, child)
+		}
+	}
+	return projects, nil
+}
+
 // DeleteProject ...
 func DeleteProject(id int64) error {
 	project, err := GetProjectByID(id)
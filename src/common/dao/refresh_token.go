@@ -0,0 +1,70 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddRefreshToken persists a refresh token, the TokenHash must be set
+func AddRefreshToken(token *models.RefreshToken) (int64, error) {
+	now := time.Now()
+	token.CreationTime = now
+	token.LastUsedTime = now
+	return GetOrmer().Insert(token)
+}
+
+// GetRefreshTokenByHash returns the refresh token whose hash is the one
+// provided, nil is returned if it does not exist
+func GetRefreshTokenByHash(hash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{
+		TokenHash: hash,
+	}
+	err := GetOrmer().Read(token, "TokenHash")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// UpdateRefreshTokenLastUsedTime updates the last used time of the refresh token to now
+func UpdateRefreshTokenLastUsedTime(id int64) error {
+	token := &models.RefreshToken{
+		ID:           id,
+		LastUsedTime: time.Now(),
+	}
+	_, err := GetOrmer().Update(token, "LastUsedTime")
+	return err
+}
+
+// GetRefreshTokensByUser returns the refresh tokens issued to the user
+func GetRefreshTokensByUser(userID int) ([]*models.RefreshToken, error) {
+	tokens := []*models.RefreshToken{}
+	_, err := GetOrmer().QueryTable(new(models.RefreshToken)).
+		Filter("UserID", userID).OrderBy("-CreationTime").All(&tokens)
+	return tokens, err
+}
+
+// DeleteRefreshTokensByUser revokes all refresh tokens issued to the user
+func DeleteRefreshTokensByUser(userID int) (int64, error) {
+	return GetOrmer().QueryTable(new(models.RefreshToken)).
+		Filter("UserID", userID).Delete()
+}
@@ -20,21 +20,27 @@ import (
 
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/common/utils/log"
+	pwdhash "github.com/vmware/harbor/src/common/utils/password"
 )
 
 // Register is used for user to register, the password is encrypted before the record is inserted into database.
 func Register(user models.User) (int64, error) {
 	o := GetOrmer()
-	p, err := o.Raw("insert into tenx_users (user_name, password, displayname, email, avatar, api_token, admin_role, creation_time, update_time) values (?, ?, ?, ?, ?, ?, ?, ?, ?)").Prepare()
+	p, err := o.Raw("insert into tenx_users (user_name, password, displayname, email, avatar, api_token, admin_role, creation_time, update_time, auth_source) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)").Prepare()
 	if err != nil {
 		return 0, err
 	}
 	defer p.Close()
 
+	hash, err := pwdhash.Hash(user.Password)
+	if err != nil {
+		return 0, err
+	}
 	salt := utils.GenerateRandomString()
 
 	now := time.Now()
-	r, err := p.Exec(user.Username, utils.Encrypt(user.Password, salt), user.Realname, user.Email, user.Comment, salt, user.HasAdminRole, now, now)
+	r, err := p.Exec(user.Username, hash, user.Realname, user.Email, user.Comment, salt, user.HasAdminRole, now, now, user.AuthSource)
 
 	if err != nil {
 		return 0, err
@@ -43,6 +49,9 @@ func Register(user models.User) (int64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err = AddPasswordHistory(int(userID), hash); err != nil {
+		log.Errorf("failed to record the password history of user %d: %v", userID, err)
+	}
 
 	return userID, nil
 }
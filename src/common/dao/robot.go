@@ -0,0 +1,98 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddRobot persists a robot account, the SecretHash must be set
+func AddRobot(robot *models.Robot) (int64, error) {
+	now := time.Now()
+	robot.CreationTime = now
+	robot.UpdateTime = now
+	return GetOrmer().Insert(robot)
+}
+
+// GetRobot returns the robot account by ID, nil is returned if it does not exist
+func GetRobot(id int64) (*models.Robot, error) {
+	robot := &models.Robot{
+		ID: id,
+	}
+	err := GetOrmer().Read(robot)
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return robot, nil
+}
+
+// GetRobotByName returns the robot account by name, nil is returned if it does
+// not exist
+func GetRobotByName(name string) (*models.Robot, error) {
+	robot := &models.Robot{
+		Name: name,
+	}
+	err := GetOrmer().Read(robot, "Name")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return robot, nil
+}
+
+// ListRobots returns the robot accounts of the project, including the revoked ones
+func ListRobots(projectID int64) ([]*models.Robot, error) {
+	robots := []*models.Robot{}
+	_, err := GetOrmer().QueryTable(new(models.Robot)).
+		Filter("ProjectID", projectID).OrderBy("-CreationTime").All(&robots)
+	return robots, err
+}
+
+// RevokeRobot revokes the robot account, the record is kept so the name
+// in the logs is not reused
+func RevokeRobot(id int64) error {
+	_, err := GetOrmer().QueryTable(new(models.Robot)).
+		Filter("ID", id).Update(orm.Params{
+		"Revoked":    true,
+		"UpdateTime": time.Now(),
+	})
+	return err
+}
+
+// RevokeRobotsByProject revokes all robot accounts of the project and returns
+// the ones revoked
+func RevokeRobotsByProject(projectID int64) ([]*models.Robot, error) {
+	robots := []*models.Robot{}
+	qs := GetOrmer().QueryTable(new(models.Robot)).
+		Filter("ProjectID", projectID).Filter("Revoked", false)
+	if _, err := qs.All(&robots); err != nil {
+		return nil, err
+	}
+	if _, err := qs.Update(orm.Params{
+		"Revoked":    true,
+		"UpdateTime": time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+	return robots, nil
+}
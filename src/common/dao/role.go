@@ -19,6 +19,7 @@ import (
 
 	"github.com/astaxie/beego/orm"
 	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
 )
 
 // GetUserProjectRoles returns roles that the user has according to the project.
@@ -44,6 +45,36 @@ func GetUserProjectRoles(userID int, projectID int64) ([]models.Role, error) {
 	return roleList, nil
 }
 
+// GetUserProjectRolesWithInheritance returns roles that the user has according
+// to the project. If the user is not a member of the project, the roles on the
+// nearest ancestor of the nested project are inherited.
+func GetUserProjectRolesWithInheritance(userID int, projectID int64) ([]models.Role, error) {
+	roles, err := GetUserProjectRoles(userID, projectID)
+	if err != nil || len(roles) > 0 {
+		return roles, err
+	}
+
+	project, err := GetProjectByID(projectID)
+	if err != nil || project == nil {
+		return roles, err
+	}
+
+	for _, name := range utils.ParentProjects(project.Name) {
+		parent, err := GetProjectByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			continue
+		}
+		roles, err = GetUserProjectRoles(userID, parent.ProjectID)
+		if err != nil || len(roles) > 0 {
+			return roles, err
+		}
+	}
+	return roles, nil
+}
+
 // IsAdminRole returns whether the user is admin.
 func IsAdminRole(userIDOrUsername interface{}) (bool, error) {
 	u := models.User{}
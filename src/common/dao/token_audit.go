@@ -0,0 +1,58 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddTokenAudit persists a record of denied or reduced access
+func AddTokenAudit(audit *models.TokenAudit) (int64, error) {
+	audit.OpTime = time.Now()
+	return GetOrmer().Insert(audit)
+}
+
+// FilterTokenAudits returns the records which match the conditions, the newest first
+func FilterTokenAudits(username, service string, startTime, endTime *time.Time,
+	limit, offset int64) ([]*models.TokenAudit, int64, error) {
+	audits := []*models.TokenAudit{}
+
+	qs := GetOrmer().QueryTable(new(models.TokenAudit))
+	if len(username) != 0 {
+		qs = qs.Filter("Username__icontains", username)
+	}
+	if len(service) != 0 {
+		qs = qs.Filter("Service", service)
+	}
+	if startTime != nil {
+		qs = qs.Filter("OpTime__gte", startTime)
+	}
+	if endTime != nil {
+		qs = qs.Filter("OpTime__lte", endTime)
+	}
+
+	total, err := qs.Count()
+	if err != nil {
+		return audits, 0, err
+	}
+
+	_, err = qs.OrderBy("-OpTime", "-ID").Limit(limit).Offset(offset).All(&audits)
+	if err != nil {
+		return audits, 0, err
+	}
+	return audits, total, nil
+}
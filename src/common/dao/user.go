@@ -15,15 +15,14 @@
 package dao
 
 import (
-	"crypto/md5"
 	"database/sql"
-	"encoding/hex"
 	"errors"
 	"fmt"
-	"io"
 
+	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils"
+	pwdhash "github.com/vmware/harbor/src/common/utils/password"
 
 	"github.com/vmware/harbor/src/common/utils/log"
 )
@@ -34,7 +33,7 @@ func GetUser(query models.User) (*models.User, error) {
 	o := GetOrmer()
 
 	sql := `select user_id, user_name, email, displayname, avatar, confirm_code, api_token,
-		admin_role, creation_time, last_login_time
+		admin_role, creation_time, last_login_time, auth_source
 		from tenx_users u
 		where migrated = 0 `
 	queryParam := make([]interface{}, 1)
@@ -86,23 +85,46 @@ func LoginByDb(auth models.AuthModel) (*models.User, error) {
 
 	user := users[0]
 
-	if user.Password != encodeMD5(auth.Password) {
+	match, rehash := pwdhash.Verify(auth.Password, user.Password, user.Salt)
+	if !match {
 		return nil, nil
 	}
+	if rehash {
+		if err := upgradePasswordHash(user.UserID, user.Password, auth.Password); err != nil {
+			log.Errorf("failed to upgrade the password hash of user %s: %v", user.Username, err)
+		}
+	}
 
 	user.Password = "" //do not return the password
 
 	return &user, nil
 }
 
-// EncodeMD5 TenxCloud Added
-func encodeMD5(str string) string {
-	h := md5.New()
-	io.WriteString(h, str)
+// upgradePasswordHash replaces the legacy or weak hash of the user with the
+// one of the default hasher, unless the password is changed meanwhile
+func upgradePasswordHash(userID int, oldHash, pwd string) error {
+	hash, err := pwdhash.Hash(pwd)
+	if err != nil {
+		return err
+	}
+	_, err = GetOrmer().Raw(`update tenx_users set password=? where user_id=? and password=?`,
+		hash, userID, oldHash).Exec()
+	return err
+}
 
-	strSum := h.Sum(nil)
-	res := hex.EncodeToString(strSum)
-	return res
+// getPasswordHash returns the password hash of the user and the salt of the
+// legacy hash
+func getPasswordHash(userID int) (string, string, error) {
+	var users []models.User
+	n, err := GetOrmer().Raw(`select user_id, password, api_token from tenx_users where user_id = ? and migrated = 0`,
+		userID).QueryRows(&users)
+	if err != nil {
+		return "", "", err
+	}
+	if n == 0 {
+		return "", "", nil
+	}
+	return users[0].Password, users[0].Salt, nil
 }
 
 // ListUsers lists all users according to different conditions.
@@ -110,7 +132,7 @@ func ListUsers(query models.User) ([]models.User, error) {
 	o := GetOrmer()
 	u := []models.User{}
 	sql := `select  user_id, user_name, email, displayname, avatar, confirm_code, api_token,
-		admin_role, creation_time, last_login_time
+		admin_role, creation_time, last_login_time, auth_source
 		from tenx_users u
 		where u.migrated = 0 and u.user_id != 1 `
 
@@ -152,13 +174,26 @@ func ChangeUserPassword(u models.User, oldPassword ...string) (err error) {
 
 	o := GetOrmer()
 
+	hash, err := pwdhash.Hash(u.Password)
+	if err != nil {
+		return err
+	}
+
 	var r sql.Result
 	salt := utils.GenerateRandomString()
 	if len(oldPassword) == 0 {
 		//In some cases, it may no need to check old password, just as Linux change password policies.
-		r, err = o.Raw(`update tenx_users set password=?, api_token=? where user_id=?`, utils.Encrypt(u.Password, salt), salt, u.UserID).Exec()
+		r, err = o.Raw(`update tenx_users set password=?, api_token=? where user_id=?`, hash, salt, u.UserID).Exec()
 	} else {
-		r, err = o.Raw(`update tenx_users set password=?, api_token=? where user_id=? and password = ?`, utils.Encrypt(u.Password, salt), salt, u.UserID, utils.Encrypt(oldPassword[0], u.Salt)).Exec()
+		var oldHash, oldSalt string
+		oldHash, oldSalt, err = getPasswordHash(u.UserID)
+		if err != nil {
+			return err
+		}
+		if match, _ := pwdhash.Verify(oldPassword[0], oldHash, oldSalt); !match {
+			return errors.New("no record has been modified, change password failed")
+		}
+		r, err = o.Raw(`update tenx_users set password=?, api_token=? where user_id=? and password = ?`, hash, salt, u.UserID, oldHash).Exec()
 	}
 
 	if err != nil {
@@ -171,14 +206,21 @@ func ChangeUserPassword(u models.User, oldPassword ...string) (err error) {
 	if c == 0 {
 		return errors.New("no record has been modified, change password failed")
 	}
+	if err = AddPasswordHistory(u.UserID, hash); err != nil {
+		log.Errorf("failed to record the password history of user %d: %v", u.UserID, err)
+	}
 
 	return nil
 }
 
 // ResetUserPassword ...
 func ResetUserPassword(u models.User) error {
+	hash, err := pwdhash.Hash(u.Password)
+	if err != nil {
+		return err
+	}
 	o := GetOrmer()
-	r, err := o.Raw(`update tenx_users set password=?, confirm_code=? where confirm_code=?`, utils.Encrypt(u.Password, u.Salt), "", u.ResetUUID).Exec()
+	r, err := o.Raw(`update tenx_users set password=?, confirm_code=? where confirm_code=?`, hash, "", u.ResetUUID).Exec()
 	if err != nil {
 		return err
 	}
@@ -189,9 +231,49 @@ func ResetUserPassword(u models.User) error {
 	if count == 0 {
 		return errors.New("no record be changed, reset password failed")
 	}
+	if u.UserID != 0 {
+		if err = AddPasswordHistory(u.UserID, hash); err != nil {
+			log.Errorf("failed to record the password history of user %d: %v", u.UserID, err)
+		}
+	}
 	return nil
 }
 
+// UpdateUserAuthSource records the auth mode which authenticated the user.
+func UpdateUserAuthSource(userID int, authSource string) error {
+	_, err := GetOrmer().Raw(`update tenx_users set auth_source = ? where user_id = ?`,
+		authSource, userID).Exec()
+	return err
+}
+
+// BackfillUserAuthSource records the auth mode on the users who have not
+// logged in since the auth chain was introduced. The admin is always
+// authenticated against the database, the others by the auth mode in effect
+// before the upgrade. It returns the number of the users updated.
+func BackfillUserAuthSource(authMode string) (int64, error) {
+	o := GetOrmer()
+	r, err := o.Raw(`update tenx_users set auth_source = ?
+		where user_id = 1 and (auth_source is null or auth_source = '')`,
+		common.DBAuth).Exec()
+	if err != nil {
+		return 0, err
+	}
+	admin, err := r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	r, err = o.Raw(`update tenx_users set auth_source = ?
+		where auth_source is null or auth_source = ''`, authMode).Exec()
+	if err != nil {
+		return 0, err
+	}
+	others, err := r.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return admin + others, nil
+}
+
 // UpdateUserResetUUID ...
 func UpdateUserResetUUID(u models.User) error {
 	o := GetOrmer()
@@ -210,23 +292,20 @@ func CheckUserPassword(query models.User) (*models.User, error) {
 		return nil, nil
 	}
 
-	sql := `select user_id, user_name, api_token from tenx_users where migrated = 0 and user_name = ? and password = ?`
-	queryParam := make([]interface{}, 1)
-	queryParam = append(queryParam, currentUser.Username)
-	queryParam = append(queryParam, utils.Encrypt(query.Password, currentUser.Salt))
-	o := GetOrmer()
-	var user []models.User
-
-	n, err := o.Raw(sql, queryParam).QueryRows(&user)
+	hash, salt, err := getPasswordHash(currentUser.UserID)
 	if err != nil {
 		return nil, err
 	}
-	if n == 0 {
+	if match, _ := pwdhash.Verify(query.Password, hash, salt); !match {
 		log.Warning("User principal does not match password. Current:", currentUser)
 		return nil, nil
 	}
 
-	return &user[0], nil
+	return &models.User{
+		UserID:   currentUser.UserID,
+		Username: currentUser.Username,
+		Salt:     salt,
+	}, nil
 }
 
 // DeleteUser ...
@@ -0,0 +1,134 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddUserMFA persists the second factor of the user pending for confirmation
+func AddUserMFA(mfa *models.UserMFA) (int64, error) {
+	now := time.Now()
+	mfa.CreationTime = now
+	mfa.UpdateTime = now
+	return GetOrmer().Insert(mfa)
+}
+
+// GetUserMFA returns the second factor of the user, nil is returned if it
+// does not exist
+func GetUserMFA(userID int) (*models.UserMFA, error) {
+	mfa := &models.UserMFA{
+		UserID: userID,
+	}
+	err := GetOrmer().Read(mfa, "UserID")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mfa, nil
+}
+
+// GetEnabledUserMFAByUsername returns the enabled second factor of the user
+// whose name or email is the principal, nil is returned if the user has not
+// enabled it
+func GetEnabledUserMFAByUsername(principal string) (*models.UserMFA, error) {
+	var mfas []*models.UserMFA
+	n, err := GetOrmer().Raw(`select m.* from user_mfa m
+		join tenx_users u on m.user_id = u.user_id
+		where (u.user_name = ? or u.email = ?) and u.migrated = 0 and m.enabled = 1`,
+		principal, principal).QueryRows(&mfas)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return mfas[0], nil
+}
+
+// EnableUserMFA confirms the pending second factor of the user, it returns
+// false if there is no pending one
+func EnableUserMFA(userID int, step int64, recoveryCodes, cliSecretHash string) (bool, error) {
+	n, err := GetOrmer().QueryTable(new(models.UserMFA)).
+		Filter("UserID", userID).
+		Filter("Enabled", false).
+		Update(orm.Params{
+			"Enabled":       true,
+			"LastStep":      step,
+			"RecoveryCodes": recoveryCodes,
+			"CLISecretHash": cliSecretHash,
+			"UpdateTime":    time.Now(),
+		})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// UpdateUserMFALastStep records the time step of the accepted code, it
+// returns false if the step is not later than the recorded one, i.e. the
+// code is replayed
+func UpdateUserMFALastStep(userID int, step int64) (bool, error) {
+	n, err := GetOrmer().QueryTable(new(models.UserMFA)).
+		Filter("UserID", userID).
+		Filter("LastStep__lt", step).
+		Update(orm.Params{
+			"LastStep":   step,
+			"UpdateTime": time.Now(),
+		})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// UpdateUserMFARecoveryCodes replaces the recovery codes of the user if they
+// are not changed since being read, it returns whether they are replaced
+func UpdateUserMFARecoveryCodes(userID int, oldCodes, newCodes string) (bool, error) {
+	n, err := GetOrmer().QueryTable(new(models.UserMFA)).
+		Filter("UserID", userID).
+		Filter("RecoveryCodes", oldCodes).
+		Update(orm.Params{
+			"RecoveryCodes": newCodes,
+			"UpdateTime":    time.Now(),
+		})
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// UpdateUserMFACLISecretHash replaces the hash of the CLI secret of the user
+func UpdateUserMFACLISecretHash(userID int, cliSecretHash string) error {
+	_, err := GetOrmer().QueryTable(new(models.UserMFA)).
+		Filter("UserID", userID).
+		Update(orm.Params{
+			"CLISecretHash": cliSecretHash,
+			"UpdateTime":    time.Now(),
+		})
+	return err
+}
+
+// DeleteUserMFA removes the second factor of the user
+func DeleteUserMFA(userID int) error {
+	_, err := GetOrmer().QueryTable(new(models.UserMFA)).
+		Filter("UserID", userID).Delete()
+	return err
+}
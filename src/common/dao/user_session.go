@@ -0,0 +1,95 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dao
+
+import (
+	"time"
+
+	"github.com/astaxie/beego/orm"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// AddUserSession persists the tracking record of a session, the SessionHash
+// must be set
+func AddUserSession(session *models.UserSession) (int64, error) {
+	now := time.Now()
+	session.CreationTime = now
+	session.LastSeenTime = now
+	return GetOrmer().Insert(session)
+}
+
+// GetUserSessionByHash returns the session whose hash is the one provided,
+// nil is returned if it does not exist
+func GetUserSessionByHash(hash string) (*models.UserSession, error) {
+	session := &models.UserSession{
+		SessionHash: hash,
+	}
+	err := GetOrmer().Read(session, "SessionHash")
+	if err == orm.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// UpdateUserSessionLastSeenTime updates the last seen time of the session to now
+func UpdateUserSessionLastSeenTime(id int64) error {
+	session := &models.UserSession{
+		ID:           id,
+		LastSeenTime: time.Now(),
+	}
+	_, err := GetOrmer().Update(session, "LastSeenTime")
+	return err
+}
+
+// GetUserSessions returns the sessions of the user
+func GetUserSessions(userID int) ([]*models.UserSession, error) {
+	sessions := []*models.UserSession{}
+	_, err := GetOrmer().QueryTable(new(models.UserSession)).
+		Filter("UserID", userID).OrderBy("-LastSeenTime").All(&sessions)
+	return sessions, err
+}
+
+// DeleteUserSession revokes the session of the user, the number of sessions
+// deleted is returned
+func DeleteUserSession(userID int, id int64) (int64, error) {
+	return GetOrmer().QueryTable(new(models.UserSession)).
+		Filter("UserID", userID).Filter("ID", id).Delete()
+}
+
+// DeleteUserSessionByHash deletes the session whose hash is the one provided
+func DeleteUserSessionByHash(hash string) error {
+	_, err := GetOrmer().QueryTable(new(models.UserSession)).
+		Filter("SessionHash", hash).Delete()
+	return err
+}
+
+// DeleteUserSessions revokes the sessions of the user, except the one whose
+// hash is exceptHash if it is not empty
+func DeleteUserSessions(userID int, exceptHash string) (int64, error) {
+	qs := GetOrmer().QueryTable(new(models.UserSession)).Filter("UserID", userID)
+	if len(exceptHash) > 0 {
+		qs = qs.Exclude("SessionHash", exceptHash)
+	}
+	return qs.Delete()
+}
+
+// DeleteStaleUserSessions deletes the sessions which are not seen since the time
+func DeleteStaleUserSessions(before time.Time) (int64, error) {
+	return GetOrmer().QueryTable(new(models.UserSession)).
+		Filter("LastSeenTime__lt", before).Delete()
+}
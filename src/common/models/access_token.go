@@ -0,0 +1,56 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+const (
+	// AccessTokenScopeRead allows the token to pull images and read the APIs
+	AccessTokenScopeRead = "read"
+	// AccessTokenScopeWrite grants the token all permissions of the user
+	AccessTokenScopeWrite = "write"
+)
+
+// AccessToken is a personal access token minted by a user for the scripts and
+// docker clients, it is used as the password in basic auth. Only the hash of
+// the token is persisted. ExpiresAt is a unix timestamp, 0 means the token
+// never expires.
+type AccessToken struct {
+	ID           int64     `orm:"pk;auto;column(id)" json:"id"`
+	UserID       int       `orm:"column(user_id)" json:"user_id"`
+	Name         string    `orm:"column(name)" json:"name"`
+	TokenHash    string    `orm:"column(token_hash)" json:"-"`
+	Scope        string    `orm:"column(scope)" json:"scope"`
+	ExpiresAt    int64     `orm:"column(expires_at)" json:"expires_at"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	LastUsedTime time.Time `orm:"column(last_used_time);auto_now" json:"last_used_time"`
+}
+
+//TableName is required by beego orm to map AccessToken to table access_token
+func (a *AccessToken) TableName() string {
+	return "access_token"
+}
+
+// Expired returns whether the token has expired
+func (a *AccessToken) Expired() bool {
+	return a.ExpiresAt > 0 && time.Now().Unix() >= a.ExpiresAt
+}
+
+// ReadOnly returns whether the token is limited to the read scope
+func (a *AccessToken) ReadOnly() bool {
+	return a.Scope != AccessTokenScopeWrite
+}
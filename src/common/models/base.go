@@ -26,5 +26,18 @@ func init() {
 		new(Project),
 		new(Role),
 		new(AccessLog),
-		new(RepoRecord))
+		new(RepoRecord),
+		new(DriftReport),
+		new(JobEvent),
+		new(Job),
+		new(RefreshToken),
+		new(TokenAudit),
+		new(OIDCUser),
+		new(AccountLockout),
+		new(LockoutAudit),
+		new(UserMFA),
+		new(PasswordHistory),
+		new(Robot),
+		new(AccessToken),
+		new(UserSession))
 }
@@ -35,6 +35,43 @@ type LDAP struct {
 	Timeout        int    `json:"timeout"` // in second
 }
 
+// OIDCSetting ...
+type OIDCSetting struct {
+	Endpoint         string   `json:"endpoint"`
+	ClientID         string   `json:"client_id"`
+	ClientSecret     string   `json:"client_secret"`
+	Scope            []string `json:"scope"`
+	GroupsClaim      string   `json:"groups_claim"`
+	GroupRoleMapping string   `json:"group_role_mapping"`
+	VerifyCert       bool     `json:"verify_cert"`
+}
+
+// ProxyAuthSetting ...
+type ProxyAuthSetting struct {
+	TrustedCIDRs     []string `json:"trusted_cidrs"`
+	Secret           string   `json:"secret"`
+	GroupRoleMapping string   `json:"group_role_mapping"`
+	TokenService     bool     `json:"token_service"`
+}
+
+// LockoutSetting ...
+type LockoutSetting struct {
+	Threshold int `json:"threshold"`
+	Window    int `json:"window"`   // in minute
+	Duration  int `json:"duration"` // in minute
+}
+
+// PasswordPolicy ...
+type PasswordPolicy struct {
+	MinLength int `json:"min_length"`
+	// RequiredClasses are the character classes the passwords must contain,
+	// i.e. lower, upper, digit and special
+	RequiredClasses []string `json:"required_classes"`
+	// History is the count of the last passwords which can not be reused
+	History int `json:"history"`
+	MaxAge  int `json:"max_age"` // in day
+}
+
 // Database ...
 type Database struct {
 	Type   string  `json:"type"`
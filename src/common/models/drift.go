@@ -0,0 +1,54 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+const (
+	//DriftMissingRepository means the repository exists locally but not on the target
+	DriftMissingRepository string = "missing_repository"
+	//DriftMissingTag means the tag exists locally but not on the target
+	DriftMissingTag string = "missing_tag"
+	//DriftDigestMismatch means the tag exists on both sides but the manifest digests differ
+	DriftDigestMismatch string = "digest_mismatch"
+	//DriftExtraTag means the tag exists on the target but not locally
+	DriftExtraTag string = "extra_tag"
+)
+
+// DriftItem is a single difference between the local registry and the target of a policy
+type DriftItem struct {
+	Repository   string `json:"repository"`
+	Tag          string `json:"tag,omitempty"`
+	Type         string `json:"type"`
+	LocalDigest  string `json:"local_digest,omitempty"`
+	RemoteDigest string `json:"remote_digest,omitempty"`
+}
+
+// DriftReport records the differences between the repositories of a replication
+// policy's project and its target at the time the report was generated
+type DriftReport struct {
+	ID           int64        `orm:"column(id)" json:"id"`
+	PolicyID     int64        `orm:"column(policy_id)" json:"policy_id"`
+	Items        string       `orm:"column(items)" json:"-"`
+	ItemList     []*DriftItem `orm:"-" json:"items"`
+	CreationTime time.Time    `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+}
+
+//TableName is required by beego orm to map DriftReport to table replication_drift_report
+func (d *DriftReport) TableName() string {
+	return "replication_drift_report"
+}
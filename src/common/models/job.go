@@ -0,0 +1,40 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+const (
+	//JobKindReplication is the kind of replication jobs, which are stored in table replication_job
+	JobKindReplication string = "replication"
+)
+
+// Job is the model for a job of generic kind, e.g. garbage collection, the parameters
+// are stored as JSON and interpreted by the handlers registered for the kind on job service.
+type Job struct {
+	ID           int64     `orm:"column(id)" json:"id"`
+	Kind         string    `orm:"column(kind)" json:"kind"`
+	Status       string    `orm:"column(status)" json:"status"`
+	Parameters   string    `orm:"column(parameters)" json:"parameters"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	UpdateTime   time.Time `orm:"column(update_time);auto_now" json:"update_time"`
+}
+
+//TableName is required by beego orm to map Job to table job
+func (j *Job) TableName() string {
+	return "job"
+}
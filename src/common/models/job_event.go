@@ -0,0 +1,37 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// JobEvent records a transition of the state machine which handles a job, Message
+// is not empty if the transition failed. The failure of the handler of a state is
+// recorded as an event from the state to itself. Job IDs are unique within a kind.
+type JobEvent struct {
+	ID           int64     `orm:"column(id)" json:"id"`
+	Kind         string    `orm:"column(kind)" json:"kind"`
+	JobID        int64     `orm:"column(job_id)" json:"job_id"`
+	FromState    string    `orm:"column(from_state)" json:"from_state"`
+	ToState      string    `orm:"column(to_state)" json:"to_state"`
+	Message      string    `orm:"column(message)" json:"message,omitempty"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+}
+
+//TableName is required by beego orm to map JobEvent to table job_event
+func (j *JobEvent) TableName() string {
+	return "job_event"
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// lockout operations
+const (
+	LockoutOpLock   = "lock"
+	LockoutOpUnlock = "unlock"
+)
+
+// AccountLockout holds the login failures of a username, it is shared by all
+// the UI instances
+type AccountLockout struct {
+	ID       int64  `orm:"column(id)" json:"-"`
+	Username string `orm:"column(username)" json:"username"`
+	// Failures is the number of the login failures since FirstFailure
+	Failures     int       `orm:"column(failures)" json:"failures"`
+	FirstFailure time.Time `orm:"column(first_failure)" json:"first_failure"`
+	LockedUntil  time.Time `orm:"column(locked_until)" json:"locked_until"`
+}
+
+//TableName is required by beego orm to map AccountLockout to table account_lockout
+func (a *AccountLockout) TableName() string {
+	return "account_lockout"
+}
+
+// LockoutAudit records an account is locked or unlocked
+type LockoutAudit struct {
+	ID        int64  `orm:"column(id)" json:"id"`
+	Username  string `orm:"column(username)" json:"username"`
+	Operation string `orm:"column(operation)" json:"operation"`
+	// Operator is the admin who unlocks the account, it is empty if the
+	// account is locked by the login failures
+	Operator string    `orm:"column(operator)" json:"operator"`
+	OpTime   time.Time `orm:"column(op_time);auto_now_add" json:"op_time"`
+}
+
+//TableName is required by beego orm to map LockoutAudit to table lockout_audit
+func (l *LockoutAudit) TableName() string {
+	return "lockout_audit"
+}
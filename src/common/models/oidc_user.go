@@ -0,0 +1,37 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// OIDCUser links a user onboarded from the OpenID Connect provider to the
+// subject of the provider. The CLI secret is used by docker clients instead
+// of the password, only its hash is persisted.
+type OIDCUser struct {
+	ID           int64     `orm:"column(id)" json:"id"`
+	UserID       int       `orm:"column(user_id)" json:"user_id"`
+	Issuer       string    `orm:"column(issuer)" json:"issuer"`
+	Subject      string    `orm:"column(subject)" json:"subject"`
+	SecretHash   string    `orm:"column(secret_hash)" json:"-"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	UpdateTime   time.Time `orm:"column(update_time);auto_now" json:"update_time"`
+}
+
+//TableName is required by beego orm to map OIDCUser to table oidc_user
+func (o *OIDCUser) TableName() string {
+	return "oidc_user"
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// PasswordHistory records a password hash of a user in the database, the
+// latest one is the current password and its creation time is when the
+// password is changed
+type PasswordHistory struct {
+	ID           int64     `orm:"column(id)" json:"-"`
+	UserID       int       `orm:"column(user_id)" json:"user_id"`
+	PasswordHash string    `orm:"column(password_hash)" json:"-"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+}
+
+//TableName is required by beego orm to map PasswordHistory to table password_history
+func (p *PasswordHistory) TableName() string {
+	return "password_history"
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken is issued by the token service in the OAuth2 password grant
+// flow, clients such as docker credential helpers store it as identity token
+// to get access tokens later. Only the hash of the token is persisted.
+type RefreshToken struct {
+	ID           int64     `orm:"column(id)" json:"id"`
+	UserID       int       `orm:"column(user_id)" json:"user_id"`
+	TokenHash    string    `orm:"column(token_hash)" json:"-"`
+	ClientID     string    `orm:"column(client_id)" json:"client_id"`
+	Service      string    `orm:"column(service)" json:"service"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	LastUsedTime time.Time `orm:"column(last_used_time);auto_now" json:"last_used_time"`
+}
+
+//TableName is required by beego orm to map RefreshToken to table refresh_token
+func (r *RefreshToken) TableName() string {
+	return "refresh_token"
+}
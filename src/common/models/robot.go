@@ -0,0 +1,54 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+const (
+	// RobotAccessPull allows the robot account to pull the repositories
+	RobotAccessPull = "pull"
+	// RobotAccessPush allows the robot account to pull and push the repositories
+	RobotAccessPush = "push"
+)
+
+// Robot is an account of a project used by the automation such as CI
+// pipelines. It authenticates with the generated name and secret and can only
+// access the repositories of the project. Only the hash of the secret is
+// persisted. ExpiresAt is a unix timestamp, 0 means the robot never expires.
+type Robot struct {
+	ID           int64     `orm:"pk;auto;column(id)" json:"id"`
+	Name         string    `orm:"column(name)" json:"name"`
+	Description  string    `orm:"column(description)" json:"description"`
+	ProjectID    int64     `orm:"column(project_id)" json:"project_id"`
+	SecretHash   string    `orm:"column(secret_hash)" json:"-"`
+	Access       string    `orm:"column(access)" json:"access"`
+	CreatorID    int       `orm:"column(creator_id)" json:"creator_id"`
+	ExpiresAt    int64     `orm:"column(expires_at)" json:"expires_at"`
+	Revoked      bool      `orm:"column(revoked)" json:"revoked"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	UpdateTime   time.Time `orm:"column(update_time);auto_now" json:"update_time"`
+}
+
+//TableName is required by beego orm to map Robot to table robot
+func (r *Robot) TableName() string {
+	return "robot"
+}
+
+// Expired returns whether the robot account has expired
+func (r *Robot) Expired() bool {
+	return r.ExpiresAt > 0 && time.Now().Unix() >= r.ExpiresAt
+}
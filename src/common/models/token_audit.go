@@ -0,0 +1,38 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// TokenAudit records the access requested in a token request which is denied
+// or reduced by the token service
+type TokenAudit struct {
+	ID       int64  `orm:"column(id)" json:"id"`
+	Username string `orm:"column(username)" json:"username"`
+	IP       string `orm:"column(ip)" json:"ip"`
+	Service  string `orm:"column(service)" json:"service"`
+	// Scope is the requested one, e.g. repository:library/ubuntu:pull,push
+	Scope string `orm:"column(scope)" json:"scope"`
+	// GrantedActions is the actions granted, separated by comma
+	GrantedActions string    `orm:"column(granted_actions)" json:"granted_actions"`
+	OpTime         time.Time `orm:"column(op_time);auto_now_add" json:"op_time"`
+}
+
+//TableName is required by beego orm to map TokenAudit to table token_audit
+func (t *TokenAudit) TableName() string {
+	return "token_audit"
+}
@@ -38,6 +38,8 @@ type User struct {
 	Salt         string    `orm:"column(api_token)" json:"-"`
 	CreationTime time.Time `orm:"creation_time" json:"creation_time"`
 	UpdateTime   time.Time `orm:"last_login_time" json:"update_time"`
+	// AuthSource is the auth mode which authenticated the user, e.g. db_auth
+	AuthSource string `orm:"column(auth_source)" json:"auth_source"`
 }
 
 /* Original database schema
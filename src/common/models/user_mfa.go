@@ -0,0 +1,44 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// UserMFA holds the second factor of a user in the database. The TOTP secret
+// is encrypted with the secret key, the recovery codes and the CLI secret,
+// which is used by the API and docker clients instead of the password, are
+// persisted as hashes.
+type UserMFA struct {
+	ID     int64  `orm:"column(id)" json:"-"`
+	UserID int    `orm:"column(user_id)" json:"user_id"`
+	Secret string `orm:"column(secret)" json:"-"`
+	// Enabled is false until the enrollment is confirmed with a code
+	Enabled bool `orm:"column(enabled)" json:"enabled"`
+	// LastStep is the time step of the last accepted code, the codes of it
+	// and the earlier steps are rejected to avoid replay
+	LastStep int64 `orm:"column(last_step)" json:"-"`
+	// RecoveryCodes is the hashes of the unused recovery codes, separated by comma
+	RecoveryCodes string    `orm:"column(recovery_codes)" json:"-"`
+	CLISecretHash string    `orm:"column(cli_secret_hash)" json:"-"`
+	CreationTime  time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	UpdateTime    time.Time `orm:"column(update_time);auto_now" json:"update_time"`
+}
+
+//TableName is required by beego orm to map UserMFA to table user_mfa
+func (u *UserMFA) TableName() string {
+	return "user_mfa"
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package models
+
+import (
+	"time"
+)
+
+// UserSession tracks a web session of a user, so the sessions can be listed
+// and revoked. Only the hash of the session ID is persisted.
+type UserSession struct {
+	ID           int64     `orm:"pk;auto;column(id)" json:"id"`
+	UserID       int       `orm:"column(user_id)" json:"user_id"`
+	SessionHash  string    `orm:"column(session_hash)" json:"-"`
+	IP           string    `orm:"column(ip)" json:"ip"`
+	UserAgent    string    `orm:"column(user_agent)" json:"user_agent"`
+	CreationTime time.Time `orm:"column(creation_time);auto_now_add" json:"creation_time"`
+	LastSeenTime time.Time `orm:"column(last_seen_time)" json:"last_seen_time"`
+	// Current marks the session of the request listing the sessions
+	Current bool `orm:"-" json:"current"`
+}
+
+//TableName is required by beego orm to map UserSession to table user_session
+func (u *UserSession) TableName() string {
+	return "user_session"
+}
@@ -23,6 +23,7 @@ import (
 
 	"crypto/tls"
 
+	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
@@ -35,16 +36,22 @@ import (
 func GetSystemLdapConf() (models.LdapConf, error) {
 	var err error
 	var ldapConfs models.LdapConf
-	var authMode string
+	var chain []string
 
-	authMode, err = config.AuthMode()
+	chain, err = config.AuthChain()
 	if err != nil {
 		log.Errorf("can't load auth mode from system, error: %v", err)
 		return ldapConfs, err
 	}
 
-	if authMode != "ldap_auth" {
-		return ldapConfs, fmt.Errorf("system auth_mode isn't ldap_auth, please check configuration")
+	enabled := false
+	for _, mode := range chain {
+		if mode == common.LDAPAuth {
+			enabled = true
+		}
+	}
+	if !enabled {
+		return ldapConfs, fmt.Errorf("ldap_auth isn't in system auth_mode or auth_chain, please check configuration")
 	}
 
 	ldap, err := config.LDAP()
@@ -300,6 +307,7 @@ func ImportUser(user models.LdapUser) (int64, error) {
 
 	u.Password = "12345678AbC"
 	u.Comment = "from LDAP."
+	u.AuthSource = common.LDAPAuth
 	if u.Email == "" {
 		u.Email = u.Username + "@placeholder.com"
 	}
@@ -0,0 +1,113 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package password hashes the passwords of the users in the database. The
+// hashes are prefixed with the name of the algorithm, so the hashes of
+// different algorithms or costs can coexist and be upgraded on login.
+package password
+
+import (
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"strings"
+	"sync"
+
+	"github.com/vmware/harbor/src/common/utils"
+)
+
+const separator = "$"
+
+// Hasher hashes passwords with an algorithm
+type Hasher interface {
+	// Algorithm returns the name of the algorithm, which prefixes the hashes
+	Algorithm() string
+	// Hash returns the encoded hash of the password
+	Hash(password string) (string, error)
+	// Verify returns whether the password matches the encoded hash and
+	// whether the hash should be upgraded as it is weaker than the ones
+	// the hasher creates
+	Verify(password, encoded string) (bool, bool)
+}
+
+var (
+	lock          sync.RWMutex
+	hashers       = map[string]Hasher{}
+	defaultHasher Hasher
+)
+
+// Register adds the hasher so the hashes of its algorithm can be verified
+func Register(hasher Hasher) {
+	lock.Lock()
+	defer lock.Unlock()
+	hashers[hasher.Algorithm()] = hasher
+}
+
+// SetDefault registers the hasher and uses it to hash the passwords
+func SetDefault(hasher Hasher) {
+	Register(hasher)
+	lock.Lock()
+	defer lock.Unlock()
+	defaultHasher = hasher
+}
+
+// Hash hashes the password with the default hasher
+func Hash(password string) (string, error) {
+	lock.RLock()
+	hasher := defaultHasher
+	lock.RUnlock()
+	return hasher.Hash(password)
+}
+
+// Verify returns whether the password matches the encoded hash and whether
+// the hash should be replaced by the one of the default hasher. The hashes
+// created before the hashers were introduced have no prefix, they are the
+// MD5 of the password or the salted PBKDF2-SHA1 of utils.Encrypt, and they
+// always need to be upgraded.
+func Verify(password, encoded, legacySalt string) (bool, bool) {
+	i := strings.Index(encoded, separator)
+	if i < 0 {
+		return verifyLegacy(password, encoded, legacySalt), true
+	}
+
+	lock.RLock()
+	hasher, ok := hashers[encoded[:i]]
+	current := defaultHasher
+	lock.RUnlock()
+	if !ok {
+		return false, false
+	}
+
+	match, weak := hasher.Verify(password, encoded)
+	if !match {
+		return false, false
+	}
+	return true, weak || hasher.Algorithm() != current.Algorithm()
+}
+
+func verifyLegacy(password, encoded, salt string) bool {
+	sum := md5.Sum([]byte(password))
+	if equal(hex.EncodeToString(sum[:]), encoded) {
+		return true
+	}
+	return len(salt) > 0 && equal(utils.Encrypt(password, salt), encoded)
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func init() {
+	SetDefault(NewPBKDF2(DefaultPBKDF2Iterations))
+}
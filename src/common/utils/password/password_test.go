@@ -0,0 +1,84 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/utils"
+)
+
+func TestPBKDF2(t *testing.T) {
+	hasher := NewPBKDF2(1000)
+	hash, err := hasher.Hash("Harbor12345")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	assert.True(t, strings.HasPrefix(hash, "pbkdf2_sha256$1000$"))
+
+	another, err := hasher.Hash("Harbor12345")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	assert.NotEqual(t, hash, another, "the hashes should be salted")
+
+	ok, weak := hasher.Verify("Harbor12345", hash)
+	assert.True(t, ok)
+	assert.False(t, weak)
+	ok, _ = hasher.Verify("harbor12345", hash)
+	assert.False(t, ok)
+
+	ok, weak = NewPBKDF2(2000).Verify("Harbor12345", hash)
+	assert.True(t, ok)
+	assert.True(t, weak, "the hash of less iterations should be upgraded")
+
+	for _, invalid := range []string{"", "pbkdf2_sha256$1000$salt", "pbkdf2_sha256$x$c2FsdA$a2V5", "md5$1000$c2FsdA$a2V5"} {
+		ok, _ = hasher.Verify("Harbor12345", invalid)
+		assert.False(t, ok, invalid)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	defer SetDefault(NewPBKDF2(DefaultPBKDF2Iterations))
+	SetDefault(NewPBKDF2(1000))
+
+	hash, err := Hash("Harbor12345")
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	ok, rehash := Verify("Harbor12345", hash, "")
+	assert.True(t, ok)
+	assert.False(t, rehash)
+	ok, _ = Verify("Harbor1234", hash, "")
+	assert.False(t, ok)
+
+	// the legacy hashes are accepted and should be upgraded
+	ok, rehash = Verify("Harbor12345", "c02674aa52055094393a898f44d9b91f", "")
+	assert.True(t, ok)
+	assert.True(t, rehash)
+	ok, _ = Verify("Harbor12345", "a2fa84bfae736e573581d4b0342a2eac", "")
+	assert.False(t, ok)
+	ok, rehash = Verify("Harbor12345", utils.Encrypt("Harbor12345", "salt"), "salt")
+	assert.True(t, ok)
+	assert.True(t, rehash)
+	ok, _ = Verify("Harbor12345", utils.Encrypt("Harbor12345", "salt"), "")
+	assert.False(t, ok)
+
+	// unknown algorithm
+	ok, _ = Verify("Harbor12345", "unknown$Harbor12345", "")
+	assert.False(t, ok)
+}
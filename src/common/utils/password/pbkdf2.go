@@ -0,0 +1,96 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package password
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// PBKDF2SHA256 is the name of the PBKDF2 algorithm with SHA256
+	PBKDF2SHA256 = "pbkdf2_sha256"
+	// DefaultPBKDF2Iterations is used if the iterations are not configured
+	DefaultPBKDF2Iterations = 10000
+
+	pbkdf2SaltLen = 16
+	pbkdf2KeyLen  = 32
+)
+
+// PBKDF2 hashes the passwords with PBKDF2-SHA256, the hash is encoded as
+// pbkdf2_sha256$iterations$salt$key with the salt and key in base64
+type PBKDF2 struct {
+	iterations int
+}
+
+// NewPBKDF2 returns a PBKDF2 hasher which iterates the specified times, the
+// default iterations are used if it is not positive
+func NewPBKDF2(iterations int) *PBKDF2 {
+	if iterations <= 0 {
+		iterations = DefaultPBKDF2Iterations
+	}
+	return &PBKDF2{
+		iterations: iterations,
+	}
+}
+
+// Algorithm ...
+func (p *PBKDF2) Algorithm() string {
+	return PBKDF2SHA256
+}
+
+// Hash ...
+func (p *PBKDF2) Hash(password string) (string, error) {
+	salt := make([]byte, pbkdf2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := pbkdf2.Key([]byte(password), salt, p.iterations, pbkdf2KeyLen, sha256.New)
+	return fmt.Sprintf("%s$%d$%s$%s", PBKDF2SHA256, p.iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify ...
+func (p *PBKDF2) Verify(password, encoded string) (bool, bool) {
+	parts := strings.Split(encoded, separator)
+	if len(parts) != 4 || parts[0] != PBKDF2SHA256 {
+		return false, false
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return false, false
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, false
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+
+	k := pbkdf2.Key([]byte(password), salt, iterations, len(key), sha256.New)
+	if !equal(string(k), string(key)) {
+		return false, false
+	}
+	return true, iterations < p.iterations
+}
@@ -0,0 +1,203 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	dist_digest "github.com/docker/distribution/digest"
+	"github.com/vmware/harbor/src/common/utils/log"
+	registry_error "github.com/vmware/harbor/src/common/utils/registry/error"
+)
+
+const maxBlobResumes = 5
+
+// the interval before resuming the download, it is multiplied by the
+// number of consecutive failures
+var blobResumeInterval = time.Second
+
+// DigestMismatchError is returned when the digest of the content
+// does not match the expected one
+type DigestMismatchError struct {
+	Expected string
+	Actual   string
+}
+
+func (d *DigestMismatchError) Error() string {
+	return fmt.Sprintf("digest mismatch, expected: %s, actual: %s", d.Expected, d.Actual)
+}
+
+// PullBlobWithResume is similar to PullBlob, but if the download breaks, the
+// returned data resumes it from the last byte read by sending a Range request.
+// The digest of the whole blob is verified and a DigestMismatchError rather than
+// io.EOF is returned by the last Read if it does not match.
+// Client must close data if it is not nil.
+func (r *Repository) PullBlobWithResume(dgt string) (size int64, data io.ReadCloser, err error) {
+	d, err := dist_digest.ParseDigest(dgt)
+	if err != nil {
+		return
+	}
+
+	resp, err := r.getBlob(dgt, 0)
+	if err != nil {
+		return
+	}
+
+	size, err = strconv.ParseInt(resp.Header.Get(http.CanonicalHeaderKey("Content-Length")), 10, 64)
+	if err != nil {
+		resp.Body.Close()
+		return
+	}
+
+	data = &resumableBlobReader{
+		repository: r,
+		digest:     d,
+		hash:       d.Algorithm().Hash(),
+		size:       size,
+		body:       resp.Body,
+	}
+	return
+}
+
+// getBlob gets the content of the blob from the offset
+func (r *Repository) getBlob(dgt string, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest("GET", buildBlobURL(r.Endpoint.String(), r.Name, dgt), nil)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		req.Header.Set(http.CanonicalHeaderKey("Range"), fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		// the registry ignores the Range header, skip the bytes already read
+		if offset > 0 {
+			if _, err = io.CopyN(ioutil.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		}
+		return resp, nil
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		contentRange := resp.Header.Get(http.CanonicalHeaderKey("Content-Range"))
+		if !strings.HasPrefix(contentRange, fmt.Sprintf("bytes %d-", offset)) {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected Content-Range %s for offset %d", contentRange, offset)
+		}
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return nil, &registry_error.Error{
+		StatusCode: resp.StatusCode,
+		Detail:     string(b),
+	}
+}
+
+type resumableBlobReader struct {
+	repository *Repository
+	digest     dist_digest.Digest
+	hash       hash.Hash
+	size       int64
+	offset     int64
+	body       io.ReadCloser
+	// the number of consecutive failures
+	failures int
+}
+
+func (r *resumableBlobReader) Read(p []byte) (int, error) {
+	for {
+		if r.body == nil {
+			if err := r.resume(); err != nil {
+				return 0, err
+			}
+		}
+
+		n, err := r.body.Read(p)
+		if n > 0 {
+			r.hash.Write(p[:n])
+			r.offset += int64(n)
+			r.failures = 0
+		}
+
+		if err == nil {
+			return n, nil
+		}
+
+		if err == io.EOF && r.offset >= r.size {
+			if r.offset > r.size {
+				return n, fmt.Errorf("blob %s is larger than %d bytes", r.digest, r.size)
+			}
+			if actual := dist_digest.NewDigest(r.digest.Algorithm(), r.hash); actual != r.digest {
+				return n, &DigestMismatchError{
+					Expected: r.digest.String(),
+					Actual:   actual.String(),
+				}
+			}
+			return n, io.EOF
+		}
+
+		// the download breaks, resume it in the next loop or call
+		log.Warningf("download of blob %s breaks at %d/%d: %v", r.digest, r.offset, r.size, err)
+		r.body.Close()
+		r.body = nil
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *resumableBlobReader) resume() error {
+	for {
+		r.failures++
+		if r.failures > maxBlobResumes {
+			return fmt.Errorf("failed to download blob %s after %d resumes", r.digest, maxBlobResumes)
+		}
+		time.Sleep(time.Duration(r.failures) * blobResumeInterval)
+
+		log.Infof("resuming download of blob %s from %d", r.digest, r.offset)
+		resp, err := r.repository.getBlob(r.digest.String(), r.offset)
+		if err != nil {
+			log.Warningf("failed to resume download of blob %s: %v", r.digest, err)
+			continue
+		}
+		r.body = resp.Body
+		return nil
+	}
+}
+
+func (r *resumableBlobReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	return r.body.Close()
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dist_digest "github.com/docker/distribution/digest"
+)
+
+// newBlobServer returns a server which breaks the connection after sending
+// half of the content for the first breaks requests and serves the others
+// with the support of Range requests
+func newBlobServer(t *testing.T, content []byte, breaks int) *httptest.Server {
+	count := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count <= breaks {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Errorf("failed to hijack the connection: %v", err)
+				return
+			}
+			defer conn.Close()
+			half := content[:len(content)/2]
+			if r.Header.Get("Range") != "" {
+				half = []byte{}
+			}
+			fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Length: %d\r\n\r\n", len(content))
+			conn.Write(half)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestPullBlobWithResume(t *testing.T) {
+	interval := blobResumeInterval
+	blobResumeInterval = time.Millisecond
+	defer func() {
+		blobResumeInterval = interval
+	}()
+
+	content := bytes.Repeat([]byte("blob"), 1024)
+	dgt := dist_digest.FromBytes(content).String()
+
+	server := newBlobServer(t, content, 2)
+	defer server.Close()
+
+	client, err := newRepository(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client for repository: %v", err)
+	}
+
+	size, data, err := client.PullBlobWithResume(dgt)
+	if err != nil {
+		t.Fatalf("failed to pull blob: %v", err)
+	}
+	defer data.Close()
+
+	if size != int64(len(content)) {
+		t.Errorf("unexpected size: %d != %d", size, len(content))
+	}
+
+	b, err := ioutil.ReadAll(data)
+	if err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("unexpected content of blob")
+	}
+}
+
+func TestPullBlobWithResumeDigestMismatch(t *testing.T) {
+	content := []byte("blob")
+	dgt := dist_digest.FromBytes([]byte("another blob")).String()
+
+	server := newBlobServer(t, content, 0)
+	defer server.Close()
+
+	client, err := newRepository(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client for repository: %v", err)
+	}
+
+	_, data, err := client.PullBlobWithResume(dgt)
+	if err != nil {
+		t.Fatalf("failed to pull blob: %v", err)
+	}
+	defer data.Close()
+
+	_, err = ioutil.ReadAll(data)
+	mismatch, ok := err.(*DigestMismatchError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mismatch.Expected != dgt || mismatch.Actual != dist_digest.FromBytes(content).String() {
+		t.Errorf("unexpected digests: %v", mismatch)
+	}
+}
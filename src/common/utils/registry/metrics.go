@@ -0,0 +1,104 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultMetrics records the requests sent by the clients created by
+// NewRegistryWithModifiers and NewRepositoryWithModifiers
+var DefaultMetrics = NewMetrics()
+
+// RequestMetrics is the statistics of the requests with a certain method
+type RequestMetrics struct {
+	// Count is the number of attempts, including retries
+	Count int64 `json:"count"`
+	// Retries is the number of retries
+	Retries int64 `json:"retries"`
+	// Errors is the number of attempts which got no response
+	Errors int64 `json:"errors"`
+	// Statuses counts the attempts by the status code of response
+	Statuses map[int]int64 `json:"statuses"`
+	// TotalLatency and MaxLatency are in milliseconds
+	TotalLatency int64 `json:"total_latency_ms"`
+	MaxLatency   int64 `json:"max_latency_ms"`
+}
+
+// Metrics records the latency and status of requests sent to registries
+type Metrics struct {
+	requests map[string]*RequestMetrics
+	lock     *sync.Mutex
+}
+
+// NewMetrics returns an instance of Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests: map[string]*RequestMetrics{},
+		lock:     &sync.Mutex{},
+	}
+}
+
+// Snapshot returns a copy of the metrics keyed by the request method
+func (m *Metrics) Snapshot() map[string]RequestMetrics {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	snapshot := map[string]RequestMetrics{}
+	for method, rm := range m.requests {
+		s := *rm
+		s.Statuses = map[int]int64{}
+		for code, count := range rm.Statuses {
+			s.Statuses[code] = count
+		}
+		snapshot[method] = s
+	}
+	return snapshot
+}
+
+func (m *Metrics) get(method string) *RequestMetrics {
+	rm, ok := m.requests[method]
+	if !ok {
+		rm = &RequestMetrics{
+			Statuses: map[int]int64{},
+		}
+		m.requests[method] = rm
+	}
+	return rm
+}
+
+func (m *Metrics) observe(method string, resp *http.Response, err error, latency time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	rm := m.get(method)
+	rm.Count++
+	if err != nil || resp == nil {
+		rm.Errors++
+	} else {
+		rm.Statuses[resp.StatusCode]++
+	}
+	ms := int64(latency / time.Millisecond)
+	rm.TotalLatency += ms
+	if ms > rm.MaxLatency {
+		rm.MaxLatency = ms
+	}
+}
+
+func (m *Metrics) retry(method string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.get(method).Retries++
+}
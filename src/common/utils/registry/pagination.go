@@ -0,0 +1,127 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/harbor/src/common/utils"
+	registry_error "github.com/vmware/harbor/src/common/utils/registry/error"
+)
+
+// getPage gets one page of a list from the registry and decodes it into v,
+// the URL of the next page indicated by the Link header is returned and
+// it is empty if the page is the last one
+func getPage(client *http.Client, endpoint *url.URL, u string, v interface{}) (string, error) {
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", parseError(err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &registry_error.Error{
+			StatusCode: resp.StatusCode,
+			Detail:     string(b),
+		}
+	}
+
+	if err = json.Unmarshal(b, v); err != nil {
+		return "", err
+	}
+
+	//Link: </v2/_catalog?last=library%2Fhello-world-25&n=100>; rel="next"
+	next := utils.ParseLink(resp.Header.Get(http.CanonicalHeaderKey("link"))).Next()
+	if len(next) == 0 {
+		return "", nil
+	}
+
+	nu, err := url.Parse(next)
+	if err != nil {
+		return "", err
+	}
+	if nu.IsAbs() {
+		return next, nil
+	}
+	// the link returned by registry is relative to the root
+	return strings.TrimSuffix(endpoint.String(), "/") + "/" + strings.TrimPrefix(next, "/"), nil
+}
+
+// buildPaginationQuery builds the query string for listing at most n items
+// after last, the parameter is omitted if n <= 0 or last is empty
+func buildPaginationQuery(n int, last string) string {
+	values := url.Values{}
+	if n > 0 {
+		values.Set("n", strconv.Itoa(n))
+	}
+	if len(last) > 0 {
+		values.Set("last", last)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// parseLast returns the value of parameter "last" in the URL of next page
+func parseLast(next string) (string, error) {
+	if len(next) == 0 {
+		return "", nil
+	}
+	u, err := url.Parse(next)
+	if err != nil {
+		return "", err
+	}
+	return u.Query().Get("last"), nil
+}
+
+// trimPage makes sure the page contains at most n items after last, as the
+// registry may not support pagination and return all items in one page.
+// The last for getting the next page is returned: it is the last item of the
+// page if the page is cut, otherwise the one parsed from the Link header.
+func trimPage(items []string, n int, last, nextLast string) ([]string, string) {
+	if len(last) > 0 {
+		for i, item := range items {
+			if item == last {
+				// the registry ignored the last and listed from the beginning,
+				// so the Link header can not be trusted either
+				items = items[i+1:]
+				nextLast = ""
+				break
+			}
+		}
+	}
+
+	if n > 0 && len(items) > n {
+		items = items[:n]
+		nextLast = items[n-1]
+	}
+	return items, nextLast
+}
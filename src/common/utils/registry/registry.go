@@ -16,17 +16,18 @@ package registry
 
 import (
 	"crypto/tls"
-	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"strings"
 	// "time"
 
 	"github.com/vmware/harbor/src/common/utils"
 	registry_error "github.com/vmware/harbor/src/common/utils/registry/error"
 )
 
+const catalogPageSize = 1000
+
 // Registry holds information of a registry entity
 type Registry struct {
 	Endpoint *url.URL
@@ -74,7 +75,8 @@ func NewRegistry(endpoint string, client *http.Client) (*Registry, error) {
 // NewRegistryWithModifiers returns an instance of Registry according to the modifiers
 func NewRegistryWithModifiers(endpoint string, insecure bool, modifiers ...Modifier) (*Registry, error) {
 
-	transport := NewTransport(GetHTTPTransport(insecure), modifiers...)
+	transport := NewTransport(NewRetryTransport(GetHTTPTransport(insecure), DefaultMetrics),
+		modifiers...)
 
 	return NewRegistry(endpoint, &http.Client{
 		Transport: transport,
@@ -84,57 +86,51 @@ func NewRegistryWithModifiers(endpoint string, insecure bool, modifiers ...Modif
 	})
 }
 
-// Catalog ...
+// Catalog lists all repositories in the registry, the Link headers are
+// followed to get all pages
 func (r *Registry) Catalog() ([]string, error) {
 	repos := []string{}
-	suffix := "/v2/_catalog?n=1000"
-	var url string
-
-	for len(suffix) > 0 {
-		url = r.Endpoint.String() + suffix
-
-		req, err := http.NewRequest("GET", url, nil)
+	next := buildCatalogURL(r.Endpoint.String(), catalogPageSize, "")
+	for len(next) > 0 {
+		catalogResp := struct {
+			Repositories []string `json:"repositories"`
+		}{}
+
+		var err error
+		next, err = getPage(r.client, r.Endpoint, next, &catalogResp)
 		if err != nil {
 			return repos, err
 		}
-		resp, err := r.client.Do(req)
-		if err != nil {
-			return nil, parseError(err)
-		}
 
-		defer resp.Body.Close()
-		b, err := ioutil.ReadAll(resp.Body)
-		if err != nil {
-			return repos, err
-		}
-
-		if resp.StatusCode == http.StatusOK {
-			catalogResp := struct {
-				Repositories []string `json:"repositories"`
-			}{}
-
-			if err := json.Unmarshal(b, &catalogResp); err != nil {
-				return repos, err
-			}
-
-			repos = append(repos, catalogResp.Repositories...)
-			//Link: </v2/_catalog?last=library%2Fhello-world-25&n=100>; rel="next"
-			link := resp.Header.Get("Link")
-			if strings.HasSuffix(link, `rel="next"`) && strings.Index(link, "<") >= 0 && strings.Index(link, ">") >= 0 {
-				suffix = link[strings.Index(link, "<")+1 : strings.Index(link, ">")]
-			} else {
-				suffix = ""
-			}
-		} else {
-			return repos, &registry_error.Error{
-				StatusCode: resp.StatusCode,
-				Detail:     string(b),
-			}
-		}
+		repos = append(repos, catalogResp.Repositories...)
 	}
 	return repos, nil
 }
 
+// CatalogWithPagination lists at most n repositories whose names are lexically
+// after last. The returned string is the last for getting the next page and
+// it is empty if there are no more repositories.
+func (r *Registry) CatalogWithPagination(n int, last string) ([]string, string, error) {
+	catalogResp := struct {
+		Repositories []string `json:"repositories"`
+	}{}
+
+	next, err := getPage(r.client, r.Endpoint, buildCatalogURL(r.Endpoint.String(), n, last), &catalogResp)
+	if err != nil {
+		return []string{}, "", err
+	}
+
+	nextLast, err := parseLast(next)
+	if err != nil {
+		return []string{}, "", err
+	}
+	if catalogResp.Repositories == nil {
+		catalogResp.Repositories = []string{}
+	}
+	repositories, nextLast := trimPage(catalogResp.Repositories, n, last, nextLast)
+	return repositories, nextLast, nil
+}
+
 // Ping ...
 func (r *Registry) Ping() error {
 	req, err := http.NewRequest("GET", buildPingURL(r.Endpoint.String()), nil)
@@ -162,3 +158,7 @@ func (r *Registry) Ping() error {
 		Detail:     string(b),
 	}
 }
+
+func buildCatalogURL(endpoint string, n int, last string) string {
+	return fmt.Sprintf("%s/v2/_catalog%s", endpoint, buildPaginationQuery(n, last))
+}
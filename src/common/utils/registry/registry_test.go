@@ -142,6 +142,22 @@ func TestCatalog(t *testing.T) {
 	if len(repos) != len(repositories) {
 		t.Errorf("unexpected length of repositories: %d != %d", len(repos), len(repositories))
 	}
+
+	repos, last, err := client.CatalogWithPagination(10, "")
+	if err != nil {
+		t.Fatalf("failed to catalog repositories: %v", err)
+	}
+	if len(repos) != 10 || last != repositories[9] {
+		t.Errorf("unexpected page: %v, last: %s", repos, last)
+	}
+
+	repos, last, err = client.CatalogWithPagination(10, repositories[995])
+	if err != nil {
+		t.Fatalf("failed to catalog repositories: %v", err)
+	}
+	if len(repos) != 5 || repos[0] != repositories[996] || len(last) != 0 {
+		t.Errorf("unexpected page: %v, last: %s", repos, last)
+	}
 }
 
 func newRegistryClient(url string) (*Registry, error) {
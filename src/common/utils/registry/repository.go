@@ -16,7 +16,6 @@ package registry
 
 import (
 	"bytes"
-	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -61,7 +60,8 @@ func NewRepository(name, endpoint string, client *http.Client) (*Repository, err
 // NewRepositoryWithModifiers returns an instance of Repository according to the modifiers
 func NewRepositoryWithModifiers(name, endpoint string, insecure bool, modifiers ...Modifier) (*Repository, error) {
 
-	transport := NewTransport(GetHTTPTransport(insecure), modifiers...)
+	transport := NewTransport(NewRetryTransport(GetHTTPTransport(insecure), DefaultMetrics),
+		modifiers...)
 	return NewRepository(name, endpoint, &http.Client{
 		Transport: transport,
 		//  for transferring large image, OS will handle i/o timeout
@@ -78,44 +78,50 @@ func parseError(err error) error {
 	return err
 }
 
-// ListTag ...
+// ListTag lists all tags of the repository, the Link headers are followed
+// to get all pages
 func (r *Repository) ListTag() ([]string, error) {
 	tags := []string{}
-	req, err := http.NewRequest("GET", buildTagListURL(r.Endpoint.String(), r.Name), nil)
-	if err != nil {
-		return tags, err
-	}
-
-	resp, err := r.client.Do(req)
-	if err != nil {
-		return tags, parseError(err)
-	}
-
-	defer resp.Body.Close()
-
-	b, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return tags, err
-	}
-
-	if resp.StatusCode == http.StatusOK {
+	next := buildTagListURL(r.Endpoint.String(), r.Name)
+	for len(next) > 0 {
 		tagsResp := struct {
 			Tags []string `json:"tags"`
 		}{}
 
-		if err := json.Unmarshal(b, &tagsResp); err != nil {
-			return tags, err
+		var err error
+		next, err = getPage(r.client, r.Endpoint, next, &tagsResp)
+		if err != nil {
+			return []string{}, err
 		}
 
-		tags = tagsResp.Tags
-
-		return tags, nil
+		tags = append(tags, tagsResp.Tags...)
 	}
-	return tags, &registry_error.Error{
-		StatusCode: resp.StatusCode,
-		Detail:     string(b),
+	return tags, nil
+}
+
+// ListTagWithPagination lists at most n tags which are lexically after last.
+// The returned string is the last for getting the next page and it is empty
+// if there are no more tags.
+func (r *Repository) ListTagWithPagination(n int, last string) ([]string, string, error) {
+	tagsResp := struct {
+		Tags []string `json:"tags"`
+	}{}
+
+	next, err := getPage(r.client, r.Endpoint,
+		buildTagListURL(r.Endpoint.String(), r.Name)+buildPaginationQuery(n, last), &tagsResp)
+	if err != nil {
+		return []string{}, "", err
 	}
 
+	nextLast, err := parseLast(next)
+	if err != nil {
+		return []string{}, "", err
+	}
+	if tagsResp.Tags == nil {
+		tagsResp.Tags = []string{}
+	}
+	tags, nextLast := trimPage(tagsResp.Tags, n, last, nextLast)
+	return tags, nextLast, nil
 }
 
 // ManifestExist ...
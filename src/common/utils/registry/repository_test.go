@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	dist_digest "github.com/docker/distribution/digest"
 	"github.com/docker/distribution/manifest/schema2"
 	registry_error "github.com/vmware/harbor/src/common/utils/registry/error"
 	"github.com/vmware/harbor/src/common/utils/test"
@@ -394,6 +395,111 @@ func TestListTag(t *testing.T) {
 	}
 }
 
+func TestListTagWithPagination(t *testing.T) {
+	allTags := []string{"a", "b", "c", "d", "e"}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		n, err := strconv.Atoi(q.Get("n"))
+		if err != nil || n <= 0 {
+			n = 2
+		}
+		begin := 0
+		if last := q.Get("last"); len(last) > 0 {
+			for i, t := range allTags {
+				if t == last {
+					begin = i + 1
+					break
+				}
+			}
+		}
+		end := begin + n
+		if end > len(allTags) {
+			end = len(allTags)
+		}
+		if end < len(allTags) {
+			w.Header().Set("Link", fmt.Sprintf("</v2/%s/tags/list?last=%s&n=%d>; rel=\"next\"",
+				repository, allTags[end-1], n))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, "{\"name\": \"%s\",\"tags\": [\"%s\"]}", repository,
+			strings.Join(allTags[begin:end], "\",\""))
+	}
+
+	server := test.NewServer(
+		&test.RequestHandlerMapping{
+			Method:  "GET",
+			Pattern: fmt.Sprintf("/v2/%s/tags/list", repository),
+			Handler: handler,
+		})
+	defer server.Close()
+
+	client, err := newRepository(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client for repository: %v", err)
+	}
+
+	tags, last, err := client.ListTagWithPagination(3, "")
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	if strings.Join(tags, ",") != "a,b,c" || last != "c" {
+		t.Errorf("unexpected page: %v, last: %s", tags, last)
+	}
+
+	tags, last, err = client.ListTagWithPagination(3, last)
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	if strings.Join(tags, ",") != "d,e" || last != "" {
+		t.Errorf("unexpected page: %v, last: %s", tags, last)
+	}
+
+	// ListTag follows the Link headers
+	tags, err = client.ListTag()
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	if strings.Join(tags, ",") != strings.Join(allTags, ",") {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+}
+
+func TestListTagWithPaginationNotSupported(t *testing.T) {
+	// the registry ignores n and last and returns all tags
+	server := test.NewServer(
+		&test.RequestHandlerMapping{
+			Method:  "GET",
+			Pattern: fmt.Sprintf("/v2/%s/tags/list", repository),
+			Handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				fmt.Fprintf(w, "{\"name\": \"%s\",\"tags\": [\"a\",\"b\",\"c\",\"d\",\"e\"]}", repository)
+			},
+		})
+	defer server.Close()
+
+	client, err := newRepository(server.URL)
+	if err != nil {
+		t.Fatalf("failed to create client for repository: %v", err)
+	}
+
+	last := ""
+	pages := []string{}
+	for {
+		var tags []string
+		tags, last, err = client.ListTagWithPagination(2, last)
+		if err != nil {
+			t.Fatalf("failed to list tags: %v", err)
+		}
+		pages = append(pages, strings.Join(tags, ","))
+		if len(last) == 0 {
+			break
+		}
+	}
+	if strings.Join(pages, "|") != "a,b|c,d|e" {
+		t.Errorf("unexpected pages: %v", pages)
+	}
+}
+
 func TestParseError(t *testing.T) {
 	err := &url.Error{
 		Err: &registry_error.Error{},
@@ -407,3 +513,71 @@ func TestParseError(t *testing.T) {
 func newRepository(endpoint string) (*Repository, error) {
 	return NewRepository(repository, endpoint, &http.Client{})
 }
+
+func TestRepositoryWithFakeRegistry(t *testing.T) {
+	server := test.NewRegistry(nil)
+	defer server.Close()
+
+	client, err := NewRepository(repository, server.URL, &http.Client{})
+	if err != nil {
+		t.Fatalf("failed to create client for repository: %v", err)
+	}
+
+	config := []byte("config")
+	layer := []byte("layer")
+	blobs := []string{}
+	for _, content := range [][]byte{config, layer} {
+		dgt := digestOf(content)
+		if err = client.PushBlob(dgt, int64(len(content)), bytes.NewReader(content)); err != nil {
+			t.Fatalf("failed to push blob %s: %v", dgt, err)
+		}
+		blobs = append(blobs, dgt)
+	}
+
+	payload := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%s","config":{"digest":"%s"},"layers":[{"digest":"%s"}]}`,
+		schema2.MediaTypeManifest, blobs[0], blobs[1]))
+	dgt, err := client.PushManifest(tag, schema2.MediaTypeManifest, payload)
+	if err != nil {
+		t.Fatalf("failed to push manifest: %v", err)
+	}
+
+	tags, err := client.ListTag()
+	if err != nil {
+		t.Fatalf("failed to list tags: %v", err)
+	}
+	if len(tags) != 1 || tags[0] != tag {
+		t.Errorf("unexpected tags: %v", tags)
+	}
+
+	d, exist, err := client.ManifestExist(tag)
+	if err != nil {
+		t.Fatalf("failed to check the existence of manifest: %v", err)
+	}
+	if !exist || d != dgt {
+		t.Errorf("unexpected existence and digest of manifest: %t %s", exist, d)
+	}
+
+	_, data, err := client.PullBlobWithResume(blobs[1])
+	if err != nil {
+		t.Fatalf("failed to pull blob: %v", err)
+	}
+	defer data.Close()
+	b, err := ioutil.ReadAll(data)
+	if err != nil {
+		t.Fatalf("failed to read blob: %v", err)
+	}
+	if !bytes.Equal(b, layer) {
+		t.Errorf("unexpected content of blob: %s", string(b))
+	}
+
+	if err = client.DeleteTag(tag); err != nil {
+		t.Fatalf("failed to delete tag: %v", err)
+	}
+	if _, exist, err = client.ManifestExist(tag); err != nil || exist {
+		t.Errorf("the manifest should be deleted: %t %v", exist, err)
+	}
+}
+
+func digestOf(content []byte) string {
+	return dist_digest.FromBytes(content).String()
+}
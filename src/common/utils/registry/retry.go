@@ -0,0 +1,203 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	defaultRetries     = 3
+	defaultBaseBackoff = 500 * time.Millisecond
+	defaultMaxBackoff  = 30 * time.Second
+)
+
+// RetryTransport retries the requests which fail due to network errors or get
+// responses with status code 429, 502, 503 or 504. Only the idempotent requests are
+// retried: GET and HEAD requests, and PUT requests for manifests whose bodies are
+// buffered to be replayed. The requests for blob upload sessions(PATCH and the PUT
+// completing the upload) are never retried as the registry tracks the state of the
+// upload.
+// The latency and status of every attempt are recorded in the metrics.
+type RetryTransport struct {
+	transport http.RoundTripper
+	metrics   *Metrics
+	// Retries is the max number of retries for a request
+	Retries int
+	// BaseBackoff is the interval before the first retry, it doubles for
+	// each following retry
+	BaseBackoff time.Duration
+	// MaxBackoff is the max interval between two attempts, a request will not
+	// be retried if the Retry-After returned by the server exceeds it
+	MaxBackoff time.Duration
+}
+
+// NewRetryTransport returns an instance of RetryTransport, the metrics can be nil
+func NewRetryTransport(transport http.RoundTripper, metrics *Metrics) *RetryTransport {
+	return &RetryTransport{
+		transport:   transport,
+		metrics:     metrics,
+		Retries:     defaultRetries,
+		BaseBackoff: defaultBaseBackoff,
+		MaxBackoff:  defaultMaxBackoff,
+	}
+}
+
+// RoundTrip ...
+func (r *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// the body of the manifest is read once and replayed on every attempt
+	var body []byte
+	if retryableMethod(req) && req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = b
+	}
+
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			r2 := new(http.Request)
+			*r2 = *req
+			r2.Body = ioutil.NopCloser(bytes.NewReader(body))
+			req = r2
+		}
+
+		start := time.Now()
+		resp, err := r.transport.RoundTrip(req)
+		if r.metrics != nil {
+			r.metrics.observe(req.Method, resp, err, time.Since(start))
+		}
+
+		if attempt >= r.Retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+
+		wait := r.backoff(attempt, resp)
+		if wait < 0 {
+			return resp, err
+		}
+
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err != nil {
+			log.Debugf("%s %s failed: %v, will retry in %v", req.Method, req.URL.String(), err, wait)
+		} else {
+			log.Debugf("%d | %s %s, will retry in %v", resp.StatusCode, req.Method, req.URL.String(), wait)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+
+		if r.metrics != nil {
+			r.metrics.retry(req.Method)
+		}
+	}
+}
+
+// backoff returns the interval before the next attempt, it returns a negative
+// value if the Retry-After returned by the server exceeds the max backoff
+func (r *RetryTransport) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if after, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if after > r.MaxBackoff {
+				return -1
+			}
+			return after
+		}
+	}
+
+	wait := r.BaseBackoff << uint(attempt)
+	if wait > r.MaxBackoff || wait <= 0 {
+		wait = r.MaxBackoff
+	}
+	return wait
+}
+
+// parseRetryAfter parses the value of header Retry-After which is either
+// delay seconds or a HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		after := t.Sub(time.Now())
+		if after < 0 {
+			after = 0
+		}
+		return after, true
+	}
+	return 0, false
+}
+
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if !retryableMethod(req) {
+		return false
+	}
+
+	if err != nil {
+		if _, ok := err.(net.Error); ok {
+			return true
+		}
+		return err == io.EOF || err == io.ErrUnexpectedEOF
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableMethod returns whether the request is idempotent: GET, HEAD and
+// the PUT of manifests
+func retryableMethod(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		return true
+	case http.MethodPut:
+		return isManifestRequest(req)
+	}
+	return false
+}
+
+// isManifestRequest returns whether the request is sent to the manifest
+// endpoint: /v2/<name>/manifests/<reference>
+func isManifestRequest(req *http.Request) bool {
+	return strings.Contains(req.URL.Path, "/manifests/")
+}
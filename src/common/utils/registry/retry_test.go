@@ -0,0 +1,241 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// flakyServer responds with the status code fail for the first failures
+// requests and 200 for the following ones
+func flakyServer(failures int, fail int, header http.Header) (*httptest.Server, *int) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		ioutil.ReadAll(r.Body)
+		if count <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(fail)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	return server, &count
+}
+
+func newTestRetryTransport(metrics *Metrics) *RetryTransport {
+	transport := NewRetryTransport(&http.Transport{}, metrics)
+	transport.BaseBackoff = time.Millisecond
+	return transport
+}
+
+func TestRetryGet(t *testing.T) {
+	server, count := flakyServer(2, http.StatusServiceUnavailable, nil)
+	defer server.Close()
+
+	metrics := NewMetrics()
+	client := &http.Client{
+		Transport: newTestRetryTransport(metrics),
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code: %d != %d", resp.StatusCode, http.StatusOK)
+	}
+	if *count != 3 {
+		t.Errorf("unexpected count of requests: %d != %d", *count, 3)
+	}
+
+	m := metrics.Snapshot()[http.MethodGet]
+	if m.Count != 3 || m.Retries != 2 || m.Errors != 0 {
+		t.Errorf("unexpected metrics: %+v", m)
+	}
+	if m.Statuses[http.StatusServiceUnavailable] != 2 || m.Statuses[http.StatusOK] != 1 {
+		t.Errorf("unexpected status counters: %v", m.Statuses)
+	}
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	server, count := flakyServer(10, http.StatusBadGateway, nil)
+	defer server.Close()
+
+	transport := newTestRetryTransport(nil)
+	transport.Retries = 2
+	client := &http.Client{
+		Transport: transport,
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("unexpected status code: %d != %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if *count != 3 {
+		t.Errorf("unexpected count of requests: %d != %d", *count, 3)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	server, count := flakyServer(1, http.StatusTooManyRequests,
+		http.Header{"Retry-After": []string{"1"}})
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: newTestRetryTransport(nil),
+	}
+	start := time.Now()
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || *count != 2 {
+		t.Errorf("unexpected result: status %d, count %d", resp.StatusCode, *count)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retry-After is not honored, retried after %v", elapsed)
+	}
+
+	// Retry-After exceeds the max backoff
+	server2, count2 := flakyServer(1, http.StatusServiceUnavailable,
+		http.Header{"Retry-After": []string{"3600"}})
+	defer server2.Close()
+	resp, err = client.Get(server2.URL)
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || *count2 != 1 {
+		t.Errorf("unexpected result: status %d, count %d", resp.StatusCode, *count2)
+	}
+}
+
+func TestRetryWriteRequests(t *testing.T) {
+	cases := []struct {
+		method string
+		path   string
+		count  int
+	}{
+		{http.MethodPut, "/v2/library/hello-world/manifests/latest", 2},
+		{http.MethodPut, "/v2/library/hello-world/blobs/uploads/uuid?digest=sha256:abc", 1},
+		{http.MethodPatch, "/v2/library/hello-world/blobs/uploads/uuid", 1},
+		{http.MethodPost, "/v2/library/hello-world/blobs/uploads/", 1},
+		{http.MethodDelete, "/v2/library/hello-world/manifests/latest", 1},
+	}
+
+	for _, c := range cases {
+		var bodies []string
+		count := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			count++
+			b, _ := ioutil.ReadAll(r.Body)
+			bodies = append(bodies, string(b))
+			if count == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		// the body can not be replayed by itself
+		req, err := http.NewRequest(c.method, server.URL+c.path, ioutil.NopCloser(bytes.NewReader([]byte("data"))))
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+
+		client := &http.Client{
+			Transport: newTestRetryTransport(nil),
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		server.Close()
+
+		if count != c.count {
+			t.Errorf("unexpected count of %s %s requests: %d != %d",
+				c.method, c.path, count, c.count)
+		}
+		for _, body := range bodies {
+			if body != "data" {
+				t.Errorf("unexpected body of %s %s request: %q", c.method, c.path, body)
+			}
+		}
+	}
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	metrics := NewMetrics()
+	transport := newTestRetryTransport(metrics)
+	transport.Retries = 1
+	client := &http.Client{
+		Transport: transport,
+	}
+	if _, err := client.Get(url); err == nil {
+		t.Errorf("an error expected")
+	}
+
+	// PATCH requests of blob uploads are never retried
+	req, err := http.NewRequest(http.MethodPatch, url, bytes.NewReader([]byte("data")))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if _, err := client.Do(req); err == nil {
+		t.Errorf("an error expected")
+	}
+
+	if m := metrics.Snapshot()[http.MethodGet]; m.Count != 2 || m.Errors != 2 || m.Retries != 1 {
+		t.Errorf("unexpected metrics of %s: %+v", http.MethodGet, m)
+	}
+	if m := metrics.Snapshot()[http.MethodPatch]; m.Count != 1 || m.Errors != 1 || m.Retries != 0 {
+		t.Errorf("unexpected metrics of %s: %+v", http.MethodPatch, m)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("5"); !ok || d != 5*time.Second {
+		t.Errorf("unexpected result: %v %t", d, ok)
+	}
+	if _, ok := parseRetryAfter(""); ok {
+		t.Errorf("unexpected result for empty value")
+	}
+	if _, ok := parseRetryAfter("invalid"); ok {
+		t.Errorf("unexpected result for invalid value")
+	}
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(date); !ok || d <= 0 || d > time.Hour {
+		t.Errorf("unexpected result for %s: %v %t", date, d, ok)
+	}
+}
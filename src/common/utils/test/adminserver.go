@@ -26,6 +26,7 @@ import (
 var adminServerDefaultConfig = map[string]interface{}{
 	common.ExtEndpoint:                "https://host01.com",
 	common.AUTHMode:                   common.DBAuth,
+	common.AUTHChain:                  "",
 	common.DatabaseType:               "mysql",
 	common.MySQLHost:                  "127.0.0.1",
 	common.MySQLPort:                  3306,
@@ -59,6 +60,27 @@ var adminServerDefaultConfig = map[string]interface{}{
 	common.AdminInitialPassword:       "password",
 	common.AdmiralEndpoint:            "http://www.vmware.com",
 	common.WithNotary:                 false,
+	common.TokenAuditAccessLog:        false,
+	common.OIDCEndpoint:               "",
+	common.OIDCClientID:               "",
+	common.OIDCClientSecret:           "",
+	common.OIDCScope:                  "openid,profile,email",
+	common.OIDCGroupsClaim:            "groups",
+	common.OIDCGroupRoleMapping:       "",
+	common.OIDCVerifyCert:             true,
+	common.ProxyAuthTrustedCIDRs:      "",
+	common.ProxyAuthSecret:            "",
+	common.ProxyAuthGroupRoleMapping:  "",
+	common.ProxyAuthTokenService:      false,
+	common.LockoutThreshold:           5,
+	common.LockoutWindow:              15,
+	common.LockoutDuration:            30,
+	common.PasswordHashIterations:     10000,
+	common.MFAEnforceAdmin:            false,
+	common.PasswordMinLength:          8,
+	common.PasswordRequiredClasses:    "",
+	common.PasswordHistory:            0,
+	common.PasswordMaxAge:             0,
 }
 
 // NewAdminserver returns a mock admin server
@@ -0,0 +1,723 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/harbor/src/common/models"
+)
+
+// media types of manifests supported by the fake registry
+const (
+	MediaTypeManifestV1       = "application/vnd.docker.distribution.manifest.v1+json"
+	MediaTypeSignedManifestV1 = "application/vnd.docker.distribution.manifest.v1+prettyjws"
+	MediaTypeManifestV2       = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeManifestList     = "application/vnd.docker.distribution.manifest.list.v2+json"
+)
+
+// RegistryOptions are the options of the fake registry
+type RegistryOptions struct {
+	// TokenRealm enables the token authentication if it is set, the requests
+	// without bearer token are challenged with the realm and TokenService
+	TokenRealm   string
+	TokenService string
+	// TokenValidator validates the bearer token against the scope requested,
+	// e.g. "repository:library/hello-world:pull,push", any token is valid if
+	// it is nil
+	TokenValidator func(token, scope string) bool
+	// NotificationEndpoint is the URL which the events are sent to if it is set
+	NotificationEndpoint string
+	// DisablePagination makes the registry ignore the parameters "n" and "last"
+	// and list all items in one page as the registries which do not support
+	// pagination do
+	DisablePagination bool
+}
+
+// Registry is an in-memory Docker Registry v2 server which supports catalog,
+// tag listing, manifests(schema1, schema2 and manifest list), blob upload
+// (monolithic and chunked), cross repository mount and deletion. The manifests
+// are returned as they were pushed, no conversion is done.
+type Registry struct {
+	*httptest.Server
+	options      *RegistryOptions
+	lock         *sync.Mutex
+	blobs        map[string][]byte // key: digest
+	repositories map[string]*fakeRepository
+	uploads      map[string]*bytes.Buffer // key: uuid
+	events       []models.Event
+}
+
+type fakeManifest struct {
+	mediaType string
+	payload   []byte
+}
+
+type fakeRepository struct {
+	blobs     map[string]bool          // digests of the blobs linked to the repository
+	manifests map[string]*fakeManifest // key: digest
+	tags      map[string]string        // key: tag, value: digest
+}
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		blobs:     map[string]bool{},
+		manifests: map[string]*fakeManifest{},
+		tags:      map[string]string{},
+	}
+}
+
+type registryError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewRegistry starts a fake registry, the options can be nil
+func NewRegistry(options *RegistryOptions) *Registry {
+	if options == nil {
+		options = &RegistryOptions{}
+	}
+	r := &Registry{
+		options:      options,
+		lock:         &sync.Mutex{},
+		blobs:        map[string][]byte{},
+		repositories: map[string]*fakeRepository{},
+		uploads:      map[string]*bytes.Buffer{},
+	}
+	r.Server = httptest.NewUnstartedServer(http.HandlerFunc(r.serveHTTP))
+	r.Server.Start()
+	return r
+}
+
+// AddBlob stores the content as a blob of the repository and returns its digest
+func (r *Registry) AddBlob(repository string, content []byte) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	digest := digestOf(content)
+	r.blobs[digest] = content
+	r.repository(repository, true).blobs[digest] = true
+	return digest
+}
+
+// AddManifest stores the manifest in the repository without checking the
+// blobs it references, the tag can be empty. The digest of the manifest is returned.
+func (r *Registry) AddManifest(repository, tag, mediaType string, payload []byte) string {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	digest := digestOf(payload)
+	repo := r.repository(repository, true)
+	repo.manifests[digest] = &fakeManifest{
+		mediaType: mediaType,
+		payload:   payload,
+	}
+	if len(tag) != 0 {
+		repo.tags[tag] = digest
+	}
+	return digest
+}
+
+// Events returns the events which have occurred
+func (r *Registry) Events() []models.Event {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	events := make([]models.Event, len(r.events))
+	copy(events, r.events)
+	return events
+}
+
+func (r *Registry) repository(name string, create bool) *fakeRepository {
+	repo, ok := r.repositories[name]
+	if !ok && create {
+		repo = newFakeRepository()
+		r.repositories[name] = repo
+	}
+	return repo
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	if !strings.HasPrefix(path, "/v2/") {
+		writeRegistryError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+		return
+	}
+	path = strings.TrimPrefix(path, "/v2/")
+
+	var name, scope string
+	var handler func(http.ResponseWriter, *http.Request, string, string)
+	switch {
+	case len(path) == 0:
+		handler = func(w http.ResponseWriter, req *http.Request, name, ref string) {
+			w.Header().Set(http.CanonicalHeaderKey("Content-Type"), "application/json")
+			w.Write([]byte("{}"))
+		}
+	case path == "_catalog":
+		scope = "registry:catalog:*"
+		handler = r.catalog
+	case strings.HasSuffix(path, "/tags/list"):
+		name = strings.TrimSuffix(path, "/tags/list")
+		handler = r.listTags
+	case strings.Contains(path, "/manifests/"):
+		name, path = splitPath(path, "/manifests/")
+		handler = r.manifest
+	case strings.Contains(path, "/blobs/uploads"):
+		name, path = splitPath(path, "/blobs/uploads")
+		path = strings.TrimPrefix(path, "/")
+		handler = r.upload
+	case strings.Contains(path, "/blobs/"):
+		name, path = splitPath(path, "/blobs/")
+		handler = r.blob
+	default:
+		writeRegistryError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+		return
+	}
+
+	if len(name) != 0 {
+		actions := "pull"
+		switch req.Method {
+		case http.MethodPost, http.MethodPut, http.MethodPatch:
+			actions = "pull,push"
+		case http.MethodDelete:
+			actions = "*"
+		}
+		scope = fmt.Sprintf("repository:%s:%s", name, actions)
+	}
+
+	if !r.authenticate(w, req, scope) {
+		return
+	}
+
+	handler(w, req, name, path)
+}
+
+func splitPath(path, sep string) (string, string) {
+	i := strings.LastIndex(path, sep)
+	return path[:i], path[i+len(sep):]
+}
+
+// authenticate challenges the request if token authentication is enabled
+// and the request does not carry a valid bearer token
+func (r *Registry) authenticate(w http.ResponseWriter, req *http.Request, scope string) bool {
+	if len(r.options.TokenRealm) == 0 {
+		return true
+	}
+
+	token := ""
+	authorization := req.Header.Get(http.CanonicalHeaderKey("Authorization"))
+	if strings.HasPrefix(authorization, "Bearer ") {
+		token = strings.TrimPrefix(authorization, "Bearer ")
+	}
+	if len(token) != 0 && (r.options.TokenValidator == nil ||
+		r.options.TokenValidator(token, scope)) {
+		return true
+	}
+
+	challenge := fmt.Sprintf("Bearer realm=%q,service=%q", r.options.TokenRealm, r.options.TokenService)
+	if len(scope) != 0 {
+		challenge += fmt.Sprintf(",scope=%q", scope)
+	}
+	w.Header().Set(http.CanonicalHeaderKey("WWW-Authenticate"), challenge)
+	writeRegistryError(w, http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
+	return false
+}
+
+func (r *Registry) catalog(w http.ResponseWriter, req *http.Request, name, ref string) {
+	r.lock.Lock()
+	repositories := []string{}
+	for repository := range r.repositories {
+		repositories = append(repositories, repository)
+	}
+	r.lock.Unlock()
+
+	page, ok := r.paginate(w, req, repositories, "/v2/_catalog")
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"repositories": page,
+	})
+}
+
+func (r *Registry) listTags(w http.ResponseWriter, req *http.Request, name, ref string) {
+	r.lock.Lock()
+	repo := r.repository(name, false)
+	if repo == nil {
+		r.lock.Unlock()
+		writeRegistryError(w, http.StatusNotFound, "NAME_UNKNOWN", "repository name not known to registry")
+		return
+	}
+	tags := []string{}
+	for tag := range repo.tags {
+		tags = append(tags, tag)
+	}
+	r.lock.Unlock()
+
+	page, ok := r.paginate(w, req, tags, fmt.Sprintf("/v2/%s/tags/list", name))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"name": name,
+		"tags": page,
+	})
+}
+
+// paginate sorts the items and returns the page specified by the parameters
+// "n" and "last", the Link header is set if there are more items. All items
+// are returned if the pagination is disabled.
+func (r *Registry) paginate(w http.ResponseWriter, req *http.Request, items []string, path string) ([]string, bool) {
+	sort.Strings(items)
+	if r.options.DisablePagination {
+		return items, true
+	}
+
+	query := req.URL.Query()
+	last := query.Get("last")
+	if len(last) != 0 {
+		i := sort.SearchStrings(items, last)
+		if i < len(items) && items[i] == last {
+			i++
+		}
+		items = items[i:]
+	}
+
+	if len(query.Get("n")) == 0 {
+		return items, true
+	}
+	n, err := strconv.Atoi(query.Get("n"))
+	if err != nil || n < 0 {
+		writeRegistryError(w, http.StatusBadRequest, "PAGINATION_NUMBER_INVALID", "invalid number of results requested")
+		return nil, false
+	}
+	if n < len(items) {
+		items = items[:n]
+		if n > 0 {
+			values := url.Values{}
+			values.Set("last", items[n-1])
+			values.Set("n", strconv.Itoa(n))
+			w.Header().Set(http.CanonicalHeaderKey("Link"),
+				fmt.Sprintf("<%s?%s>; rel=\"next\"", path, values.Encode()))
+		}
+	}
+	return items, true
+}
+
+func (r *Registry) manifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		r.getManifest(w, req, name, reference)
+	case http.MethodPut:
+		r.putManifest(w, req, name, reference)
+	case http.MethodDelete:
+		r.deleteManifest(w, req, name, reference)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) getManifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	r.lock.Lock()
+	var manifest *fakeManifest
+	digest := reference
+	if repo := r.repository(name, false); repo != nil {
+		if d, ok := repo.tags[reference]; ok {
+			digest = d
+		}
+		manifest = repo.manifests[digest]
+	}
+	r.lock.Unlock()
+
+	if manifest == nil {
+		writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+		return
+	}
+
+	w.Header().Set(http.CanonicalHeaderKey("Content-Type"), manifest.mediaType)
+	w.Header().Set(http.CanonicalHeaderKey("Content-Length"), strconv.Itoa(len(manifest.payload)))
+	w.Header().Set(http.CanonicalHeaderKey("Docker-Content-Digest"), digest)
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodGet {
+		w.Write(manifest.payload)
+		r.notify(req, "pull", name, reference, digest, manifest.mediaType)
+	}
+}
+
+func (r *Registry) putManifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	payload, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "MANIFEST_INVALID", err.Error())
+		return
+	}
+	digest := digestOf(payload)
+	if isDigest(reference) && reference != digest {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "provided digest did not match uploaded content")
+		return
+	}
+
+	mediaType := req.Header.Get(http.CanonicalHeaderKey("Content-Type"))
+	blobs, manifests, err := referencesOf(mediaType, payload)
+	if err != nil {
+		writeRegistryError(w, http.StatusBadRequest, "MANIFEST_INVALID", err.Error())
+		return
+	}
+
+	r.lock.Lock()
+	repo := r.repository(name, true)
+	for _, blob := range blobs {
+		if !repo.blobs[blob] {
+			r.lock.Unlock()
+			writeRegistryError(w, http.StatusBadRequest, "MANIFEST_BLOB_UNKNOWN", fmt.Sprintf("blob unknown to registry: %s", blob))
+			return
+		}
+	}
+	for _, manifest := range manifests {
+		if _, ok := repo.manifests[manifest]; !ok {
+			r.lock.Unlock()
+			writeRegistryError(w, http.StatusBadRequest, "MANIFEST_UNKNOWN", fmt.Sprintf("manifest unknown: %s", manifest))
+			return
+		}
+	}
+	repo.manifests[digest] = &fakeManifest{
+		mediaType: mediaType,
+		payload:   payload,
+	}
+	tag := ""
+	if !isDigest(reference) {
+		tag = reference
+		repo.tags[tag] = digest
+	}
+	r.lock.Unlock()
+
+	r.notify(req, "push", name, tag, digest, mediaType)
+	w.Header().Set(http.CanonicalHeaderKey("Location"), fmt.Sprintf("%s/v2/%s/manifests/%s", r.URL, name, digest))
+	w.Header().Set(http.CanonicalHeaderKey("Docker-Content-Digest"), digest)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (r *Registry) deleteManifest(w http.ResponseWriter, req *http.Request, name, reference string) {
+	if !isDigest(reference) {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "deleting manifest by tag is not supported")
+		return
+	}
+
+	r.lock.Lock()
+	repo := r.repository(name, false)
+	if repo == nil || repo.manifests[reference] == nil {
+		r.lock.Unlock()
+		writeRegistryError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest unknown")
+		return
+	}
+	mediaType := repo.manifests[reference].mediaType
+	delete(repo.manifests, reference)
+	for tag, digest := range repo.tags {
+		if digest == reference {
+			delete(repo.tags, tag)
+		}
+	}
+	r.lock.Unlock()
+
+	r.notify(req, "delete", name, "", reference, mediaType)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// referencesOf returns the blobs and manifests referenced by the manifest
+func referencesOf(mediaType string, payload []byte) ([]string, []string, error) {
+	blobs, manifests := []string{}, []string{}
+	switch mediaType {
+	case MediaTypeManifestV1, MediaTypeSignedManifestV1, "application/json":
+		m := struct {
+			FSLayers []struct {
+				BlobSum string `json:"blobSum"`
+			} `json:"fsLayers"`
+		}{}
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil, nil, err
+		}
+		for _, layer := range m.FSLayers {
+			blobs = append(blobs, layer.BlobSum)
+		}
+	case MediaTypeManifestV2:
+		m := struct {
+			Config struct {
+				Digest string `json:"digest"`
+			} `json:"config"`
+			Layers []struct {
+				Digest string `json:"digest"`
+			} `json:"layers"`
+		}{}
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil, nil, err
+		}
+		blobs = append(blobs, m.Config.Digest)
+		for _, layer := range m.Layers {
+			blobs = append(blobs, layer.Digest)
+		}
+	case MediaTypeManifestList:
+		m := struct {
+			Manifests []struct {
+				Digest string `json:"digest"`
+			} `json:"manifests"`
+		}{}
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil, nil, err
+		}
+		for _, manifest := range m.Manifests {
+			manifests = append(manifests, manifest.Digest)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported media type %s", mediaType)
+	}
+	return blobs, manifests, nil
+}
+
+func (r *Registry) blob(w http.ResponseWriter, req *http.Request, name, digest string) {
+	r.lock.Lock()
+	repo := r.repository(name, false)
+	linked := repo != nil && repo.blobs[digest]
+	content := r.blobs[digest]
+	if linked && req.Method == http.MethodDelete {
+		delete(repo.blobs, digest)
+	}
+	r.lock.Unlock()
+
+	if !linked {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob unknown to registry")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		w.Header().Set(http.CanonicalHeaderKey("Content-Type"), "application/octet-stream")
+		w.Header().Set(http.CanonicalHeaderKey("Docker-Content-Digest"), digest)
+		// ServeContent handles the Range requests
+		http.ServeContent(w, req, "", time.Time{}, bytes.NewReader(content))
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) upload(w http.ResponseWriter, req *http.Request, name, uuid string) {
+	if len(uuid) == 0 {
+		if req.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		r.startUpload(w, req, name)
+		return
+	}
+
+	r.lock.Lock()
+	buffer, ok := r.uploads[uuid]
+	r.lock.Unlock()
+	if !ok {
+		writeRegistryError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "blob upload unknown to registry")
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		r.writeUploadStatus(w, name, uuid, buffer.Len(), http.StatusNoContent)
+	case http.MethodPatch:
+		if contentRange := req.Header.Get(http.CanonicalHeaderKey("Content-Range")); len(contentRange) != 0 &&
+			!strings.HasPrefix(contentRange, fmt.Sprintf("%d-", buffer.Len())) {
+			writeRegistryError(w, http.StatusRequestedRangeNotSatisfiable, "BLOB_UPLOAD_INVALID", "invalid content range")
+			return
+		}
+		if _, err := buffer.ReadFrom(req.Body); err != nil {
+			writeRegistryError(w, http.StatusBadRequest, "BLOB_UPLOAD_INVALID", err.Error())
+			return
+		}
+		r.writeUploadStatus(w, name, uuid, buffer.Len(), http.StatusAccepted)
+	case http.MethodPut:
+		if _, err := buffer.ReadFrom(req.Body); err != nil {
+			writeRegistryError(w, http.StatusBadRequest, "BLOB_UPLOAD_INVALID", err.Error())
+			return
+		}
+		r.lock.Lock()
+		delete(r.uploads, uuid)
+		r.lock.Unlock()
+		r.completeUpload(w, name, req.URL.Query().Get("digest"), buffer.Bytes())
+	case http.MethodDelete:
+		r.lock.Lock()
+		delete(r.uploads, uuid)
+		r.lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (r *Registry) startUpload(w http.ResponseWriter, req *http.Request, name string) {
+	query := req.URL.Query()
+
+	// cross repository mount, fall back to a normal upload if the blob
+	// does not exist in the source repository
+	if mount := query.Get("mount"); len(mount) != 0 {
+		r.lock.Lock()
+		from := r.repository(query.Get("from"), false)
+		mounted := from != nil && from.blobs[mount]
+		if mounted {
+			r.repository(name, true).blobs[mount] = true
+		}
+		r.lock.Unlock()
+		if mounted {
+			r.writeBlobCreated(w, name, mount)
+			return
+		}
+	}
+
+	// monolithic upload in a single POST
+	if digest := query.Get("digest"); len(digest) != 0 {
+		content, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			writeRegistryError(w, http.StatusBadRequest, "BLOB_UPLOAD_INVALID", err.Error())
+			return
+		}
+		r.completeUpload(w, name, digest, content)
+		return
+	}
+
+	uuid := newUUID()
+	r.lock.Lock()
+	r.uploads[uuid] = &bytes.Buffer{}
+	r.lock.Unlock()
+	r.writeUploadStatus(w, name, uuid, 0, http.StatusAccepted)
+}
+
+func (r *Registry) completeUpload(w http.ResponseWriter, name, digest string, content []byte) {
+	if digest != digestOf(content) {
+		writeRegistryError(w, http.StatusBadRequest, "DIGEST_INVALID", "provided digest did not match uploaded content")
+		return
+	}
+
+	r.lock.Lock()
+	r.blobs[digest] = content
+	r.repository(name, true).blobs[digest] = true
+	r.lock.Unlock()
+	r.writeBlobCreated(w, name, digest)
+}
+
+// notify records the event and sends it to the notification endpoint
+func (r *Registry) notify(req *http.Request, action, repository, tag, digest, mediaType string) {
+	event := models.Event{
+		ID:        newUUID(),
+		TimeStamp: time.Now().UTC(),
+		Action:    action,
+		Target: &models.Target{
+			MediaType:  mediaType,
+			Digest:     digest,
+			Repository: repository,
+			URL:        fmt.Sprintf("%s/v2/%s/manifests/%s", r.URL, repository, digest),
+			Tag:        tag,
+		},
+		Request: &models.Request{
+			ID:        newUUID(),
+			Method:    req.Method,
+			UserAgent: req.UserAgent(),
+		},
+		Actor: &models.Actor{},
+	}
+
+	r.lock.Lock()
+	r.events = append(r.events, event)
+	r.lock.Unlock()
+
+	if len(r.options.NotificationEndpoint) == 0 {
+		return
+	}
+	b, err := json.Marshal(&models.Notification{
+		Events: []models.Event{event},
+	})
+	if err != nil {
+		return
+	}
+	resp, err := http.Post(r.options.NotificationEndpoint,
+		"application/vnd.docker.distribution.events.v1+json", bytes.NewReader(b))
+	if err != nil {
+		return
+	}
+	resp.Body.Close()
+}
+
+func (r *Registry) writeUploadStatus(w http.ResponseWriter, name, uuid string, offset, statusCode int) {
+	end := offset - 1
+	if end < 0 {
+		end = 0
+	}
+	w.Header().Set(http.CanonicalHeaderKey("Location"), fmt.Sprintf("%s/v2/%s/blobs/uploads/%s", r.URL, name, uuid))
+	w.Header().Set(http.CanonicalHeaderKey("Docker-Upload-UUID"), uuid)
+	w.Header().Set(http.CanonicalHeaderKey("Range"), fmt.Sprintf("0-%d", end))
+	w.Header().Set(http.CanonicalHeaderKey("Content-Length"), "0")
+	w.WriteHeader(statusCode)
+}
+
+func (r *Registry) writeBlobCreated(w http.ResponseWriter, name, digest string) {
+	w.Header().Set(http.CanonicalHeaderKey("Location"), fmt.Sprintf("%s/v2/%s/blobs/%s", r.URL, name, digest))
+	w.Header().Set(http.CanonicalHeaderKey("Docker-Content-Digest"), digest)
+	w.Header().Set(http.CanonicalHeaderKey("Content-Length"), "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+func writeRegistryError(w http.ResponseWriter, statusCode int, code, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"errors": []registryError{
+			{
+				Code:    code,
+				Message: message,
+			},
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set(http.CanonicalHeaderKey("Content-Type"), "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(b)
+}
+
+func digestOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func isDigest(reference string) bool {
+	return strings.HasPrefix(reference, "sha256:")
+}
+
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
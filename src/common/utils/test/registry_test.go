@@ -0,0 +1,228 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func doRequest(t *testing.T, method, url string, body []byte, headers map[string]string) *http.Response {
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to send request %s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func expectStatus(t *testing.T, resp *http.Response, statusCode int) {
+	defer resp.Body.Close()
+	if resp.StatusCode != statusCode {
+		b, _ := ioutil.ReadAll(resp.Body)
+		t.Fatalf("unexpected status code of %s %s: %d != %d, %s", resp.Request.Method,
+			resp.Request.URL, resp.StatusCode, statusCode, string(b))
+	}
+}
+
+func TestRegistryChunkedUpload(t *testing.T) {
+	registry := NewRegistry(nil)
+	defer registry.Close()
+
+	content := []byte("chunked blob")
+	digest := digestOf(content)
+
+	resp := doRequest(t, http.MethodPost, registry.URL+"/v2/library/hello-world/blobs/uploads/", nil, nil)
+	expectStatus(t, resp, http.StatusAccepted)
+	location := resp.Header.Get("Location")
+
+	resp = doRequest(t, http.MethodPatch, location, content[:5], map[string]string{"Content-Range": "0-4"})
+	expectStatus(t, resp, http.StatusAccepted)
+	if r := resp.Header.Get("Range"); r != "0-4" {
+		t.Errorf("unexpected range: %s", r)
+	}
+
+	// wrong offset
+	resp = doRequest(t, http.MethodPatch, location, content[5:], map[string]string{"Content-Range": "0-6"})
+	expectStatus(t, resp, http.StatusRequestedRangeNotSatisfiable)
+
+	resp = doRequest(t, http.MethodPatch, location, content[5:], nil)
+	expectStatus(t, resp, http.StatusAccepted)
+
+	resp = doRequest(t, http.MethodPut, location+"?digest="+digest, nil, nil)
+	expectStatus(t, resp, http.StatusCreated)
+
+	resp = doRequest(t, http.MethodGet, registry.URL+"/v2/library/hello-world/blobs/"+digest, nil,
+		map[string]string{"Range": "bytes=8-"})
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(b) != "blob" {
+		t.Errorf("unexpected response of range request: %d %s", resp.StatusCode, string(b))
+	}
+
+	// monolithic upload with wrong digest
+	resp = doRequest(t, http.MethodPost, registry.URL+"/v2/library/hello-world/blobs/uploads/?digest="+digest,
+		[]byte("corrupted"), nil)
+	expectStatus(t, resp, http.StatusBadRequest)
+}
+
+func TestRegistryMountAndDelete(t *testing.T) {
+	registry := NewRegistry(nil)
+	defer registry.Close()
+
+	digest := registry.AddBlob("library/source", []byte("blob"))
+
+	resp := doRequest(t, http.MethodPost, fmt.Sprintf("%s/v2/library/target/blobs/uploads/?mount=%s&from=library/source",
+		registry.URL, digest), nil, nil)
+	expectStatus(t, resp, http.StatusCreated)
+
+	// mount from a repository which does not have the blob starts an upload
+	resp = doRequest(t, http.MethodPost, fmt.Sprintf("%s/v2/library/another/blobs/uploads/?mount=%s&from=library/none",
+		registry.URL, digest), nil, nil)
+	expectStatus(t, resp, http.StatusAccepted)
+
+	resp = doRequest(t, http.MethodHead, registry.URL+"/v2/library/target/blobs/"+digest, nil, nil)
+	expectStatus(t, resp, http.StatusOK)
+
+	resp = doRequest(t, http.MethodDelete, registry.URL+"/v2/library/target/blobs/"+digest, nil, nil)
+	expectStatus(t, resp, http.StatusAccepted)
+
+	resp = doRequest(t, http.MethodHead, registry.URL+"/v2/library/target/blobs/"+digest, nil, nil)
+	expectStatus(t, resp, http.StatusNotFound)
+
+	// the blob is still linked to the source repository
+	resp = doRequest(t, http.MethodHead, registry.URL+"/v2/library/source/blobs/"+digest, nil, nil)
+	expectStatus(t, resp, http.StatusOK)
+}
+
+func TestRegistryManifests(t *testing.T) {
+	var notification []byte
+	receiver := NewServer(&RequestHandlerMapping{
+		Method:  "POST",
+		Pattern: "/service/notifications",
+		Handler: func(w http.ResponseWriter, r *http.Request) {
+			notification, _ = ioutil.ReadAll(r.Body)
+		},
+	})
+	defer receiver.Close()
+
+	registry := NewRegistry(&RegistryOptions{
+		NotificationEndpoint: receiver.URL + "/service/notifications",
+	})
+	defer registry.Close()
+
+	config := registry.AddBlob("library/hello-world", []byte("config"))
+	layer := registry.AddBlob("library/hello-world", []byte("layer"))
+	manifest := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%s","config":{"digest":"%s"},"layers":[{"digest":"%s"}]}`,
+		MediaTypeManifestV2, config, layer))
+	url := registry.URL + "/v2/library/hello-world/manifests/"
+
+	resp := doRequest(t, http.MethodPut, url+"latest", manifest, map[string]string{"Content-Type": MediaTypeManifestV2})
+	expectStatus(t, resp, http.StatusCreated)
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest != digestOf(manifest) {
+		t.Errorf("unexpected digest: %s != %s", digest, digestOf(manifest))
+	}
+	if !strings.Contains(string(notification), `"Action":"push"`) ||
+		!strings.Contains(string(notification), `"Tag":"latest"`) {
+		t.Errorf("unexpected notification: %s", string(notification))
+	}
+
+	// the manifest list references the manifest pushed above
+	list := []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%s","manifests":[{"digest":"%s"}]}`,
+		MediaTypeManifestList, digest))
+	resp = doRequest(t, http.MethodPut, url+"multi-arch", list, map[string]string{"Content-Type": MediaTypeManifestList})
+	expectStatus(t, resp, http.StatusCreated)
+
+	// schema1 manifest references an unknown blob
+	schema1 := []byte(`{"schemaVersion":1,"fsLayers":[{"blobSum":"sha256:unknown"}]}`)
+	resp = doRequest(t, http.MethodPut, url+"v1", schema1, map[string]string{"Content-Type": MediaTypeSignedManifestV1})
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	resp = doRequest(t, http.MethodGet, registry.URL+"/v2/library/hello-world/tags/list?n=1", nil, nil)
+	defer resp.Body.Close()
+	tags := struct {
+		Tags []string `json:"tags"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		t.Fatalf("failed to decode tags: %v", err)
+	}
+	if len(tags.Tags) != 1 || tags.Tags[0] != "latest" {
+		t.Errorf("unexpected tags: %v", tags.Tags)
+	}
+	if link := resp.Header.Get("Link"); link != `</v2/library/hello-world/tags/list?last=latest&n=1>; rel="next"` {
+		t.Errorf("unexpected link: %s", link)
+	}
+
+	resp = doRequest(t, http.MethodGet, url+"latest", nil, nil)
+	expectStatus(t, resp, http.StatusOK)
+	if mediaType := resp.Header.Get("Content-Type"); mediaType != MediaTypeManifestV2 {
+		t.Errorf("unexpected media type: %s", mediaType)
+	}
+
+	resp = doRequest(t, http.MethodDelete, url+"latest", nil, nil)
+	expectStatus(t, resp, http.StatusBadRequest)
+
+	resp = doRequest(t, http.MethodDelete, url+digest, nil, nil)
+	expectStatus(t, resp, http.StatusAccepted)
+
+	resp = doRequest(t, http.MethodGet, url+"latest", nil, nil)
+	expectStatus(t, resp, http.StatusNotFound)
+
+	actions := []string{}
+	for _, event := range registry.Events() {
+		actions = append(actions, event.Action)
+	}
+	if strings.Join(actions, ",") != "push,push,pull,delete" {
+		t.Errorf("unexpected events: %v", actions)
+	}
+}
+
+func TestRegistryTokenAuth(t *testing.T) {
+	registry := NewRegistry(&RegistryOptions{
+		TokenRealm:   "http://token.service/service/token",
+		TokenService: "token-service",
+		TokenValidator: func(token, scope string) bool {
+			return token == "valid" && scope == "repository:library/hello-world:pull"
+		},
+	})
+	defer registry.Close()
+
+	url := registry.URL + "/v2/library/hello-world/tags/list"
+	resp := doRequest(t, http.MethodGet, url, nil, nil)
+	expectStatus(t, resp, http.StatusUnauthorized)
+	challenge := `Bearer realm="http://token.service/service/token",service="token-service",scope="repository:library/hello-world:pull"`
+	if c := resp.Header.Get("WWW-Authenticate"); c != challenge {
+		t.Errorf("unexpected challenge: %s != %s", c, challenge)
+	}
+
+	resp = doRequest(t, http.MethodGet, url, nil, map[string]string{"Authorization": "Bearer invalid"})
+	expectStatus(t, resp, http.StatusUnauthorized)
+
+	// the repository does not exist
+	resp = doRequest(t, http.MethodGet, url, nil, map[string]string{"Authorization": "Bearer valid"})
+	expectStatus(t, resp, http.StatusNotFound)
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package totp implements the time-based one-time passwords of RFC 6238,
+// which are compatible with the common authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits is the length of the codes
+	Digits = 6
+	// Period is the seconds each code is valid in
+	Period = 30
+
+	secretLen = 20
+	// the codes of the adjacent periods are accepted as well, to tolerate
+	// the clock drift of the devices
+	skew = 1
+)
+
+// GenerateSecret returns a random secret encoded in base32
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encode(b), nil
+}
+
+// Code returns the code of the secret at the time
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decode(secret)
+	if err != nil {
+		return "", err
+	}
+	return hotp(key, step(t)), nil
+}
+
+// Validate checks the code against the secret at the time. It returns the
+// time step the code belongs to, so the caller can reject the codes of the
+// steps used already.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	key, err := decode(secret)
+	if err != nil || len(code) != Digits {
+		return 0, false
+	}
+	current := step(t)
+	for s := current - skew; s <= current+skew; s++ {
+		if hmac.Equal([]byte(hotp(key, s)), []byte(code)) {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningURI returns the otpauth URI of the secret, which is shown as a
+// QR code for the authenticator apps to scan
+func ProvisioningURI(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprintf("%d", Digits))
+	v.Set("period", fmt.Sprintf("%d", Period))
+	label := (&url.URL{Path: issuer + ":" + account}).EscapedPath()
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, v.Encode())
+}
+
+// encode returns the base32 encoding of the bytes without padding, as the
+// authenticator apps expect
+func encode(b []byte) string {
+	return strings.TrimRight(base32.StdEncoding.EncodeToString(b), "=")
+}
+
+// decode accepts the secret with or without padding, in either case
+func decode(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.TrimRight(secret, "="))
+	if n := len(s) % 8; n != 0 {
+		s += strings.Repeat("=", 8-n)
+	}
+	return base32.StdEncoding.DecodeString(s)
+}
+
+func step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// hotp computes the code of the counter as defined in RFC 4226
+func hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	bin := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, bin%mod)
+}
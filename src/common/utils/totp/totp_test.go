@@ -0,0 +1,105 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the secret "12345678901234567890" of the test vectors in RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, expected := range cases {
+		code, err := Code(rfcSecret, time.Unix(ts, 0))
+		if err != nil {
+			t.Fatalf("failed to compute the code: %v", err)
+		}
+		assert.Equal(t, expected, code, "time %d", ts)
+	}
+
+	_, err := Code("not base32!", time.Now())
+	assert.NotNil(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	step, ok := Validate(rfcSecret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1111111109/Period), step)
+
+	// the code of the previous period is accepted
+	_, ok = Validate(rfcSecret, "081804", now.Add(Period*time.Second))
+	assert.True(t, ok)
+	_, ok = Validate(rfcSecret, "081804", now.Add(3*Period*time.Second))
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, "000000", now)
+	assert.False(t, ok)
+	_, ok = Validate(rfcSecret, "81804", now)
+	assert.False(t, ok)
+	// the secret is case insensitive
+	_, ok = Validate(strings.ToLower(rfcSecret), "081804", now)
+	assert.True(t, ok)
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	assert.Equal(t, 32, len(secret))
+	code, err := Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to compute the code: %v", err)
+	}
+	_, ok := Validate(secret, code, time.Now())
+	assert.True(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Harbor", "admin", rfcSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Harbor:admin?"), uri)
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=Harbor")
+
+	uri = ProvisioningURI("My Harbor", "admin", rfcSecret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/My%20Harbor:admin?"), uri)
+}
+
+func TestSecretPadding(t *testing.T) {
+	secret, err := GenerateSecret()
+	assert.Nil(t, err)
+	assert.False(t, strings.Contains(secret, "="), secret)
+
+	// 5 bytes are encoded without padding, the others are padded
+	for _, b := range [][]byte{[]byte("12345"), []byte("123456")} {
+		encoded := encode(b)
+		assert.False(t, strings.Contains(encoded, "="), encoded)
+		decoded, err := decode(strings.ToLower(encoded))
+		assert.Nil(t, err)
+		assert.Equal(t, b, decoded)
+	}
+}
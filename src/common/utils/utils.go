@@ -62,6 +62,34 @@ func ParseRepository(repository string) (project, rest string) {
 	return
 }
 
+// ParentProjects returns the prefixes of the name in path segments, the
+// longest first, e.g. ["org/team", "org"] for "org/team/app". For a nested
+// project they are the names of its ancestors, and for a repository they are
+// the candidates of the project it belongs to.
+func ParentProjects(name string) []string {
+	name = strings.Trim(name, "/")
+	parents := []string{}
+	for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name, "/") {
+		name = name[:i]
+		parents = append(parents, name)
+	}
+	return parents
+}
+
+// ResolveProject splits a repository into two parts: project and rest, the
+// project is the longest prefix of the repository in path segments for which
+// exists returns true. If there is no such prefix, the first segment is
+// returned as project just like ParseRepository does.
+func ResolveProject(repository string, exists func(project string) bool) (project, rest string) {
+	repository = strings.Trim(repository, "/")
+	for _, candidate := range ParentProjects(repository) {
+		if exists(candidate) {
+			return candidate, repository[len(candidate)+1:]
+		}
+	}
+	return ParseRepository(repository)
+}
+
 // GenerateRandomString generates a random string
 func GenerateRandomString() string {
 	length := 32
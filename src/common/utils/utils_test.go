@@ -93,6 +93,46 @@ func TestParseRepository(t *testing.T) {
 	}
 }
 
+func TestParentProjects(t *testing.T) {
+	parents := ParentProjects("org/team/app")
+	if len(parents) != 2 || parents[0] != "org/team" || parents[1] != "org" {
+		t.Errorf("unexpected parents: %v", parents)
+	}
+	if parents = ParentProjects("library"); len(parents) != 0 {
+		t.Errorf("unexpected parents: %v", parents)
+	}
+}
+
+func TestResolveProject(t *testing.T) {
+	projects := map[string]bool{
+		"org":      true,
+		"org/team": true,
+	}
+	exists := func(project string) bool {
+		return projects[project]
+	}
+
+	cases := []struct {
+		repository string
+		project    string
+		rest       string
+	}{
+		{"org/team/app", "org/team", "app"},
+		{"org/team/sub/app", "org/team", "sub/app"},
+		{"org/app", "org", "app"},
+		{"org/team", "org", "team"},
+		{"library/ubuntu", "library", "ubuntu"},
+		{"ubuntu", "", "ubuntu"},
+	}
+	for _, c := range cases {
+		project, rest := ResolveProject(c.repository, exists)
+		if project != c.project || rest != c.rest {
+			t.Errorf("unexpected result for %s: %s %s != %s %s",
+				c.repository, project, rest, c.project, c.rest)
+		}
+	}
+}
+
 func TestEncrypt(t *testing.T) {
 	content := "content"
 	salt := "salt"
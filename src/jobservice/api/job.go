@@ -0,0 +1,150 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/jobservice/job"
+	"github.com/vmware/harbor/src/jobservice/utils"
+)
+
+// GenericJob handles /api/jobs /api/jobs/:id /api/jobs/:id/log /api/jobs/:id/events
+// /api/jobs/:id/actions, the jobs are of the kinds registered via job.RegisterGenericKind
+type GenericJob struct {
+	api.BaseAPI
+}
+
+// GenericJobReq holds informations of request for /api/jobs
+type GenericJobReq struct {
+	Kind       string          `json:"kind"`
+	Parameters json.RawMessage `json:"parameters"`
+}
+
+// JobActionReq holds informations of request for /api/jobs/:id/actions
+type JobActionReq struct {
+	Action string `json:"action"`
+}
+
+// Prepare ...
+func (g *GenericJob) Prepare() {
+	authenticate(&g.BaseAPI)
+}
+
+// Post creates a job and sends it to the scheduler
+func (g *GenericJob) Post() {
+	var data GenericJobReq
+	g.DecodeJSONReq(&data)
+	if !job.IsGenericKind(data.Kind) {
+		g.RenderError(http.StatusBadRequest, fmt.Sprintf("Unsupported job kind: %s", data.Kind))
+		return
+	}
+
+	j := models.Job{
+		Kind:       data.Kind,
+		Parameters: string(data.Parameters),
+	}
+	id, err := dao.AddJob(j)
+	if err != nil {
+		log.Errorf("Failed to insert job record, error: %v", err)
+		g.RenderError(http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Debugf("Send %s job to scheduler, job id: %d", data.Kind, id)
+	job.Schedule(data.Kind, id)
+
+	g.Redirect(http.StatusCreated, strconv.FormatInt(id, 10))
+}
+
+// List filters jobs by the parameters "kind" and "status"
+func (g *GenericJob) List() {
+	page, pageSize := g.GetPaginationParams()
+	jobs, total, err := dao.FilterJobs(g.GetString("kind"), g.GetString("status"),
+		pageSize, pageSize*(page-1))
+	if err != nil {
+		log.Errorf("Failed to filter jobs, error: %v", err)
+		g.RenderError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	g.SetPaginationHeader(total, page, pageSize)
+	g.Data["json"] = jobs
+	g.ServeJSON()
+}
+
+// Get ...
+func (g *GenericJob) Get() {
+	g.Data["json"] = g.getJob()
+	g.ServeJSON()
+}
+
+// GetLog gets logs of the job
+func (g *GenericJob) GetLog() {
+	j := g.getJob()
+	logFile, err := utils.GetJobLogPath(j.Kind, j.ID)
+	if err != nil {
+		log.Errorf("failed to get log path of %s job %d: %v", j.Kind, j.ID, err)
+		g.RenderError(http.StatusInternalServerError,
+			http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	g.Ctx.Output.Download(logFile)
+}
+
+// GetEvents returns the state transitions of the job in the order they happened
+func (g *GenericJob) GetEvents() {
+	j := g.getJob()
+	events, err := dao.GetJobEvents(j.Kind, j.ID)
+	if err != nil {
+		log.Errorf("Failed to get events of %s job %d, error: %v", j.Kind, j.ID, err)
+		g.RenderError(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	g.Data["json"] = events
+	g.ServeJSON()
+}
+
+// HandleAction supports some operations to the job, currently only "stop" is supported
+func (g *GenericJob) HandleAction() {
+	var data JobActionReq
+	g.DecodeJSONReq(&data)
+	if data.Action != "stop" {
+		log.Errorf("Unrecognized action: %s", data.Action)
+		g.RenderError(http.StatusBadRequest, fmt.Sprintf("Unrecongized action: %s", data.Action))
+		return
+	}
+	j := g.getJob()
+	job.WorkerPool.StopJobs(j.Kind, []int64{j.ID})
+}
+
+// getJob aborts the request if the job doesn't exist
+func (g *GenericJob) getJob() *models.Job {
+	id := g.GetIDFromURL()
+	j, err := dao.GetJob(id)
+	if err != nil {
+		log.Errorf("Failed to get job %d, error: %v", id, err)
+		g.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if j == nil {
+		g.CustomAbort(http.StatusNotFound, fmt.Sprintf("Job not found, id: %d", id))
+	}
+	return j
+}
@@ -0,0 +1,37 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/utils/registry"
+)
+
+// RegistryClient handles /api/registry_client/metrics
+type RegistryClient struct {
+	api.BaseAPI
+}
+
+// Prepare ...
+func (r *RegistryClient) Prepare() {
+	authenticate(&r.BaseAPI)
+}
+
+// Metrics returns the latency and status counters of the requests sent
+// to registries by the replication jobs, keyed by request method
+func (r *RegistryClient) Metrics() {
+	r.Data["json"] = registry.DefaultMetrics.Snapshot()
+	r.ServeJSON()
+}
@@ -47,22 +47,23 @@ type ReplicationReq struct {
 
 // Prepare ...
 func (rj *ReplicationJob) Prepare() {
-	rj.authenticate()
+	authenticate(&rj.BaseAPI)
 }
 
-func (rj *ReplicationJob) authenticate() {
-	cookie, err := rj.Ctx.Request.Cookie(models.UISecretCookie)
+// authenticate aborts the request if it doesn't carry the UI secret
+func authenticate(b *api.BaseAPI) {
+	cookie, err := b.Ctx.Request.Cookie(models.UISecretCookie)
 	if err != nil && err != http.ErrNoCookie {
 		log.Errorf("failed to get cookie %s: %v", models.UISecretCookie, err)
-		rj.CustomAbort(http.StatusInternalServerError, "")
+		b.CustomAbort(http.StatusInternalServerError, "")
 	}
 
 	if err == http.ErrNoCookie {
-		rj.CustomAbort(http.StatusUnauthorized, "")
+		b.CustomAbort(http.StatusUnauthorized, "")
 	}
 
 	if cookie.Value != config.UISecret() {
-		rj.CustomAbort(http.StatusForbidden, "")
+		b.CustomAbort(http.StatusForbidden, "")
 	}
 }
 
@@ -127,7 +128,7 @@ func (rj *ReplicationJob) addJob(repo string, policyID int64, operation string,
 		return err
 	}
 	log.Debugf("Send job to scheduler, job id: %d", id)
-	job.Schedule(id)
+	job.Schedule(models.JobKindReplication, id)
 	return nil
 }
 
@@ -157,7 +158,7 @@ func (rj *ReplicationJob) HandleAction() {
 	for _, j := range jobs {
 		jobIDList = append(jobIDList, j.ID)
 	}
-	job.WorkerPool.StopJobs(jobIDList)
+	job.WorkerPool.StopJobs(models.JobKindReplication, jobIDList)
 }
 
 // GetLog gets logs of the job
@@ -169,7 +170,7 @@ func (rj *ReplicationJob) GetLog() {
 		rj.RenderError(http.StatusBadRequest, "Invalid job id")
 		return
 	}
-	logFile, err := utils.GetJobLogPath(jid)
+	logFile, err := utils.GetJobLogPath(models.JobKindReplication, jid)
 	if err != nil {
 		log.Errorf("failed to get log path of job %s: %v", idStr, err)
 		rj.RenderError(http.StatusInternalServerError,
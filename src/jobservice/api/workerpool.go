@@ -0,0 +1,36 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/jobservice/job"
+)
+
+// WorkerPool handles /api/workers/stats
+type WorkerPool struct {
+	api.BaseAPI
+}
+
+// Prepare ...
+func (w *WorkerPool) Prepare() {
+	authenticate(&w.BaseAPI)
+}
+
+// Stats returns the utilization of the worker pool
+func (w *WorkerPool) Stats() {
+	w.Data["json"] = job.WorkerPool.Stats()
+	w.ServeJSON()
+}
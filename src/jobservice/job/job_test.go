@@ -14,9 +14,161 @@
 package job
 
 import (
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
 )
 
 func TestMain(t *testing.T) {
 }
 
+func TestRegisterKind(t *testing.T) {
+	if GetKind(models.JobKindReplication) == nil {
+		t.Fatalf("kind %s is not registered", models.JobKindReplication)
+	}
+	if IsGenericKind(models.JobKindReplication) {
+		t.Errorf("kind %s should not be generic", models.JobKindReplication)
+	}
+
+	if GetKind("test") == nil {
+		RegisterGenericKind("test", func(sm *SM, parameters string) error {
+			return nil
+		})
+	}
+	if GetKind("test") == nil || !IsGenericKind("test") {
+		t.Errorf("kind test is not registered as generic kind")
+	}
+
+	if GetKind("unknown") != nil {
+		t.Errorf("kind unknown should not be registered")
+	}
+	if err := updateStatus("unknown", 1, models.JobRunning); err == nil {
+		t.Errorf("expected error when updating status of job of unknown kind")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic when registering a kind twice")
+		}
+	}()
+	RegisterKind(models.JobKindReplication, &replicationKind{})
+}
+
+func TestTruncateMessage(t *testing.T) {
+	if m := truncateMessage("abc", 3); m != "abc" {
+		t.Errorf("unexpected message: %s", m)
+	}
+	if m := truncateMessage("abcd", 3); m != "abc" {
+		t.Errorf("unexpected message: %s", m)
+	}
+	if m := truncateMessage("abéé", 3); m != "abé" {
+		t.Errorf("unexpected message: %q", m)
+	}
+}
+
+// fakeHandler records the events persisted when it is entered
+type fakeHandler struct {
+	events *[]models.JobEvent
+	seen   *int
+	err    error
+}
+
+func (f *fakeHandler) Enter() (string, error) {
+	*f.seen = len(*f.events)
+	return "", f.err
+}
+
+func (f *fakeHandler) Exit() error {
+	return nil
+}
+
+func TestEnterStateRecordsEvents(t *testing.T) {
+	events := []models.JobEvent{}
+	addJobEvent = func(e models.JobEvent) (int64, error) {
+		events = append(events, e)
+		return int64(len(events)), nil
+	}
+	defer func() { addJobEvent = dao.AddJobEvent }()
+
+	sm := &SM{}
+	sm.Init()
+	sm.JobID = 1
+	sm.Kind = "test"
+	sm.CurrentState = models.JobPending
+	seen := -1
+	sm.AddTransition(models.JobPending, models.JobRunning, &fakeHandler{events: &events, seen: &seen})
+	sm.AddTransition(models.JobRunning, "transfer", &fakeHandler{events: &events, seen: &seen, err: errors.New("failed")})
+
+	if _, err := sm.EnterState(models.JobRunning); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the transition is recorded before the handler runs
+	if seen != 1 || len(events) != 1 || events[0].ToState != models.JobRunning || len(events[0].Message) > 0 {
+		t.Fatalf("unexpected events: %+v, seen by handler: %d", events, seen)
+	}
+
+	if _, err := sm.EnterState("transfer"); err == nil {
+		t.Fatalf("expected error")
+	}
+	if seen != 2 || len(events) != 3 {
+		t.Fatalf("unexpected events: %+v, seen by handler: %d", events, seen)
+	}
+	if e := events[1]; e.FromState != models.JobRunning || e.ToState != "transfer" || len(e.Message) > 0 {
+		t.Errorf("unexpected transition event: %+v", e)
+	}
+	if e := events[2]; e.FromState != "transfer" || e.ToState != "transfer" || e.Message != "failed" {
+		t.Errorf("unexpected failure event: %+v", e)
+	}
+}
+
+func TestResizeWorkerPool(t *testing.T) {
+	WorkerPool = newWorkerPool()
+	WorkerPool.Resize(3)
+	if stats := WorkerPool.Stats(); stats.Size != 3 || stats.Idle != 3 || stats.Busy != 0 {
+		t.Fatalf("unexpected stats after resizing to 3: %+v", stats)
+	}
+
+	// pretend the first worker is handling a job
+	busy := WorkerPool.workerList[0]
+	WorkerPool.setBusy(busy, true)
+
+	WorkerPool.Resize(1)
+	waitForWorkers(t, 1)
+	if stats := WorkerPool.Stats(); stats.Size != 1 || stats.Busy != 1 || stats.Idle != 0 || stats.Draining != 0 {
+		t.Fatalf("unexpected stats after resizing to 1: %+v", stats)
+	}
+	if WorkerPool.workerList[0] != busy {
+		t.Fatalf("the busy worker should not be drained")
+	}
+
+	// the busy worker is drained but still in the pool until it finishes the job
+	WorkerPool.Resize(0)
+	if stats := WorkerPool.Stats(); stats.Size != 0 || stats.Busy != 1 || stats.Draining != 1 {
+		t.Fatalf("unexpected stats after resizing to 0: %+v", stats)
+	}
+
+	WorkerPool.Resize(2)
+	if stats := WorkerPool.Stats(); stats.Size != 2 || stats.Idle != 2 || stats.Draining != 1 {
+		t.Fatalf("unexpected stats after resizing to 2: %+v", stats)
+	}
+
+	WorkerPool.setBusy(busy, false)
+	WorkerPool.Resize(0)
+	waitForWorkers(t, 0)
+}
+
+func waitForWorkers(t *testing.T, n int) {
+	for i := 0; i < 100; i++ {
+		WorkerPool.lock.Lock()
+		l := len(WorkerPool.workerList)
+		WorkerPool.lock.Unlock()
+		if l == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("the number of workers is not %d", n)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/vmware/harbor/src/common/dao"
+)
+
+// Kind is a type of job handled by the worker pool, e.g. replication, garbage collection.
+// Each kind provides the transition table and handlers of its jobs.
+type Kind interface {
+	// Init loads the job whose ID is sm.JobID and adds the transitions and handlers
+	// of the job to the state machine. It returns false if the job should be canceled
+	// rather than run.
+	Init(sm *SM) (bool, error)
+	// UpdateStatus updates the status of the job in DB
+	UpdateStatus(jobID int64, status string) error
+}
+
+// TransitionFunc adds the transitions and handlers of a job of generic kind to the state machine,
+// parameters is the JSON string stored with the job. The transitions from "pending" and "retrying"
+// to "running" are added by the state machine.
+type TransitionFunc func(sm *SM, parameters string) error
+
+var (
+	kinds        = map[string]Kind{}
+	genericKinds = map[string]bool{}
+	kindsLock    = &sync.RWMutex{}
+)
+
+// RegisterKind registers a kind of job, it panics if the name is already used
+func RegisterKind(name string, k Kind) {
+	kindsLock.Lock()
+	defer kindsLock.Unlock()
+	if _, ok := kinds[name]; ok {
+		panic(fmt.Sprintf("job kind %s is already registered", name))
+	}
+	kinds[name] = k
+}
+
+// RegisterGenericKind registers a kind whose jobs are stored in the generic job table
+func RegisterGenericKind(name string, f TransitionFunc) {
+	RegisterKind(name, &genericKind{addTransitions: f})
+	kindsLock.Lock()
+	defer kindsLock.Unlock()
+	genericKinds[name] = true
+}
+
+// GetKind returns nil if the kind is not registered
+func GetKind(name string) Kind {
+	kindsLock.RLock()
+	defer kindsLock.RUnlock()
+	return kinds[name]
+}
+
+// IsGenericKind returns whether the kind is registered via RegisterGenericKind
+func IsGenericKind(name string) bool {
+	kindsLock.RLock()
+	defer kindsLock.RUnlock()
+	return genericKinds[name]
+}
+
+func updateStatus(kind string, jobID int64, status string) error {
+	k := GetKind(kind)
+	if k == nil {
+		return fmt.Errorf("unsupported job kind: %s", kind)
+	}
+	return k.UpdateStatus(jobID, status)
+}
+
+// genericKind implements the interface Kind for jobs stored in the generic job table
+type genericKind struct {
+	addTransitions TransitionFunc
+}
+
+func (g *genericKind) Init(sm *SM) (bool, error) {
+	job, err := dao.GetJob(sm.JobID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get job, error: %v", err)
+	}
+	if job == nil {
+		return false, fmt.Errorf("the job doesn't exist in DB, job id: %d", sm.JobID)
+	}
+	if job.Kind != sm.Kind {
+		return false, fmt.Errorf("the kind of job %d is %s rather than %s", sm.JobID, job.Kind, sm.Kind)
+	}
+	return true, g.addTransitions(sm, job.Parameters)
+}
+
+func (g *genericKind) UpdateStatus(jobID int64, status string) error {
+	return dao.UpdateJobStatus(jobID, status)
+}
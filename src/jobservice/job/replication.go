@@ -0,0 +1,146 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package job
+
+import (
+	"fmt"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	uti "github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/jobservice/config"
+	"github.com/vmware/harbor/src/jobservice/replication"
+)
+
+func init() {
+	RegisterKind(models.JobKindReplication, &replicationKind{})
+}
+
+// RepJobParm wraps the parm of a job
+type RepJobParm struct {
+	LocalRegURL    string
+	TargetURL      string
+	TargetUsername string
+	TargetPassword string
+	Repository     string
+	Tags           []string
+	Enabled        int
+	Operation      string
+	Insecure       bool
+}
+
+// replicationKind handles the jobs stored in table replication_job
+type replicationKind struct{}
+
+// Init loads the parms of the replication job into sm.Parms, the job will be canceled if
+// the policy is disabled.
+func (r *replicationKind) Init(sm *SM) (bool, error) {
+	job, err := dao.GetRepJob(sm.JobID)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get job, error: %v", err)
+	}
+	if job == nil {
+		return false, fmt.Errorf("The job doesn't exist in DB, job id: %d", sm.JobID)
+	}
+	policy, err := dao.GetRepPolicy(job.PolicyID)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get policy, error: %v", err)
+	}
+	if policy == nil {
+		return false, fmt.Errorf("The policy doesn't exist in DB, policy id:%d", job.PolicyID)
+	}
+
+	regURL, err := config.LocalRegURL()
+	if err != nil {
+		return false, err
+	}
+	verify, err := config.VerifyRemoteCert()
+	if err != nil {
+		return false, err
+	}
+	sm.Parms = &RepJobParm{
+		LocalRegURL: regURL,
+		Repository:  job.Repository,
+		Tags:        job.TagList,
+		Enabled:     policy.Enabled,
+		Operation:   job.Operation,
+		Insecure:    !verify,
+	}
+	if policy.Enabled == 0 {
+		//worker will cancel this job
+		return false, nil
+	}
+	target, err := dao.GetRepTarget(policy.TargetID)
+	if err != nil {
+		return false, fmt.Errorf("Failed to get target, error: %v", err)
+	}
+	if target == nil {
+		return false, fmt.Errorf("The target doesn't exist in DB, target id: %d", policy.TargetID)
+	}
+	sm.Parms.TargetURL = target.URL
+	sm.Parms.TargetUsername = target.Username
+	pwd := target.Password
+
+	if len(pwd) != 0 {
+		key, err := config.SecretKey()
+		if err != nil {
+			return false, err
+		}
+		pwd, err = uti.ReversibleDecrypt(pwd, key)
+		if err != nil {
+			return false, fmt.Errorf("failed to decrypt password: %v", err)
+		}
+	}
+
+	sm.Parms.TargetPassword = pwd
+
+	switch sm.Parms.Operation {
+	case models.RepOpTransfer:
+		addImgTransferTransition(sm)
+	case models.RepOpDelete:
+		addImgDeleteTransition(sm)
+	default:
+		return false, fmt.Errorf("unsupported operation: %s", sm.Parms.Operation)
+	}
+
+	return true, nil
+}
+
+// UpdateStatus ...
+func (r *replicationKind) UpdateStatus(jobID int64, status string) error {
+	return dao.UpdateRepJobStatus(jobID, status)
+}
+
+func addImgTransferTransition(sm *SM) {
+	base := replication.InitBaseHandler(sm.Parms.Repository, sm.Parms.LocalRegURL, config.JobserviceSecret(),
+		sm.Parms.TargetURL, sm.Parms.TargetUsername, sm.Parms.TargetPassword,
+		sm.Parms.Insecure, sm.Parms.Tags, sm.Logger)
+
+	sm.AddTransition(models.JobRunning, replication.StateInitialize, &replication.Initializer{BaseHandler: base})
+	sm.AddTransition(replication.StateInitialize, replication.StateCheck, &replication.Checker{BaseHandler: base})
+	sm.AddTransition(replication.StateCheck, replication.StatePullManifest, &replication.ManifestPuller{BaseHandler: base})
+	sm.AddTransition(replication.StatePullManifest, replication.StateTransferBlob, &replication.BlobTransfer{BaseHandler: base})
+	sm.AddTransition(replication.StatePullManifest, models.JobFinished, &StatusUpdater{sm.JobID, models.JobFinished, sm.Kind})
+	sm.AddTransition(replication.StateTransferBlob, replication.StatePushManifest, &replication.ManifestPusher{BaseHandler: base})
+	sm.AddTransition(replication.StatePushManifest, replication.StatePullManifest, &replication.ManifestPuller{BaseHandler: base})
+}
+
+func addImgDeleteTransition(sm *SM) {
+	deleter := replication.NewDeleter(sm.Parms.Repository, sm.Parms.Tags, sm.Parms.TargetURL,
+		sm.Parms.TargetUsername, sm.Parms.TargetPassword, sm.Parms.Insecure, sm.Logger)
+
+	sm.AddTransition(models.JobRunning, replication.StateDelete, deleter)
+	sm.AddTransition(replication.StateDelete, models.JobFinished, &StatusUpdater{sm.JobID, models.JobFinished, sm.Kind})
+}
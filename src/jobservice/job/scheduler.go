@@ -19,17 +19,23 @@ import (
 	"time"
 )
 
-var jobQueue = make(chan int64)
+// queuedJob identifies a job in the job queue, the ID is unique within the kind
+type queuedJob struct {
+	kind string
+	id   int64
+}
+
+var jobQueue = make(chan queuedJob)
 
-// Schedule put a job id into job queue.
-func Schedule(jobID int64) {
-	jobQueue <- jobID
+// Schedule put a job of the kind into job queue.
+func Schedule(kind string, jobID int64) {
+	jobQueue <- queuedJob{kind: kind, id: jobID}
 }
 
 // Reschedule is called by statemachine to retry a job
-func Reschedule(jobID int64) {
-	log.Debugf("Job %d will be rescheduled in 5 minutes", jobID)
+func Reschedule(kind string, jobID int64) {
+	log.Debugf("%s job %d will be rescheduled in 5 minutes", kind, jobID)
 	time.Sleep(5 * time.Minute)
-	log.Debugf("Rescheduling job %d", jobID)
-	Schedule(jobID)
+	log.Debugf("Rescheduling %s job %d", kind, jobID)
+	Schedule(kind, jobID)
 }
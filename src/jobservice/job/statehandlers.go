@@ -17,7 +17,6 @@ package job
 import (
 	"time"
 
-	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
 )
@@ -37,12 +36,13 @@ type StateHandler interface {
 type StatusUpdater struct {
 	JobID int64
 	State string
+	Kind  string
 }
 
 // Enter updates the status of a job and returns "_continue" status to tell state machine to move on.
 // If the status is a final status it returns empty string and the state machine will be stopped.
 func (su StatusUpdater) Enter() (string, error) {
-	err := dao.UpdateRepJobStatus(su.JobID, su.State)
+	err := updateStatus(su.Kind, su.JobID, su.State)
 	if err != nil {
 		log.Warningf("Failed to update state of job: %d, state: %s, error: %v", su.JobID, su.State, err)
 	}
@@ -62,15 +62,16 @@ func (su StatusUpdater) Exit() error {
 // via scheduler
 type Retry struct {
 	JobID int64
+	Kind  string
 }
 
 // Enter ...
 func (jr Retry) Enter() (string, error) {
-	err := dao.UpdateRepJobStatus(jr.JobID, models.JobRetrying)
+	err := updateStatus(jr.Kind, jr.JobID, models.JobRetrying)
 	if err != nil {
 		log.Errorf("Failed to update state of job :%d to Retrying, error: %v", jr.JobID, err)
 	}
-	go Reschedule(jr.JobID)
+	go Reschedule(jr.Kind, jr.JobID)
 	return "", err
 }
 
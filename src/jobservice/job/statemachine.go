@@ -17,32 +17,24 @@ package job
 import (
 	"fmt"
 	"sync"
+	"unicode/utf8"
 
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
-	uti "github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
-	"github.com/vmware/harbor/src/jobservice/config"
-	"github.com/vmware/harbor/src/jobservice/replication"
 	"github.com/vmware/harbor/src/jobservice/utils"
 )
 
-// RepJobParm wraps the parm of a job
-type RepJobParm struct {
-	LocalRegURL    string
-	TargetURL      string
-	TargetUsername string
-	TargetPassword string
-	Repository     string
-	Tags           []string
-	Enabled        int
-	Operation      string
-	Insecure       bool
-}
+// the length of message column in table job_event, in characters
+const maxEventMessageLen = 1024
+
+// addJobEvent persists the events, it is replaced in tests
+var addJobEvent = dao.AddJobEvent
 
 // SM is the state machine to handle job, it handles one job at a time.
 type SM struct {
 	JobID         int64
+	Kind          string
 	CurrentState  string
 	PreviousState string
 	//The states that don't have to exist in transition map, such as "Error", "Canceled"
@@ -51,44 +43,76 @@ type SM struct {
 	Handlers     map[string]StateHandler
 	desiredState string
 	Logger       *log.Logger
-	Parms        *RepJobParm
+	Parms        *RepJobParm // nil when handling jobs of kinds other than replication
 	lock         *sync.Mutex
 }
 
 // EnterState transit the statemachine from the current state to the state in parameter.
 // It returns the next state the statemachine should tranit to.
+// The transition is recorded before the enter handler of the state runs, so the
+// creation time of the event is when the job entered the state. If the handler
+// fails, another event of the state is recorded with the error.
 func (sm *SM) EnterState(s string) (string, error) {
 	log.Debugf("Job id: %d, transiting from State: %s, to State: %s", sm.JobID, sm.CurrentState, s)
 	targets, ok := sm.Transitions[sm.CurrentState]
 	_, exist := targets[s]
 	_, isForced := sm.ForcedStates[s]
 	if !exist && !isForced {
-		return "", fmt.Errorf("job id: %d, transition from %s to %s does not exist", sm.JobID, sm.CurrentState, s)
+		err := fmt.Errorf("job id: %d, transition from %s to %s does not exist", sm.JobID, sm.CurrentState, s)
+		sm.recordEvent(sm.CurrentState, s, err.Error())
+		return "", err
 	}
 	exitHandler, ok := sm.Handlers[sm.CurrentState]
 	if ok {
 		if err := exitHandler.Exit(); err != nil {
+			sm.recordEvent(sm.CurrentState, s, err.Error())
 			return "", err
 		}
 	} else {
 		log.Debugf("Job id: %d, no handler found for state:%s, skip", sm.JobID, sm.CurrentState)
 	}
+	sm.recordEvent(sm.CurrentState, s, "")
+	sm.PreviousState = sm.CurrentState
+	sm.CurrentState = s
 	enterHandler, ok := sm.Handlers[s]
 	var next = models.JobContinue
 	var err error
 	if ok {
 		if next, err = enterHandler.Enter(); err != nil {
+			sm.recordEvent(s, s, err.Error())
 			return "", err
 		}
 	} else {
 		log.Debugf("Job id: %d, no handler found for state:%s, skip", sm.JobID, s)
 	}
-	sm.PreviousState = sm.CurrentState
-	sm.CurrentState = s
 	log.Debugf("Job id: %d, transition succeeded, current state: %s", sm.JobID, s)
 	return next, nil
 }
 
+// recordEvent persists the transition in DB, the failure of recording
+// doesn't affect the job
+func (sm *SM) recordEvent(from, to, message string) {
+	message = truncateMessage(message, maxEventMessageLen)
+	if _, err := addJobEvent(models.JobEvent{
+		Kind:      sm.Kind,
+		JobID:     sm.JobID,
+		FromState: from,
+		ToState:   to,
+		Message:   message,
+	}); err != nil {
+		log.Warningf("Job id: %d, failed to record the transition from %s to %s: %v", sm.JobID, from, to, err)
+	}
+}
+
+// truncateMessage cuts the message to at most n characters without splitting
+// a multi-byte character.
+func truncateMessage(message string, n int) string {
+	if utf8.RuneCountInString(message) <= n {
+		return message
+	}
+	return string([]rune(message)[:n])
+}
+
 // Start kicks off the statemachine to transit from current state to s, and moves on
 // It will search the transit map if the next state is "_continue", and
 // will enter error state if there's more than one possible path when next state is "_continue"
@@ -112,6 +136,7 @@ func (sm *SM) Start(s string) {
 		if n == models.JobContinue && len(sm.Transitions[sm.CurrentState]) != 1 {
 			log.Errorf("Job id: %d, next state is continue but there are %d possible next states in transition table", sm.JobID, len(sm.Transitions[sm.CurrentState]))
 			err = fmt.Errorf("Unable to continue")
+			sm.recordEvent(sm.CurrentState, n, err.Error())
 			break
 		}
 		n, err = sm.EnterState(n)
@@ -144,16 +169,16 @@ func (sm *SM) RemoveTransition(from string, to string) {
 
 // Stop will set the desired state as "stopped" such that when next tranisition happen the state machine will stop handling the current job
 // and the worker can release itself to the workerpool.
-func (sm *SM) Stop(id int64) {
-	log.Debugf("Trying to stop the job: %d", id)
+func (sm *SM) Stop(kind string, id int64) {
+	log.Debugf("Trying to stop the %s job: %d", kind, id)
 	sm.lock.Lock()
 	defer sm.lock.Unlock()
 	//need to check if the sm switched to other job
-	if id == sm.JobID {
+	if id == sm.JobID && kind == sm.Kind {
 		sm.desiredState = models.JobStopped
-		log.Debugf("Desired state of job %d is set to stopped", id)
+		log.Debugf("Desired state of %s job %d is set to stopped", kind, id)
 	} else {
-		log.Debugf("State machine has switched to job %d, so the action to stop job %d will be ignored", sm.JobID, id)
+		log.Debugf("State machine has switched to %s job %d, so the action to stop %s job %d will be ignored", sm.Kind, sm.JobID, kind, id)
 	}
 }
 
@@ -182,99 +207,40 @@ func (sm *SM) Init() {
 	}
 }
 
-// Reset resets the state machine so it will start handling another job.
-func (sm *SM) Reset(jid int64) (err error) {
+// Reset resets the state machine so it will start handling another job. It returns false
+// if the job should be canceled rather than run.
+func (sm *SM) Reset(kind string, jid int64) (bool, error) {
+	k := GetKind(kind)
+	if k == nil {
+		return false, fmt.Errorf("unsupported job kind: %s", kind)
+	}
+
 	//To ensure the new jobID is visible to the thread to stop the SM
 	sm.lock.Lock()
 	sm.JobID = jid
+	sm.Kind = kind
 	sm.desiredState = ""
 	sm.lock.Unlock()
 
-	sm.Logger, err = utils.NewLogger(sm.JobID)
-	if err != nil {
-		return
-	}
-	//init parms
-	job, err := dao.GetRepJob(sm.JobID)
-	if err != nil {
-		return fmt.Errorf("Failed to get job, error: %v", err)
-	}
-	if job == nil {
-		return fmt.Errorf("The job doesn't exist in DB, job id: %d", sm.JobID)
-	}
-	policy, err := dao.GetRepPolicy(job.PolicyID)
-	if err != nil {
-		return fmt.Errorf("Failed to get policy, error: %v", err)
-	}
-	if policy == nil {
-		return fmt.Errorf("The policy doesn't exist in DB, policy id:%d", job.PolicyID)
-	}
-
-	regURL, err := config.LocalRegURL()
-	if err != nil {
-		return err
-	}
-	verify, err := config.VerifyRemoteCert()
-	if err != nil {
-		return err
-	}
-	sm.Parms = &RepJobParm{
-		LocalRegURL: regURL,
-		Repository:  job.Repository,
-		Tags:        job.TagList,
-		Enabled:     policy.Enabled,
-		Operation:   job.Operation,
-		Insecure:    !verify,
-	}
-	if policy.Enabled == 0 {
-		//worker will cancel this job
-		return nil
-	}
-	target, err := dao.GetRepTarget(policy.TargetID)
+	var err error
+	sm.Logger, err = utils.NewLogger(sm.Kind, sm.JobID)
 	if err != nil {
-		return fmt.Errorf("Failed to get target, error: %v", err)
-	}
-	if target == nil {
-		return fmt.Errorf("The target doesn't exist in DB, target id: %d", policy.TargetID)
-	}
-	sm.Parms.TargetURL = target.URL
-	sm.Parms.TargetUsername = target.Username
-	pwd := target.Password
-
-	if len(pwd) != 0 {
-		key, err := config.SecretKey()
-		if err != nil {
-			return err
-		}
-		pwd, err = uti.ReversibleDecrypt(pwd, key)
-		if err != nil {
-			return fmt.Errorf("failed to decrypt password: %v", err)
-		}
+		return false, err
 	}
-
-	sm.Parms.TargetPassword = pwd
+	sm.Parms = nil
 
 	//init states handlers
 	sm.Handlers = make(map[string]StateHandler)
 	sm.Transitions = make(map[string]map[string]struct{})
 	sm.CurrentState = models.JobPending
 
-	sm.AddTransition(models.JobPending, models.JobRunning, StatusUpdater{sm.JobID, models.JobRunning})
-	sm.AddTransition(models.JobRetrying, models.JobRunning, StatusUpdater{sm.JobID, models.JobRunning})
-	sm.Handlers[models.JobError] = StatusUpdater{sm.JobID, models.JobError}
-	sm.Handlers[models.JobStopped] = StatusUpdater{sm.JobID, models.JobStopped}
-	sm.Handlers[models.JobRetrying] = Retry{sm.JobID}
+	sm.AddTransition(models.JobPending, models.JobRunning, StatusUpdater{sm.JobID, models.JobRunning, sm.Kind})
+	sm.AddTransition(models.JobRetrying, models.JobRunning, StatusUpdater{sm.JobID, models.JobRunning, sm.Kind})
+	sm.Handlers[models.JobError] = StatusUpdater{sm.JobID, models.JobError, sm.Kind}
+	sm.Handlers[models.JobStopped] = StatusUpdater{sm.JobID, models.JobStopped, sm.Kind}
+	sm.Handlers[models.JobRetrying] = Retry{sm.JobID, sm.Kind}
 
-	switch sm.Parms.Operation {
-	case models.RepOpTransfer:
-		addImgTransferTransition(sm)
-	case models.RepOpDelete:
-		addImgDeleteTransition(sm)
-	default:
-		err = fmt.Errorf("unsupported operation: %s", sm.Parms.Operation)
-	}
-
-	return err
+	return k.Init(sm)
 }
 
 //for testing onlly
@@ -282,25 +248,3 @@ func addTestTransition(sm *SM) error {
 	sm.AddTransition(models.JobRunning, "pull-img", ImgPuller{img: sm.Parms.Repository, logger: sm.Logger})
 	return nil
 }
-
-func addImgTransferTransition(sm *SM) {
-	base := replication.InitBaseHandler(sm.Parms.Repository, sm.Parms.LocalRegURL, config.JobserviceSecret(),
-		sm.Parms.TargetURL, sm.Parms.TargetUsername, sm.Parms.TargetPassword,
-		sm.Parms.Insecure, sm.Parms.Tags, sm.Logger)
-
-	sm.AddTransition(models.JobRunning, replication.StateInitialize, &replication.Initializer{BaseHandler: base})
-	sm.AddTransition(replication.StateInitialize, replication.StateCheck, &replication.Checker{BaseHandler: base})
-	sm.AddTransition(replication.StateCheck, replication.StatePullManifest, &replication.ManifestPuller{BaseHandler: base})
-	sm.AddTransition(replication.StatePullManifest, replication.StateTransferBlob, &replication.BlobTransfer{BaseHandler: base})
-	sm.AddTransition(replication.StatePullManifest, models.JobFinished, &StatusUpdater{sm.JobID, models.JobFinished})
-	sm.AddTransition(replication.StateTransferBlob, replication.StatePushManifest, &replication.ManifestPusher{BaseHandler: base})
-	sm.AddTransition(replication.StatePushManifest, replication.StatePullManifest, &replication.ManifestPuller{BaseHandler: base})
-}
-
-func addImgDeleteTransition(sm *SM) {
-	deleter := replication.NewDeleter(sm.Parms.Repository, sm.Parms.Tags, sm.Parms.TargetURL,
-		sm.Parms.TargetUsername, sm.Parms.TargetPassword, sm.Parms.Insecure, sm.Logger)
-
-	sm.AddTransition(models.JobRunning, replication.StateDelete, deleter)
-	sm.AddTransition(replication.StateDelete, models.JobFinished, &StatusUpdater{sm.JobID, models.JobFinished})
-}
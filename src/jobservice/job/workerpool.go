@@ -15,82 +15,209 @@
 package job
 
 import (
-	"github.com/vmware/harbor/src/common/dao"
+	"sync"
+	"time"
+
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/jobservice/config"
 )
 
+// the interval to check whether the max job workers is changed
+const workerPoolWatchInterval = 10 * time.Second
+
 type workerPool struct {
 	workerChan chan *Worker
+	// workerList contains the workers which are draining as they may be still handling jobs
 	workerList []*Worker
+	size       int
+	nextID     int
+	lock       *sync.Mutex
 }
 
 // WorkerPool is a set of workers each worker is associate to a statemachine for handling jobs.
 // it consists of a channel for free workers and a list to all workers
 var WorkerPool *workerPool
 
-// StopJobs accepts a list of jobs and will try to stop them if any of them is being executed by the worker.
-func (wp *workerPool) StopJobs(jobs []int64) {
-	log.Debugf("Works working on jobs: %v will be stopped", jobs)
+// WorkerPoolStats is the utilization of the worker pool
+type WorkerPoolStats struct {
+	// Size is the number of workers the pool is resized to
+	Size int `json:"size"`
+	// Busy is the number of workers which are handling jobs, including the draining ones
+	Busy int `json:"busy"`
+	// Idle is the number of workers which are waiting for jobs
+	Idle int `json:"idle"`
+	// Draining is the number of workers which will stop after finishing the current jobs
+	Draining int `json:"draining"`
+}
+
+func newWorkerPool() *workerPool {
+	return &workerPool{
+		workerChan: make(chan *Worker),
+		lock:       &sync.Mutex{},
+	}
+}
+
+// StopJobs accepts a list of jobs of the kind and will try to stop them if any of them is being executed by the worker.
+func (wp *workerPool) StopJobs(kind string, jobs []int64) {
+	log.Debugf("Works working on %s jobs: %v will be stopped", kind, jobs)
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
 	for _, id := range jobs {
 		for _, w := range wp.workerList {
-			if w.SM.JobID == id {
-				log.Debugf("found a worker whose job ID is %d, will try to stop it", id)
-				w.SM.Stop(id)
+			if w.SM.JobID == id && w.SM.Kind == kind {
+				log.Debugf("found a worker whose %s job ID is %d, will try to stop it", kind, id)
+				w.SM.Stop(kind, id)
 			}
 		}
 	}
 }
 
+// Resize adds or drains workers to make the number of workers n, idle workers are
+// drained first and busy ones stop only after finishing their current jobs.
+func (wp *workerPool) Resize(n int) {
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	log.Infof("resizing the worker pool from %d to %d", wp.size, n)
+
+	var idle, busy []*Worker
+	for _, w := range wp.workerList {
+		if w.draining {
+			continue
+		}
+		if w.busy {
+			busy = append(busy, w)
+		} else {
+			idle = append(idle, w)
+		}
+	}
+
+	// busy workers come first so that idle workers are drained first
+	active := append(busy, idle...)
+	for len(active) < n {
+		worker := NewWorker(wp.nextID)
+		worker.pool = wp
+		wp.nextID++
+		wp.workerList = append(wp.workerList, worker)
+		active = append(active, worker)
+		worker.Start()
+		log.Debugf("worker %d started", worker.ID)
+	}
+
+	for _, w := range active[n:] {
+		w.draining = true
+		w.Stop()
+		log.Debugf("worker %d is draining", w.ID)
+	}
+
+	wp.size = n
+}
+
+// Stats returns the utilization of the worker pool
+func (wp *workerPool) Stats() WorkerPoolStats {
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
+	s := WorkerPoolStats{
+		Size: wp.size,
+	}
+	for _, w := range wp.workerList {
+		switch {
+		case w.busy:
+			s.Busy++
+		case !w.draining:
+			s.Idle++
+		}
+		if w.draining {
+			s.Draining++
+		}
+	}
+	return s
+}
+
+func (wp *workerPool) setBusy(w *Worker, busy bool) {
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
+	w.busy = busy
+}
+
+func (wp *workerPool) remove(w *Worker) {
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
+	for i, worker := range wp.workerList {
+		if worker == w {
+			wp.workerList = append(wp.workerList[:i], wp.workerList[i+1:]...)
+			return
+		}
+	}
+}
+
 // Worker consists of a channel for job from which worker gets the next job to handle, and a pointer to a statemachine,
 // the actual work to handle the job is done via state machine.
 type Worker struct {
-	ID      int
-	RepJobs chan int64
-	SM      *SM
-	quit    chan bool
+	ID       int
+	jobs     chan queuedJob
+	SM       *SM
+	quit     chan struct{}
+	stopOnce *sync.Once
+	pool     *workerPool
+	// busy and draining are protected by the lock of worker pool
+	busy     bool
+	draining bool
 }
 
 // Start is a loop worker gets id from its channel and handle it.
 func (w *Worker) Start() {
 	go func() {
+		defer w.pool.remove(w)
 		for {
-			WorkerPool.workerChan <- w
+			// check quit first so that a draining worker won't take another job
+			select {
+			case <-w.quit:
+				log.Debugf("worker: %d, will stop.", w.ID)
+				return
+			default:
+			}
+
 			select {
-			case jobID := <-w.RepJobs:
-				log.Debugf("worker: %d, will handle job: %d", w.ID, jobID)
-				w.handleRepJob(jobID)
-			case q := <-w.quit:
-				if q {
-					log.Debugf("worker: %d, will stop.", w.ID)
-					return
-				}
+			case w.pool.workerChan <- w:
+			case <-w.quit:
+				log.Debugf("worker: %d, will stop.", w.ID)
+				return
 			}
+
+			// the dispatcher always sends a job after taking the worker
+			j := <-w.jobs
+			log.Debugf("worker: %d, will handle %s job: %d", w.ID, j.kind, j.id)
+			w.pool.setBusy(w, true)
+			w.handleJob(j.kind, j.id)
+			w.pool.setBusy(w, false)
 		}
 	}()
 }
 
-// Stop ...
+// Stop makes the worker stop after it finishes the current job
 func (w *Worker) Stop() {
-	go func() {
-		w.quit <- true
-	}()
+	w.stopOnce.Do(func() {
+		close(w.quit)
+	})
 }
 
-func (w *Worker) handleRepJob(id int64) {
-	err := w.SM.Reset(id)
+func (w *Worker) handleJob(kind string, id int64) {
+	run, err := w.SM.Reset(kind, id)
 	if err != nil {
-		log.Errorf("Worker %d, failed to re-initialize statemachine for job: %d, error: %v", w.ID, id, err)
-		err2 := dao.UpdateRepJobStatus(id, models.JobError)
+		log.Errorf("Worker %d, failed to re-initialize statemachine for %s job: %d, error: %v", w.ID, kind, id, err)
+		err2 := updateStatus(kind, id, models.JobError)
 		if err2 != nil {
-			log.Errorf("Failed to update job status to ERROR, job: %d, error:%v", id, err2)
+			log.Errorf("Failed to update job status to ERROR, %s job: %d, error:%v", kind, id, err2)
 		}
 		return
 	}
-	if w.SM.Parms.Enabled == 0 {
-		log.Debugf("The policy of job:%d is disabled, will cancel the job", id)
-		_ = dao.UpdateRepJobStatus(id, models.JobCanceled)
+	if !run {
+		log.Debugf("The %s job:%d will be canceled", kind, id)
+		_ = updateStatus(kind, id, models.JobCanceled)
 		w.SM.Logger.Info("The job has been canceled")
 	} else {
 		w.SM.Start(models.JobRunning)
@@ -100,10 +227,11 @@ func (w *Worker) handleRepJob(id int64) {
 // NewWorker returns a pointer to new instance of worker
 func NewWorker(id int) *Worker {
 	w := &Worker{
-		ID:      id,
-		RepJobs: make(chan int64),
-		quit:    make(chan bool),
-		SM:      &SM{},
+		ID:       id,
+		jobs:     make(chan queuedJob),
+		quit:     make(chan struct{}),
+		stopOnce: &sync.Once{},
+		SM:       &SM{},
 	}
 	w.SM.Init()
 	return w
@@ -115,29 +243,35 @@ func InitWorkerPool() error {
 	if err != nil {
 		return err
 	}
-	WorkerPool = &workerPool{
-		workerChan: make(chan *Worker, n),
-		workerList: make([]*Worker, 0, n),
-	}
-	for i := 0; i < n; i++ {
-		worker := NewWorker(i)
-		WorkerPool.workerList = append(WorkerPool.workerList, worker)
-		worker.Start()
-		log.Debugf("worker %d started", worker.ID)
-	}
+	WorkerPool = newWorkerPool()
+	WorkerPool.Resize(n)
 	return nil
 }
 
+// WatchWorkerPool resizes the worker pool when the max job workers in configuration is changed.
+func WatchWorkerPool() {
+	for range time.Tick(workerPoolWatchInterval) {
+		n, err := config.MaxJobWorkers()
+		if err != nil {
+			log.Errorf("failed to get max job workers: %v", err)
+			continue
+		}
+		if n != WorkerPool.Stats().Size {
+			WorkerPool.Resize(n)
+		}
+	}
+}
+
 // Dispatch will listen to the jobQueue of job service and try to pick a free worker from the worker pool and assign the job to it.
 func Dispatch() {
 	for {
 		select {
-		case job := <-jobQueue:
-			go func(jobID int64) {
-				log.Debugf("Trying to dispatch job: %d", jobID)
+		case j := <-jobQueue:
+			go func(j queuedJob) {
+				log.Debugf("Trying to dispatch %s job: %d", j.kind, j.id)
 				worker := <-WorkerPool.workerChan
-				worker.RepJobs <- jobID
-			}(job)
+				worker.jobs <- j
+			}(j)
 		}
 	}
 }
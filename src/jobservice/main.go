@@ -43,6 +43,7 @@ func main() {
 
 	initRouters()
 	job.InitWorkerPool()
+	go job.WatchWorkerPool()
 	go job.Dispatch()
 	resumeJobs()
 	beego.Run()
@@ -58,11 +59,28 @@ func resumeJobs() {
 	if err == nil {
 		for _, j := range jobs {
 			log.Debugf("Resuming job: %d", j.ID)
-			job.Schedule(j.ID)
+			job.Schedule(models.JobKindReplication, j.ID)
 		}
 	} else {
 		log.Warningf("Failed to jobs to resume, error: %v", err)
 	}
+
+	if err = dao.ResetRunningGenericJobs(); err != nil {
+		log.Warningf("Failed to reset all running generic jobs to pending, error: %v", err)
+	}
+	genericJobs, err := dao.GetJobsByStatus(models.JobPending, models.JobRetrying)
+	if err != nil {
+		log.Warningf("Failed to get generic jobs to resume, error: %v", err)
+		return
+	}
+	for _, j := range genericJobs {
+		if !job.IsGenericKind(j.Kind) {
+			log.Warningf("The kind of job %d is %s which is not supported, skip", j.ID, j.Kind)
+			continue
+		}
+		log.Debugf("Resuming %s job: %d", j.Kind, j.ID)
+		job.Schedule(j.Kind, j.ID)
+	}
 }
 
 func init() {
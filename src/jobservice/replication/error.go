@@ -22,6 +22,10 @@ func retry(err error) bool {
 	if err == nil {
 		return false
 	}
+	// the content may be corrupted by a flaky network or proxy
+	if _, ok := err.(*BlobMismatchError); ok {
+		return true
+	}
 	return isNetworkErr(err)
 }
 
@@ -14,9 +14,124 @@
 package replication
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
 	"testing"
+
+	"github.com/docker/distribution/manifest/schema2"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/common/utils/registry"
+	"github.com/vmware/harbor/src/common/utils/test"
 )
 
 func TestMain(t *testing.T) {
 }
 
+// TestReplicateWithFakeRegistry replicates all tags of a repository from one
+// fake registry to another by walking through the states of a replication job
+// except checking the project, which needs the DB
+func TestReplicateWithFakeRegistry(t *testing.T) {
+	repository := "library/hello-world"
+	// the source registry doesn't support pagination
+	src := test.NewRegistry(&test.RegistryOptions{
+		DisablePagination: true,
+	})
+	defer src.Close()
+	dst := test.NewRegistry(nil)
+	defer dst.Close()
+
+	config := []byte("config")
+	shared := []byte("shared layer")
+	payloads := map[string][]byte{}
+	for _, tag := range []string{"v1", "v2", "v3"} {
+		layer := []byte("layer of " + tag)
+		payloads[tag] = []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%s",`+
+			`"config":{"mediaType":"application/octet-stream","size":%d,"digest":"%s"},`+
+			`"layers":[{"mediaType":"application/octet-stream","size":%d,"digest":"%s"},`+
+			`{"mediaType":"application/octet-stream","size":%d,"digest":"%s"}]}`,
+			schema2.MediaTypeManifest,
+			len(config), src.AddBlob(repository, config),
+			len(shared), src.AddBlob(repository, shared),
+			len(layer), src.AddBlob(repository, layer)))
+		src.AddManifest(repository, tag, schema2.MediaTypeManifest, payloads[tag])
+	}
+
+	logger := log.New(os.Stdout, log.NewTextFormatter(), log.WarningLevel)
+	// the handler is not created by InitBaseHandler which looks up the
+	// project in DB
+	base := &BaseHandler{
+		project:        "library",
+		repository:     repository,
+		srcURL:         src.URL,
+		srcSecret:      "secret",
+		dstURL:         dst.URL,
+		dstUsr:         "admin",
+		dstPwd:         "Harbor12345",
+		insecure:       true,
+		blobsExistence: map[string]bool{},
+		logger:         logger,
+	}
+
+	state, err := (&Initializer{BaseHandler: base}).Enter()
+	if err != nil || state != StateCheck {
+		t.Fatalf("failed to initialize: %s, %v", state, err)
+	}
+	if len(base.tags) != 3 {
+		t.Fatalf("unexpected tags to replicate: %v", base.tags)
+	}
+
+	handlers := map[string]interface {
+		Enter() (string, error)
+	}{
+		StatePullManifest:  &ManifestPuller{BaseHandler: base},
+		StateTransferBlob:  &BlobTransfer{BaseHandler: base},
+		StatePushManifest:  &ManifestPusher{BaseHandler: base},
+		models.JobFinished: nil,
+	}
+	state = StatePullManifest
+	for i := 0; state != models.JobFinished; i++ {
+		if i > 100 {
+			t.Fatalf("the replication does not finish")
+		}
+		handler, ok := handlers[state]
+		if !ok {
+			t.Fatalf("unexpected state: %s", state)
+		}
+		if state, err = handler.Enter(); err != nil {
+			t.Fatalf("failed to replicate: %v", err)
+		}
+	}
+
+	client, err := registry.NewRepository(repository, dst.URL, &http.Client{})
+	if err != nil {
+		t.Fatalf("failed to create client for the destination: %v", err)
+	}
+	tags, err := client.ListTag()
+	if err != nil {
+		t.Fatalf("failed to list tags on the destination: %v", err)
+	}
+	if len(tags) != 3 {
+		t.Fatalf("unexpected tags on the destination: %v", tags)
+	}
+	for tag, payload := range payloads {
+		_, _, p, err := client.PullManifest(tag, []string{schema2.MediaTypeManifest})
+		if err != nil {
+			t.Fatalf("failed to pull manifest of %s from the destination: %v", tag, err)
+		}
+		if string(p) != string(payload) {
+			t.Errorf("unexpected manifest of %s on the destination: %s", tag, string(p))
+		}
+	}
+
+	_, data, err := client.PullBlob(src.AddBlob(repository, shared))
+	if err != nil {
+		t.Fatalf("failed to pull the shared layer from the destination: %v", err)
+	}
+	defer data.Close()
+	if b, _ := ioutil.ReadAll(data); string(b) != string(shared) {
+		t.Errorf("unexpected content of the shared layer: %s", string(b))
+	}
+}
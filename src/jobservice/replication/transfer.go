@@ -25,10 +25,12 @@ import (
 	"strings"
 
 	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
 	"github.com/docker/distribution/manifest/schema1"
 	"github.com/docker/distribution/manifest/schema2"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/common/utils/registry"
 	"github.com/vmware/harbor/src/common/utils/registry/auth"
@@ -107,10 +109,13 @@ func (b *BaseHandler) Exit() error {
 	return nil
 }
 
+// getProjectName returns the longest existing project which the repository
+// belongs to, as the projects may be nested
 func getProjectName(repository string) string {
 	repository = strings.TrimSpace(repository)
 	repository = strings.TrimRight(repository, "/")
-	return repository[:strings.LastIndex(repository, "/")]
+	project, _ := dao.ParseRepository(repository)
+	return project
 }
 
 // Initializer creates clients for source and destination registry,
@@ -186,37 +191,61 @@ func (c *Checker) Enter() (string, error) {
 }
 
 func (c *Checker) enter() (string, error) {
-	project, err := dao.GetProjectByName(c.project)
+	// the parent projects must exist on the destination before the project
+	// can be created, so create them from the root to the leaf
+	parents := utils.ParentProjects(c.project)
+	names := []string{}
+	for i := len(parents) - 1; i >= 0; i-- {
+		names = append(names, parents[i])
+	}
+	names = append(names, c.project)
+
+	for _, name := range names {
+		if err := c.ensureProject(name); err != nil {
+			return "", err
+		}
+	}
+
+	return StatePullManifest, nil
+}
+
+func (c *Checker) ensureProject(name string) error {
+	project, err := dao.GetProjectByName(name)
 	if err != nil {
-		c.logger.Errorf("an error occurred while getting project %s in DB: %v", c.project, err)
-		return "", err
+		c.logger.Errorf("an error occurred while getting project %s in DB: %v", name, err)
+		return err
+	}
+	if project == nil {
+		err = fmt.Errorf("project %s not found in DB", name)
+		c.logger.Error(err)
+		return err
 	}
 
-	err = c.createProject(project.Public)
+	err = c.createProject(name, project.Public)
 	if err == nil {
-		c.logger.Infof("project %s is created on %s with user %s", c.project, c.dstURL, c.dstUsr)
-		return StatePullManifest, nil
+		c.logger.Infof("project %s is created on %s with user %s", name, c.dstURL, c.dstUsr)
+		return nil
 	}
 
 	// other job may be also doing the same thing when the current job
 	// is creating project, so when the response code is 409, continue
 	// to do next step
 	if err == ErrConflict {
-		c.logger.Warningf("the status code is 409 when creating project %s on %s with user %s, try to do next step", c.project, c.dstURL, c.dstUsr)
-		return StatePullManifest, nil
+		c.logger.Warningf("the status code is 409 when creating project %s on %s with user %s, try to do next step", name, c.dstURL, c.dstUsr)
+		return nil
 	}
 
-	c.logger.Errorf("an error occurred while creating project %s on %s with user %s : %v", c.project, c.dstURL, c.dstUsr, err)
+	c.logger.Errorf("an error occurred while creating project %s on %s with user %s : %v", name, c.dstURL, c.dstUsr, err)
 
-	return "", err
+	return err
 }
 
-func (c *Checker) createProject(public int) error {
+func (c *Checker) createProject(name string, public int) error {
 	project := struct {
 		ProjectName string `json:"project_name"`
 		Public      int    `json:"public"`
 	}{
-		ProjectName: c.project,
+		ProjectName: name,
 		Public:      public,
 	}
 
@@ -264,7 +293,7 @@ func (c *Checker) createProject(public int) error {
 	}
 
 	return fmt.Errorf("failed to create project %s on %s with user %s: %d %s",
-		c.project, c.dstURL, c.dstUsr, resp.StatusCode, string(message))
+		name, c.dstURL, c.dstUsr, resp.StatusCode, string(message))
 }
 
 // ManifestPuller pulls the manifest of a tag. And if no tag needs to be pulled,
@@ -368,7 +397,8 @@ func (b *BlobTransfer) enter() (string, error) {
 	tag := b.tags[0]
 	for _, blob := range b.blobs {
 		b.logger.Infof("transferring blob %s of %s:%s to %s ...", blob, name, tag, b.dstURL)
-		size, data, err := b.srcClient.PullBlob(blob)
+		descriptor := b.descriptor(blob)
+		size, data, err := b.srcClient.PullBlobWithResume(blob)
 		if err != nil {
 			b.logger.Errorf("an error occurred while pulling blob %s of %s:%s from %s: %v", blob, name, tag, b.srcURL, err)
 			return "", err
@@ -376,7 +406,26 @@ func (b *BlobTransfer) enter() (string, error) {
 		if data != nil {
 			defer data.Close()
 		}
-		if err = b.dstClient.PushBlob(blob, size, data); err != nil {
+		if descriptor.Size > 0 && size != descriptor.Size {
+			err = &BlobMismatchError{
+				ExpectedDigest: blob,
+				ExpectedSize:   descriptor.Size,
+				ActualSize:     size,
+			}
+			b.logger.Errorf("the size of blob %s of %s:%s from %s does not match: %v", blob, name, tag, b.srcURL, err)
+			return "", err
+		}
+		verifier, err := newVerifyingReader(data, descriptor)
+		if err != nil {
+			b.logger.Errorf("failed to verify blob %s of %s:%s: %v", blob, name, tag, err)
+			return "", err
+		}
+		if err = b.dstClient.PushBlob(blob, size, verifier); err != nil {
+			if verifier.err != nil {
+				b.logger.Errorf("blob %s of %s:%s from %s is corrupted, expected digest: %s, actual digest: %s, push to %s is aborted: %v",
+					blob, name, tag, b.srcURL, verifier.err.ExpectedDigest, verifier.err.ActualDigest, b.dstURL, verifier.err)
+				return "", verifier.err
+			}
 			b.logger.Errorf("an error occurred while pushing blob %s of %s:%s to %s : %v", blob, name, tag, b.dstURL, err)
 			return "", err
 		}
@@ -386,6 +435,21 @@ func (b *BlobTransfer) enter() (string, error) {
 	return StatePushManifest, nil
 }
 
+// descriptor returns the descriptor of the blob in the manifest, only the
+// digest is set if the blob is not referenced by the manifest
+func (b *BlobTransfer) descriptor(blob string) distribution.Descriptor {
+	if b.manifest != nil {
+		for _, descriptor := range b.manifest.References() {
+			if descriptor.Digest.String() == blob {
+				return descriptor
+			}
+		}
+	}
+	return distribution.Descriptor{
+		Digest: digest.Digest(blob),
+	}
+}
+
 // ManifestPusher pushs the manifest to destination registry
 type ManifestPusher struct {
 	*BaseHandler
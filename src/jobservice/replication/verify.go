@@ -0,0 +1,104 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+	"github.com/vmware/harbor/src/common/utils/registry"
+)
+
+// BlobMismatchError is returned when the content of a blob being transferred
+// does not match its descriptor
+type BlobMismatchError struct {
+	ExpectedDigest string
+	ActualDigest   string
+	ExpectedSize   int64
+	ActualSize     int64
+}
+
+func (b *BlobMismatchError) Error() string {
+	return fmt.Sprintf("blob mismatch, expected digest: %s, actual digest: %s, expected size: %d, actual size: %d",
+		b.ExpectedDigest, b.ActualDigest, b.ExpectedSize, b.ActualSize)
+}
+
+// verifyingReader computes the digest and size of the content read through it
+// and returns a BlobMismatchError rather than io.EOF if they don't match the
+// descriptor, so the push reading from it is aborted before it completes.
+// The size is not checked if it is unknown(e.g. the descriptors of schema1 manifest).
+type verifyingReader struct {
+	reader     io.Reader
+	descriptor distribution.Descriptor
+	hash       hash.Hash
+	size       int64
+	// err is the BlobMismatchError returned by Read
+	err *BlobMismatchError
+}
+
+func newVerifyingReader(reader io.Reader, descriptor distribution.Descriptor) (*verifyingReader, error) {
+	algorithm := descriptor.Digest.Algorithm()
+	if !algorithm.Available() {
+		return nil, fmt.Errorf("unsupported digest algorithm %s of blob %s", algorithm, descriptor.Digest)
+	}
+	return &verifyingReader{
+		reader:     reader,
+		descriptor: descriptor,
+		hash:       algorithm.Hash(),
+	}, nil
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.reader.Read(p)
+	if n > 0 {
+		v.hash.Write(p[:n])
+		v.size += int64(n)
+		if v.descriptor.Size > 0 && v.size > v.descriptor.Size {
+			return n, v.mismatch()
+		}
+	}
+
+	switch err.(type) {
+	case *registry.DigestMismatchError:
+		// the content pulled has been verified against the same digest
+		return n, v.mismatch()
+	}
+
+	if err == io.EOF {
+		if (v.descriptor.Size > 0 && v.size != v.descriptor.Size) ||
+			digest.NewDigest(v.descriptor.Digest.Algorithm(), v.hash) != v.descriptor.Digest {
+			return n, v.mismatch()
+		}
+	}
+
+	return n, err
+}
+
+func (v *verifyingReader) mismatch() error {
+	v.err = &BlobMismatchError{
+		ExpectedDigest: v.descriptor.Digest.String(),
+		ActualDigest:   digest.NewDigest(v.descriptor.Digest.Algorithm(), v.hash).String(),
+		ExpectedSize:   v.descriptor.Size,
+		ActualSize:     v.size,
+	}
+	return v.err
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package replication
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/digest"
+)
+
+func TestVerifyingReader(t *testing.T) {
+	content := []byte("blob")
+	cases := []struct {
+		descriptor distribution.Descriptor
+		mismatch   bool
+	}{
+		{distribution.Descriptor{Digest: digest.FromBytes(content), Size: 4}, false},
+		// the size is unknown
+		{distribution.Descriptor{Digest: digest.FromBytes(content)}, false},
+		{distribution.Descriptor{Digest: digest.FromBytes(content), Size: 3}, true},
+		{distribution.Descriptor{Digest: digest.FromBytes(content), Size: 5}, true},
+		{distribution.Descriptor{Digest: digest.FromBytes([]byte("corrupted")), Size: 4}, true},
+	}
+
+	for _, c := range cases {
+		reader, err := newVerifyingReader(bytes.NewReader(content), c.descriptor)
+		if err != nil {
+			t.Fatalf("failed to create verifying reader: %v", err)
+		}
+		_, err = ioutil.ReadAll(reader)
+		if !c.mismatch {
+			if err != nil {
+				t.Errorf("unexpected error for %v: %v", c.descriptor, err)
+			}
+			continue
+		}
+
+		mismatch, ok := err.(*BlobMismatchError)
+		if !ok {
+			t.Errorf("expected BlobMismatchError for %v, got %v", c.descriptor, err)
+			continue
+		}
+		if mismatch.ExpectedDigest != c.descriptor.Digest.String() {
+			t.Errorf("unexpected expected digest: %s != %s", mismatch.ExpectedDigest, c.descriptor.Digest)
+		}
+		if !retry(err) {
+			t.Errorf("BlobMismatchError should be retryable")
+		}
+	}
+}
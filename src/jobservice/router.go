@@ -24,4 +24,11 @@ func initRouters() {
 	beego.Router("/api/jobs/replication", &api.ReplicationJob{})
 	beego.Router("/api/jobs/replication/:id/log", &api.ReplicationJob{}, "get:GetLog")
 	beego.Router("/api/jobs/replication/actions", &api.ReplicationJob{}, "post:HandleAction")
+	beego.Router("/api/jobs", &api.GenericJob{}, "get:List;post:Post")
+	beego.Router("/api/jobs/:id([0-9]+)", &api.GenericJob{}, "get:Get")
+	beego.Router("/api/jobs/:id([0-9]+)/log", &api.GenericJob{}, "get:GetLog")
+	beego.Router("/api/jobs/:id([0-9]+)/events", &api.GenericJob{}, "get:GetEvents")
+	beego.Router("/api/jobs/:id([0-9]+)/actions", &api.GenericJob{}, "post:HandleAction")
+	beego.Router("/api/workers/stats", &api.WorkerPool{}, "get:Stats")
+	beego.Router("/api/registry_client/metrics", &api.RegistryClient{}, "get:Metrics")
 }
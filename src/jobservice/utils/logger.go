@@ -21,13 +21,14 @@ import (
 	"path/filepath"
 	"strconv"
 
+	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/jobservice/config"
 )
 
 // NewLogger create a logger for a speicified job
-func NewLogger(jobID int64) (*log.Logger, error) {
-	logFile, err := GetJobLogPath(jobID)
+func NewLogger(kind string, jobID int64) (*log.Logger, error) {
+	logFile, err := GetJobLogPath(kind, jobID)
 	if err != nil {
 		return nil, err
 	}
@@ -47,8 +48,12 @@ func NewLogger(jobID int64) (*log.Logger, error) {
 }
 
 // GetJobLogPath returns the absolute path in which the job log file is located.
-func GetJobLogPath(jobID int64) (string, error) {
+// The log files of replication jobs are named as job_<id>.log, others <kind>_job_<id>.log
+func GetJobLogPath(kind string, jobID int64) (string, error) {
 	f := fmt.Sprintf("job_%d.log", jobID)
+	if kind != models.JobKindReplication {
+		f = fmt.Sprintf("%s_job_%d.log", kind, jobID)
+	}
 	k := jobID / 1000
 	p := ""
 	var d string
@@ -14,9 +14,40 @@
 package utils
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/vmware/harbor/src/common/models"
 )
 
 func TestMain(t *testing.T) {
 }
 
+func TestGetJobLogPath(t *testing.T) {
+	if err := os.Setenv("LOG_DIR", "/tmp/jobs"); err != nil {
+		t.Fatalf("failed to set env LOG_DIR: %v", err)
+	}
+	defer os.Unsetenv("LOG_DIR")
+
+	cases := []struct {
+		kind     string
+		id       int64
+		expected string
+	}{
+		{models.JobKindReplication, 1, "job_1.log"},
+		{models.JobKindReplication, 1234, "1/job_1234.log"},
+		{"gc", 1234, "1/gc_job_1234.log"},
+	}
+
+	for _, c := range cases {
+		p, err := GetJobLogPath(c.kind, c.id)
+		if err != nil {
+			t.Fatalf("failed to get log path of %s job %d: %v", c.kind, c.id, err)
+		}
+		if p != filepath.Join("/tmp/jobs", c.expected) {
+			t.Errorf("unexpected log path of %s job %d: %s", c.kind, c.id, p)
+		}
+	}
+}
+
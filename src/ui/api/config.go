@@ -16,13 +16,16 @@ package api
 
 import (
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"strconv"
 
 	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/api"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
 	"github.com/vmware/harbor/src/ui/config"
 )
 
@@ -31,6 +34,7 @@ var (
 	validKeys = []string{
 		common.ExtEndpoint,
 		common.AUTHMode,
+		common.AUTHChain,
 		common.DatabaseType,
 		common.MySQLHost,
 		common.MySQLPort,
@@ -63,6 +67,26 @@ var (
 		common.CfgExpiration,
 		common.JobLogDir,
 		common.AdminInitialPassword,
+		common.TokenAuditAccessLog,
+		common.OIDCEndpoint,
+		common.OIDCClientID,
+		common.OIDCClientSecret,
+		common.OIDCScope,
+		common.OIDCGroupsClaim,
+		common.OIDCGroupRoleMapping,
+		common.OIDCVerifyCert,
+		common.ProxyAuthTrustedCIDRs,
+		common.ProxyAuthSecret,
+		common.ProxyAuthGroupRoleMapping,
+		common.ProxyAuthTokenService,
+		common.LockoutThreshold,
+		common.LockoutWindow,
+		common.LockoutDuration,
+		common.MFAEnforceAdmin,
+		common.PasswordMinLength,
+		common.PasswordRequiredClasses,
+		common.PasswordHistory,
+		common.PasswordMaxAge,
 	}
 
 	numKeys = []string{
@@ -73,12 +97,22 @@ var (
 		common.MaxJobWorkers,
 		common.TokenExpiration,
 		common.CfgExpiration,
+		common.LockoutThreshold,
+		common.LockoutWindow,
+		common.LockoutDuration,
+		common.PasswordMinLength,
+		common.PasswordHistory,
+		common.PasswordMaxAge,
 	}
 
 	boolKeys = []string{
 		common.EmailSSL,
 		common.SelfRegistration,
 		common.VerifyRemoteCert,
+		common.TokenAuditAccessLog,
+		common.OIDCVerifyCert,
+		common.ProxyAuthTokenService,
+		common.MFAEnforceAdmin,
 	}
 
 	passwordKeys = []string{
@@ -86,6 +120,8 @@ var (
 		common.EmailPassword,
 		common.LDAPSearchPwd,
 		common.MySQLPassword,
+		common.OIDCClientSecret,
+		common.ProxyAuthSecret,
 	}
 )
 
@@ -211,13 +247,33 @@ func validateCfg(c map[string]string) (bool, error) {
 	}
 
 	if value, ok := c[common.AUTHMode]; ok {
-		if value != common.DBAuth && value != common.LDAPAuth {
-			return isSysErr, fmt.Errorf("invalid %s, shoud be %s or %s", common.AUTHMode, common.DBAuth, common.LDAPAuth)
+		if !isValidAuthMode(value) {
+			return isSysErr, fmt.Errorf("invalid %s, shoud be %s, %s, %s or %s", common.AUTHMode, common.DBAuth, common.LDAPAuth, common.OIDCAuth, common.ProxyAuth)
 		}
 		mode = value
 	}
 
-	if mode == common.LDAPAuth {
+	chain, err := config.AuthChain()
+	if err != nil {
+		isSysErr = true
+		return isSysErr, err
+	}
+
+	if value, ok := c[common.AUTHChain]; ok {
+		chain = config.SplitList(value)
+		modes := map[string]bool{}
+		for _, m := range chain {
+			if !isValidAuthMode(m) {
+				return isSysErr, fmt.Errorf("invalid %s, each auth mode shoud be %s, %s, %s or %s", common.AUTHChain, common.DBAuth, common.LDAPAuth, common.OIDCAuth, common.ProxyAuth)
+			}
+			if modes[m] {
+				return isSysErr, fmt.Errorf("invalid %s, duplicate auth mode %s", common.AUTHChain, m)
+			}
+			modes[m] = true
+		}
+	}
+
+	if mode == common.LDAPAuth || inAuthChain(chain, common.LDAPAuth) {
 		ldap, err := config.LDAP()
 		if err != nil {
 			isSysErr = true
@@ -247,6 +303,61 @@ func validateCfg(c map[string]string) (bool, error) {
 		}
 	}
 
+	if mode == common.OIDCAuth || inAuthChain(chain, common.OIDCAuth) {
+		setting, err := config.OIDC()
+		if err != nil {
+			isSysErr = true
+			return isSysErr, err
+		}
+
+		if len(setting.Endpoint) == 0 {
+			if _, ok := c[common.OIDCEndpoint]; !ok {
+				return isSysErr, fmt.Errorf("%s is missing", common.OIDCEndpoint)
+			}
+		}
+		if len(setting.ClientID) == 0 {
+			if _, ok := c[common.OIDCClientID]; !ok {
+				return isSysErr, fmt.Errorf("%s is missing", common.OIDCClientID)
+			}
+		}
+	}
+
+	if endpoint, ok := c[common.OIDCEndpoint]; ok && len(endpoint) > 0 {
+		u, err := url.Parse(endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return isSysErr, fmt.Errorf("invalid %s, should be an http or https URL", common.OIDCEndpoint)
+		}
+	}
+	if mode == common.ProxyAuth || inAuthChain(chain, common.ProxyAuth) {
+		setting, err := config.ProxyAuth()
+		if err != nil {
+			isSysErr = true
+			return isSysErr, err
+		}
+
+		_, hasCIDRs := c[common.ProxyAuthTrustedCIDRs]
+		_, hasSecret := c[common.ProxyAuthSecret]
+		if len(setting.TrustedCIDRs) == 0 && len(setting.Secret) == 0 && !hasCIDRs && !hasSecret {
+			return isSysErr, fmt.Errorf("%s or %s is missing", common.ProxyAuthTrustedCIDRs, common.ProxyAuthSecret)
+		}
+	}
+
+	if cidrs, ok := c[common.ProxyAuthTrustedCIDRs]; ok {
+		for _, cidr := range config.SplitList(cidrs) {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return isSysErr, fmt.Errorf("invalid %s: %v", common.ProxyAuthTrustedCIDRs, err)
+			}
+		}
+	}
+
+	for _, key := range []string{common.OIDCGroupRoleMapping, common.ProxyAuthGroupRoleMapping} {
+		if mapping, ok := c[key]; ok {
+			if _, err := auth.ParseGroupRoleMapping(mapping); err != nil {
+				return isSysErr, fmt.Errorf("invalid %s: %v", key, err)
+			}
+		}
+	}
+
 	if ldapURL, ok := c[common.LDAPURL]; ok && len(ldapURL) == 0 {
 		return isSysErr, fmt.Errorf("%s is empty", common.LDAPURL)
 	}
@@ -294,6 +405,28 @@ func validateCfg(c map[string]string) (bool, error) {
 			k == common.MySQLPort) && n > 65535 {
 			return isSysErr, fmt.Errorf("invalid %s: %s", k, v)
 		}
+
+		if (k == common.LockoutWindow ||
+			k == common.LockoutDuration) && n == 0 {
+			return isSysErr, fmt.Errorf("invalid %s: %s", k, v)
+		}
+
+		if k == common.PasswordMinLength && (n == 0 || n > maxPasswordLen) {
+			return isSysErr, fmt.Errorf("invalid %s: %s", k, v)
+		}
+
+		if k == common.PasswordHistory && n > dao.MaxPasswordHistory {
+			return isSysErr, fmt.Errorf("invalid %s: %s, should be no more than %d",
+				k, v, dao.MaxPasswordHistory)
+		}
+	}
+
+	if classes, ok := c[common.PasswordRequiredClasses]; ok {
+		for _, class := range config.SplitList(classes) {
+			if !auth.IsPasswordClass(class) {
+				return isSysErr, fmt.Errorf("invalid %s: %s", common.PasswordRequiredClasses, class)
+			}
+		}
 	}
 
 	if crt, ok := c[common.ProjectCreationRestriction]; ok &&
@@ -365,6 +498,20 @@ func convertForGet(cfg map[string]interface{}) (map[string]*value, error) {
 	return result, nil
 }
 
+func isValidAuthMode(mode string) bool {
+	return mode == common.DBAuth || mode == common.LDAPAuth ||
+		mode == common.OIDCAuth || mode == common.ProxyAuth
+}
+
+func inAuthChain(chain []string, mode string) bool {
+	for _, m := range chain {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
 func authModeCanBeModified() (bool, error) {
 	return dao.AuthModeCanBeModified()
 }
@@ -0,0 +1,346 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/common/utils/registry"
+	"github.com/vmware/harbor/src/common/utils/registry/auth"
+	registry_error "github.com/vmware/harbor/src/common/utils/registry/error"
+	"github.com/vmware/harbor/src/ui/config"
+)
+
+// DriftReportAPI handles /api/policies/replication/:id/drift_reports and
+// /api/policies/replication/:id/drift_reports/:rid
+type DriftReportAPI struct {
+	api.BaseAPI
+	policy *models.RepPolicy
+}
+
+// Prepare validates that the user has system admin role and the policy exists
+func (d *DriftReportAPI) Prepare() {
+	uid := d.ValidateUser()
+	isAdmin, err := dao.IsAdminRole(uid)
+	if err != nil {
+		log.Errorf("failed to check whether the user %d is admin: %v", uid, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if !isAdmin {
+		d.CustomAbort(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+
+	id := d.GetIDFromURL()
+	policy, err := dao.GetRepPolicy(id)
+	if err != nil {
+		log.Errorf("failed to get policy %d: %v", id, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if policy == nil || policy.Deleted == 1 {
+		d.CustomAbort(http.StatusNotFound, fmt.Sprintf("policy %d not found", id))
+	}
+	d.policy = policy
+}
+
+// Post computes the drift between the project of the policy and its target and persists it
+func (d *DriftReportAPI) Post() {
+	project, err := dao.GetProjectByID(d.policy.ProjectID)
+	if err != nil {
+		log.Errorf("failed to get project %d: %v", d.policy.ProjectID, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if project == nil {
+		d.CustomAbort(http.StatusBadRequest, fmt.Sprintf("project %d does not exist", d.policy.ProjectID))
+	}
+
+	target, err := dao.GetRepTarget(d.policy.TargetID)
+	if err != nil {
+		log.Errorf("failed to get target %d: %v", d.policy.TargetID, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if target == nil {
+		d.CustomAbort(http.StatusBadRequest, fmt.Sprintf("target %d does not exist", d.policy.TargetID))
+	}
+
+	local, err := localImages(project.Name)
+	if err != nil {
+		log.Errorf("failed to list images of project %s: %v", project.Name, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	remote, err := remoteImages(target, project.Name)
+	if err != nil {
+		log.Errorf("failed to list images of project %s on target %s: %v", project.Name, target.URL, err)
+		d.CustomAbort(http.StatusInternalServerError, fmt.Sprintf("failed to list images on target: %v", err))
+	}
+
+	report := models.DriftReport{
+		PolicyID: d.policy.ID,
+		ItemList: diffImages(local, remote),
+	}
+	id, err := dao.AddDriftReport(report)
+	if err != nil {
+		log.Errorf("failed to add drift report for policy %d: %v", d.policy.ID, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	d.Redirect(http.StatusCreated, strconv.FormatInt(id, 10))
+}
+
+// List returns the drift reports of the policy without their items
+func (d *DriftReportAPI) List() {
+	page, pageSize := d.GetPaginationParams()
+	reports, total, err := dao.GetDriftReportsByPolicy(d.policy.ID, pageSize, pageSize*(page-1))
+	if err != nil {
+		log.Errorf("failed to get drift reports of policy %d: %v", d.policy.ID, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	d.SetPaginationHeader(total, page, pageSize)
+	d.Data["json"] = reports
+	d.ServeJSON()
+}
+
+// Get downloads a drift report, the format is specified by the
+// query parameter "format" whose value can be "json"(default) or "csv"
+func (d *DriftReportAPI) Get() {
+	rid, err := strconv.ParseInt(d.Ctx.Input.Param(":rid"), 10, 64)
+	if err != nil || rid <= 0 {
+		d.CustomAbort(http.StatusBadRequest, "invalid report ID")
+	}
+
+	report, err := dao.GetDriftReport(rid)
+	if err != nil {
+		log.Errorf("failed to get drift report %d: %v", rid, err)
+		d.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if report == nil || report.PolicyID != d.policy.ID {
+		d.CustomAbort(http.StatusNotFound, fmt.Sprintf("drift report %d not found", rid))
+	}
+
+	format := d.GetString("format")
+	switch format {
+	case "", "json":
+		d.Ctx.ResponseWriter.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=drift_report_%d.json", rid))
+		d.Data["json"] = report
+		d.ServeJSON()
+	case "csv":
+		d.Ctx.ResponseWriter.Header().Set(http.CanonicalHeaderKey("Content-Type"), "text/csv")
+		d.Ctx.ResponseWriter.Header().Set("Content-Disposition",
+			fmt.Sprintf("attachment; filename=drift_report_%d.csv", rid))
+		if err = writeDriftCSV(d.Ctx.ResponseWriter, report.ItemList); err != nil {
+			log.Errorf("failed to write drift report %d as csv: %v", rid, err)
+		}
+	default:
+		d.CustomAbort(http.StatusBadRequest, fmt.Sprintf("unsupported format %s", format))
+	}
+}
+
+func writeDriftCSV(w http.ResponseWriter, items []*models.DriftItem) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"repository", "tag", "type",
+		"local_digest", "remote_digest"}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := cw.Write([]string{item.Repository, item.Tag, item.Type,
+			item.LocalDigest, item.RemoteDigest}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// localImages returns the tag->digest maps of the repositories under the project in local registry
+func localImages(project string) (map[string]map[string]string, error) {
+	rc, err := initRegistryClient()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, err := config.RegistryURL()
+	if err != nil {
+		return nil, err
+	}
+
+	return listImages(rc, project, func(repository string) (*registry.Repository, error) {
+		return NewRepositoryClient(endpoint, true, "admin", repository,
+			"repository", repository, "pull")
+	})
+}
+
+// remoteImages returns the tag->digest maps of the repositories under the project on the target
+func remoteImages(target *models.RepTarget, project string) (map[string]map[string]string, error) {
+	verify, err := config.VerifyRemoteCert()
+	if err != nil {
+		return nil, err
+	}
+
+	password := target.Password
+	if len(password) != 0 {
+		key, err := config.SecretKey()
+		if err != nil {
+			return nil, err
+		}
+		password, err = utils.ReversibleDecrypt(password, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rc, err := newRegistryClient(target.URL, !verify, target.Username, password,
+		"registry", "catalog", "*")
+	if err != nil {
+		return nil, err
+	}
+
+	credential := auth.NewBasicAuthCredential(target.Username, password)
+	return listImages(rc, project, func(repository string) (*registry.Repository, error) {
+		authorizer := auth.NewStandardTokenAuthorizer(credential, !verify,
+			"", "repository", repository, "pull")
+		store, err := auth.NewAuthorizerStore(target.URL, !verify, authorizer)
+		if err != nil {
+			return nil, err
+		}
+		return registry.NewRepositoryWithModifiers(repository, target.URL, !verify, store)
+	})
+}
+
+func listImages(rc *registry.Registry, project string,
+	newRepoClient func(string) (*registry.Repository, error)) (map[string]map[string]string, error) {
+	repositories, err := rc.Catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	images := map[string]map[string]string{}
+	for _, repository := range repositories {
+		if !strings.HasPrefix(repository, project+"/") {
+			continue
+		}
+
+		client, err := newRepoClient(repository)
+		if err != nil {
+			return nil, err
+		}
+
+		tags, err := client.ListTag()
+		if err != nil {
+			if regErr, ok := err.(*registry_error.Error); ok && regErr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, err
+		}
+
+		digests := map[string]string{}
+		for _, tag := range tags {
+			digest, exist, err := client.ManifestExist(tag)
+			if err != nil {
+				return nil, err
+			}
+			if !exist {
+				continue
+			}
+			digests[tag] = digest
+		}
+		images[repository] = digests
+	}
+	return images, nil
+}
+
+// diffImages compares the tag->digest maps of local and remote repositories,
+// the items are sorted by repository and tag
+func diffImages(local, remote map[string]map[string]string) []*models.DriftItem {
+	items := []*models.DriftItem{}
+
+	for repository, localTags := range local {
+		remoteTags, ok := remote[repository]
+		if !ok {
+			items = append(items, &models.DriftItem{
+				Repository: repository,
+				Type:       models.DriftMissingRepository,
+			})
+			continue
+		}
+		for tag, localDigest := range localTags {
+			remoteDigest, ok := remoteTags[tag]
+			if !ok {
+				items = append(items, &models.DriftItem{
+					Repository:  repository,
+					Tag:         tag,
+					Type:        models.DriftMissingTag,
+					LocalDigest: localDigest,
+				})
+				continue
+			}
+			if remoteDigest != localDigest {
+				items = append(items, &models.DriftItem{
+					Repository:   repository,
+					Tag:          tag,
+					Type:         models.DriftDigestMismatch,
+					LocalDigest:  localDigest,
+					RemoteDigest: remoteDigest,
+				})
+			}
+		}
+	}
+
+	for repository, remoteTags := range remote {
+		localTags := local[repository]
+		for tag, remoteDigest := range remoteTags {
+			if _, ok := localTags[tag]; ok {
+				continue
+			}
+			items = append(items, &models.DriftItem{
+				Repository:   repository,
+				Tag:          tag,
+				Type:         models.DriftExtraTag,
+				RemoteDigest: remoteDigest,
+			})
+		}
+	}
+
+	sort.Sort(driftItems(items))
+	return items
+}
+
+// driftItems sorts the drift items by repository and tag
+type driftItems []*models.DriftItem
+
+func (d driftItems) Len() int {
+	return len(d)
+}
+
+func (d driftItems) Less(i, j int) bool {
+	if d[i].Repository != d[j].Repository {
+		return d[i].Repository < d[j].Repository
+	}
+	return d[i].Tag < d[j].Tag
+}
+
+func (d driftItems) Swap(i, j int) {
+	d[i], d[j] = d[j], d[i]
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestDiffImages(t *testing.T) {
+	local := map[string]map[string]string{
+		"library/busybox": map[string]string{
+			"latest": "sha256:1",
+			"1.0":    "sha256:2",
+		},
+		"library/ubuntu": map[string]string{
+			"14.04": "sha256:3",
+		},
+	}
+	remote := map[string]map[string]string{
+		"library/busybox": map[string]string{
+			"latest": "sha256:4",
+			"2.0":    "sha256:5",
+		},
+	}
+
+	items := diffImages(local, remote)
+	assert.Equal(t, []*models.DriftItem{
+		&models.DriftItem{
+			Repository:  "library/busybox",
+			Tag:         "1.0",
+			Type:        models.DriftMissingTag,
+			LocalDigest: "sha256:2",
+		},
+		&models.DriftItem{
+			Repository:   "library/busybox",
+			Tag:          "2.0",
+			Type:         models.DriftExtraTag,
+			RemoteDigest: "sha256:5",
+		},
+		&models.DriftItem{
+			Repository:   "library/busybox",
+			Tag:          "latest",
+			Type:         models.DriftDigestMismatch,
+			LocalDigest:  "sha256:1",
+			RemoteDigest: "sha256:4",
+		},
+		&models.DriftItem{
+			Repository: "library/ubuntu",
+			Type:       models.DriftMissingRepository,
+		},
+	}, items)
+
+	assert.Equal(t, 0, len(diffImages(remote, remote)))
+}
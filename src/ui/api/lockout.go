@@ -0,0 +1,125 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
+)
+
+// LockoutAPI handles requests to /api/lockouts and /api/lockout_audits, it
+// lists and unlocks the accounts locked due to login failures
+type LockoutAPI struct {
+	api.BaseAPI
+	currentUserID int
+}
+
+// Prepare validates that the user has system admin role
+func (l *LockoutAPI) Prepare() {
+	l.currentUserID = l.ValidateUser()
+	isAdmin, err := dao.IsAdminRole(l.currentUserID)
+	if err != nil {
+		log.Errorf("failed to check whether the user %d is admin: %v", l.currentUserID, err)
+		l.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if !isAdmin {
+		l.CustomAbort(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+}
+
+// List returns the accounts which are locked currently
+func (l *LockoutAPI) List() {
+	lockouts, err := dao.ListLockedAccounts()
+	if err != nil {
+		log.Errorf("failed to list locked accounts: %v", err)
+		l.CustomAbort(http.StatusInternalServerError, "")
+	}
+	l.Data["json"] = lockouts
+	l.ServeJSON()
+}
+
+// Delete unlocks the account of the username
+func (l *LockoutAPI) Delete() {
+	username := l.Ctx.Input.Param(":username")
+
+	operator, err := dao.GetUser(models.User{UserID: l.currentUserID})
+	if err != nil || operator == nil {
+		log.Errorf("failed to get user %d: %v", l.currentUserID, err)
+		l.CustomAbort(http.StatusInternalServerError, "")
+	}
+
+	unlocked, err := auth.Unlock(username, operator.Username)
+	if err != nil {
+		log.Errorf("failed to unlock %s: %v", username, err)
+		l.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if !unlocked {
+		l.CustomAbort(http.StatusNotFound, "the account is not locked")
+	}
+}
+
+// ListAudits filters the lockout records according to the username,
+// operation and time range
+func (l *LockoutAPI) ListAudits() {
+	username := l.GetString("username")
+	operation := l.GetString("operation")
+	if len(operation) != 0 && operation != models.LockoutOpLock &&
+		operation != models.LockoutOpUnlock {
+		l.CustomAbort(http.StatusBadRequest, "invalid operation")
+	}
+
+	var startTime *time.Time
+	startTimeStr := l.GetString("start_time")
+	if len(startTimeStr) != 0 {
+		i, err := strconv.ParseInt(startTimeStr, 10, 64)
+		if err != nil {
+			l.CustomAbort(http.StatusBadRequest, "invalid start_time")
+		}
+		st := time.Unix(i, 0)
+		startTime = &st
+	}
+
+	var endTime *time.Time
+	endTimeStr := l.GetString("end_time")
+	if len(endTimeStr) != 0 {
+		i, err := strconv.ParseInt(endTimeStr, 10, 64)
+		if err != nil {
+			l.CustomAbort(http.StatusBadRequest, "invalid end_time")
+		}
+		et := time.Unix(i, 0)
+		endTime = &et
+	}
+
+	page, pageSize := l.GetPaginationParams()
+
+	audits, total, err := dao.FilterLockoutAudits(username, operation, startTime, endTime,
+		pageSize, pageSize*(page-1))
+	if err != nil {
+		log.Errorf("failed to filter lockout audits according to username %s, operation %s, start time %v, end time %v: %v",
+			username, operation, startTime, endTime, err)
+		l.CustomAbort(http.StatusInternalServerError, "")
+	}
+
+	l.SetPaginationHeader(total, page, pageSize)
+	l.Data["json"] = audits
+	l.ServeJSON()
+}
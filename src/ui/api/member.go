@@ -23,6 +23,7 @@ import (
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/service/token"
 )
 
 // ProjectMemberAPI handles request to /api/projects/{}/members/{}
@@ -165,6 +166,7 @@ func (pma *ProjectMemberAPI) Post() {
 		pma.RenderError(http.StatusInternalServerError, "Failed to update data in database")
 		return
 	}
+	token.InvalidateProjectPermissions(pma.project.Name)
 }
 
 // Put ...
@@ -187,6 +189,7 @@ func (pma *ProjectMemberAPI) Put() {
 		pma.RenderError(http.StatusNotFound, "user not exist in project")
 		return
 	}
+	defer token.InvalidateProjectPermissions(pma.project.Name)
 	//TODO: delete and insert should in one transaction
 	//delete user project role record for the given user
 	err = dao.DeleteProjectMember(pid, mid)
@@ -224,4 +227,5 @@ func (pma *ProjectMemberAPI) Delete() {
 		pma.RenderError(http.StatusInternalServerError, "Failed to update data in DB")
 		return
 	}
+	token.InvalidateProjectPermissions(pma.project.Name)
 }
@@ -22,10 +22,13 @@ import (
 	"github.com/vmware/harbor/src/common/api"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/ui/config"
+	"github.com/vmware/harbor/src/ui/service/token"
 
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -44,8 +47,11 @@ type projectReq struct {
 
 const projectNameMaxLen int = 30
 const projectNameMinLen int = 2
+
+// the max length of the full name of a nested project, e.g. org/team
+const projectFullNameMaxLen int = 200
 const restrictedNameChars = `[a-z0-9]+(?:[._-][a-z0-9]+)*`
-const dupProjectPattern = `Duplicate entry '\w+' for key 'name'`
+const dupProjectPattern = `Duplicate entry '[\w./-]+' for key 'name'`
 
 // Prepare validates the URL and the user
 func (p *ProjectAPI) Prepare() {
@@ -106,6 +112,25 @@ func (p *ProjectAPI) Post() {
 		p.RenderError(http.StatusConflict, "")
 		return
 	}
+
+	// a nested project can only be created under an existing project by
+	// the admin of it, as the repositories under the name move to it
+	if parents := utils.ParentProjects(projectName); len(parents) > 0 {
+		parent, err := dao.GetProjectByName(parents[0])
+		if err != nil {
+			log.Errorf("failed to get project %s: %v", parents[0], err)
+			p.CustomAbort(http.StatusInternalServerError, "Internal error.")
+		}
+		if parent == nil {
+			p.RenderError(http.StatusBadRequest, fmt.Sprintf("parent project %s does not exist", parents[0]))
+			return
+		}
+		if !isProjectAdmin(p.userID, parent.ProjectID) {
+			p.RenderError(http.StatusForbidden, fmt.Sprintf("only the admin of project %s can create projects under it", parent.Name))
+			return
+		}
+	}
+
 	project := models.Project{OwnerID: p.userID, Name: projectName, CreationTime: time.Now(), Public: public}
 	projectID, err := dao.AddProject(project)
 	if err != nil {
@@ -118,6 +143,7 @@ func (p *ProjectAPI) Post() {
 		}
 		return
 	}
+	token.InvalidateProjectPermissions(projectName)
 	p.Redirect(http.StatusCreated, strconv.FormatInt(projectID, 10))
 }
 
@@ -176,6 +202,15 @@ func (p *ProjectAPI) Delete() {
 		p.CustomAbort(http.StatusForbidden, "")
 	}
 
+	children, err := dao.GetChildProjects(p.projectName)
+	if err != nil {
+		log.Errorf("failed to get child projects of %s: %v", p.projectName, err)
+		p.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if len(children) > 0 {
+		p.CustomAbort(http.StatusPreconditionFailed, "project contains child projects, can not be deleted")
+	}
+
 	contains, err := projectContainsRepo(p.projectName)
 	if err != nil {
 		log.Errorf("failed to check whether project %s contains any repository: %v", p.projectName, err)
@@ -198,6 +233,14 @@ func (p *ProjectAPI) Delete() {
 		log.Errorf("failed to delete project %d: %v", p.projectID, err)
 		p.CustomAbort(http.StatusInternalServerError, "")
 	}
+	token.InvalidateProjectPermissions(p.projectName)
+	robots, err := dao.RevokeRobotsByProject(p.projectID)
+	if err != nil {
+		log.Errorf("failed to revoke robot accounts of project %d: %v", p.projectID, err)
+	}
+	for _, rb := range robots {
+		token.InvalidateUserPermissions(rb.Name)
+	}
 
 	go func() {
 		if err := dao.AddAccessLog(models.AccessLog{
@@ -296,7 +339,7 @@ func (p *ProjectAPI) List() {
 
 	for i := 0; i < len(projectList); i++ {
 		if public != 1 {
-			roles, err := dao.GetUserProjectRoles(p.userID, projectList[i].ProjectID)
+			roles, err := dao.GetUserProjectRolesWithInheritance(p.userID, projectList[i].ProjectID)
 			if err != nil {
 				log.Errorf("failed to get user's project role: %v", err)
 				p.CustomAbort(http.StatusInternalServerError, "")
@@ -348,7 +391,9 @@ func (p *ProjectAPI) ToggleProjectPublic() {
 	if err != nil {
 		log.Errorf("Error while updating project, project id: %d, error: %v", projectID, err)
 		p.RenderError(http.StatusInternalServerError, "Failed to update project")
+		return
 	}
+	token.InvalidateProjectPermissions(p.projectName)
 }
 
 // FilterAccessLog handles GET to /api/projects/{}/logs
@@ -399,9 +444,9 @@ func isProjectAdmin(userID int, pid int64) bool {
 		return true
 	}
 
-	rolelist, err := dao.GetUserProjectRoles(userID, pid)
+	rolelist, err := dao.GetUserProjectRolesWithInheritance(userID, pid)
 	if err != nil {
-		log.Errorf("Error occurred in GetUserProjectRoles, returning false, error: %v", err)
+		log.Errorf("Error occurred in GetUserProjectRolesWithInheritance, returning false, error: %v", err)
 		return false
 	}
 
@@ -416,15 +461,21 @@ func isProjectAdmin(userID int, pid int64) bool {
 	return hasProjectAdminRole
 }
 
+// validateProjectReq validates the name of project, the name of a nested
+// project consists of segments separated by "/", e.g. org/team
 func validateProjectReq(req projectReq) error {
-	pn := req.ProjectName
-	if isIllegalLength(req.ProjectName, projectNameMinLen, projectNameMaxLen) {
-		return fmt.Errorf("Project name is illegal in length. (greater than 2 or less than 30)")
+	if len(req.ProjectName) > projectFullNameMaxLen {
+		return fmt.Errorf("Project name is illegal in length. (less than %d)", projectFullNameMaxLen)
 	}
 	validProjectName := regexp.MustCompile(`^` + restrictedNameChars + `$`)
-	legal := validProjectName.MatchString(pn)
-	if !legal {
-		return fmt.Errorf("project name is not in lower case or contains illegal characters")
+	for _, pn := range strings.Split(req.ProjectName, "/") {
+		if isIllegalLength(pn, projectNameMinLen, projectNameMaxLen) {
+			return fmt.Errorf("Project name is illegal in length. (greater than 2 or less than 30)")
+		}
+		legal := validProjectName.MatchString(pn)
+		if !legal {
+			return fmt.Errorf("project name is not in lower case or contains illegal characters")
+		}
 	}
 	return nil
 }
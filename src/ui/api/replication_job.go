@@ -139,6 +139,36 @@ func (ra *RepJobAPI) Delete() {
 		log.Errorf("failed to deleted job %d: %v", ra.jobID, err)
 		ra.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
 	}
+
+	if err = dao.DeleteJobEvents(models.JobKindReplication, ra.jobID); err != nil {
+		log.Warningf("failed to delete events of job %d: %v", ra.jobID, err)
+	}
+}
+
+// GetEvents returns the state transitions of the job in the order they happened
+func (ra *RepJobAPI) GetEvents() {
+	if ra.jobID == 0 {
+		ra.CustomAbort(http.StatusBadRequest, "id is nil")
+	}
+
+	job, err := dao.GetRepJob(ra.jobID)
+	if err != nil {
+		log.Errorf("failed to get job %d: %v", ra.jobID, err)
+		ra.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	if job == nil {
+		ra.CustomAbort(http.StatusNotFound, fmt.Sprintf("job %d not found", ra.jobID))
+	}
+
+	events, err := dao.GetJobEvents(models.JobKindReplication, ra.jobID)
+	if err != nil {
+		log.Errorf("failed to get events of job %d: %v", ra.jobID, err)
+		ra.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	ra.Data["json"] = events
+	ra.ServeJSON()
 }
 
 // GetLog ...
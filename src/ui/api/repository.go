@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,16 +29,21 @@ import (
 	"github.com/vmware/harbor/src/common/api"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
-	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/common/utils/notary"
 	"github.com/vmware/harbor/src/common/utils/registry"
 	"github.com/vmware/harbor/src/common/utils/registry/auth"
 	registry_error "github.com/vmware/harbor/src/common/utils/registry/error"
+	"github.com/vmware/harbor/src/ui/auth/robot"
 	"github.com/vmware/harbor/src/ui/config"
 	svc_utils "github.com/vmware/harbor/src/ui/service/utils"
 )
 
+const (
+	defaultTagPageSize = 100
+	maxTagPageSize     = 500
+)
+
 // RepositoryAPI handles request to /api/repositories /api/repositories/tags /api/repositories/manifests, the parm has to be put
 // in the query string as the web framework can not parse the URL if it contains veriadic sectors.
 type RepositoryAPI struct {
@@ -84,18 +90,9 @@ func (ra *RepositoryAPI) Get() {
 		ra.CustomAbort(http.StatusNotFound, fmt.Sprintf("project %d not found", projectID))
 	}
 
-	if project.Public == 0 {
-		var userID int
-
-		if svc_utils.VerifySecret(ra.Ctx.Request, config.JobserviceSecret()) {
-			userID = 1
-		} else {
-			userID = ra.ValidateUser()
-		}
-
-		if !checkProjectPermission(userID, projectID) {
-			ra.CustomAbort(http.StatusForbidden, "")
-		}
+	if project.Public == 0 &&
+		!svc_utils.VerifySecret(ra.Ctx.Request, config.JobserviceSecret()) {
+		ra.checkPullPermission(projectID)
 	}
 
 	keyword := ra.GetString("q")
@@ -171,7 +168,7 @@ func (ra *RepositoryAPI) Delete() {
 	// using :splat to get * part in path
 	repoName := ra.GetString(":splat")
 
-	projectName, _ := utils.ParseRepository(repoName)
+	projectName, _ := dao.ParseRepository(repoName)
 	project, err := dao.GetProjectByName(projectName)
 	if err != nil {
 		log.Errorf("failed to get project %s: %v", projectName, err)
@@ -298,7 +295,7 @@ func (ra *RepositoryAPI) GetTags() {
 	repoName := ra.GetString(":splat")
 	detail := ra.GetString("detail") == "1" || ra.GetString("detail") == "true"
 
-	projectName, _ := utils.ParseRepository(repoName)
+	projectName, _ := dao.ParseRepository(repoName)
 	project, err := dao.GetProjectByName(projectName)
 	if err != nil {
 		log.Errorf("failed to get project %s: %v", projectName, err)
@@ -310,10 +307,7 @@ func (ra *RepositoryAPI) GetTags() {
 	}
 
 	if project.Public == 0 {
-		userID := ra.ValidateUser()
-		if !checkProjectPermission(userID, project.ProjectID) {
-			ra.CustomAbort(http.StatusForbidden, "")
-		}
+		ra.checkPullPermission(project.ProjectID)
 	}
 
 	client, err := ra.initRepositoryClient(repoName)
@@ -321,7 +315,27 @@ func (ra *RepositoryAPI) GetTags() {
 		log.Errorf("error occurred while initializing repository client for %s: %v", repoName, err)
 		ra.CustomAbort(http.StatusInternalServerError, "internal error")
 	}
-	tags, err := listTag(client)
+	var tags []string
+	// the tags are paginated if page_size or last is specified, the link of next
+	// page is returned in the Link header. Different from the page/page_size and
+	// X-Total-Count used by other APIs, the pages are located by the last tag as
+	// the registry lists tags by cursor and the total can not be got without
+	// listing all tags.
+	pageSize, last := ra.getTagPaginationParams()
+	if pageSize > 0 {
+		var next string
+		tags, next, err = listTagWithPagination(client, pageSize, last)
+		if err == nil && len(next) > 0 {
+			u := *(ra.Ctx.Request.URL)
+			q := u.Query()
+			q.Set("page_size", strconv.Itoa(pageSize))
+			q.Set("last", next)
+			u.RawQuery = q.Encode()
+			ra.Ctx.ResponseWriter.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", u.String()))
+		}
+	} else {
+		tags, err = listTag(client)
+	}
 	if err != nil {
 		regErr, ok := err.(*registry_error.Error)
 		if !ok {
@@ -386,6 +400,45 @@ func listTag(client *registry.Repository) ([]string, error) {
 	return tags, nil
 }
 
+// getTagPaginationParams returns the page size and the last tag of previous
+// page, the page size is 0 if neither of them is specified
+func (ra *RepositoryAPI) getTagPaginationParams() (int, string) {
+	last := ra.GetString("last")
+	if len(ra.GetString("page_size")) == 0 {
+		if len(last) == 0 {
+			return 0, ""
+		}
+		return defaultTagPageSize, last
+	}
+
+	pageSize, err := ra.GetInt("page_size")
+	if err != nil || pageSize <= 0 {
+		ra.CustomAbort(http.StatusBadRequest, "invalid page_size")
+	}
+	if pageSize > maxTagPageSize {
+		log.Debugf("the parameter page_size %d exceeds the max %d, set it to max", pageSize, maxTagPageSize)
+		pageSize = maxTagPageSize
+	}
+	return pageSize, last
+}
+
+// listTagWithPagination lists at most n tags after last, the last tag of
+// the page is returned if there are more tags
+func listTagWithPagination(client *registry.Repository, n int, last string) ([]string, string, error) {
+	tags, next, err := client.ListTagWithPagination(n, last)
+	if err != nil {
+		// the same workaround as listTag
+		if regErr, ok := err.(*registry_error.Error); ok &&
+			regErr.StatusCode == http.StatusNotFound {
+			return []string{}, "", nil
+		}
+
+		return nil, "", err
+	}
+
+	return tags, next, nil
+}
+
 // GetManifests returns the manifest of a tag
 func (ra *RepositoryAPI) GetManifests() {
 	repoName := ra.GetString(":splat")
@@ -400,7 +453,7 @@ func (ra *RepositoryAPI) GetManifests() {
 		ra.CustomAbort(http.StatusBadRequest, "version should be v1 or v2")
 	}
 
-	projectName, _ := utils.ParseRepository(repoName)
+	projectName, _ := dao.ParseRepository(repoName)
 	project, err := dao.GetProjectByName(projectName)
 	if err != nil {
 		log.Errorf("failed to get project %s: %v", projectName, err)
@@ -412,10 +465,7 @@ func (ra *RepositoryAPI) GetManifests() {
 	}
 
 	if project.Public == 0 {
-		userID := ra.ValidateUser()
-		if !checkProjectPermission(userID, project.ProjectID) {
-			ra.CustomAbort(http.StatusForbidden, "")
-		}
+		ra.checkPullPermission(project.ProjectID)
 	}
 
 	rc, err := ra.initRepositoryClient(repoName)
@@ -480,6 +530,21 @@ func getManifest(client *registry.Repository,
 	return result, nil
 }
 
+// checkPullPermission aborts the request if the requester, which is either a
+// user or a robot account, can not pull the repositories of the project
+func (ra *RepositoryAPI) checkPullPermission(projectID int64) {
+	if r := ra.Robot(); r != nil {
+		if len(robot.Permission(r, projectID)) == 0 {
+			ra.CustomAbort(http.StatusForbidden, "")
+		}
+		return
+	}
+	userID := ra.ValidateUser()
+	if !checkProjectPermission(userID, projectID) {
+		ra.CustomAbort(http.StatusForbidden, "")
+	}
+}
+
 func (ra *RepositoryAPI) initRepositoryClient(repoName string) (r *registry.Repository, err error) {
 	endpoint, err := config.RegistryURL()
 	if err != nil {
@@ -506,35 +571,27 @@ func (ra *RepositoryAPI) initRepositoryClient(repoName string) (r *registry.Repo
 		"repository", repoName, "pull", "push", "*")
 }
 
+// getUsername returns the name of the requester, which is the name of the
+// robot account if the request is sent by one. It is resolved from the
+// authenticated user rather than the session, as the requests authenticated
+// by the basic auth or the proxy headers do not set the session.
 func (ra *RepositoryAPI) getUsername() (string, error) {
-	// get username from session
-	sessionUsername := ra.GetSession("username")
-	if sessionUsername != nil {
-		username, ok := sessionUsername.(string)
-		if ok {
-			return username, nil
-		}
+	if r := ra.Robot(); r != nil {
+		return r.Name, nil
 	}
 
-	// if username does not exist in session, try to get userId from sessiion
-	// and then get username from DB according to the userId
-	sessionUserID := ra.GetSession("userId")
-	if sessionUserID != nil {
-		userID, ok := sessionUserID.(int)
-		if ok {
-			u := models.User{
-				UserID: userID,
-			}
-			user, err := dao.GetUser(u)
-			if err != nil {
-				return "", err
-			}
-
-			return user.Username, nil
-		}
+	userID, _, ok := ra.GetUserIDForRequest()
+	if !ok {
+		return "", nil
 	}
-
-	return "", nil
+	user, err := dao.GetUser(models.User{UserID: userID})
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", nil
+	}
+	return user.Username, nil
 }
 
 //GetTopRepos returns the most populor repositories
@@ -583,8 +640,6 @@ func (ra *RepositoryAPI) GetTopRepos() {
 
 //GetSignatures returns signatures of a repository
 func (ra *RepositoryAPI) GetSignatures() {
-	//use this func to init session.
-	ra.GetUserIDForRequest()
 	username, err := ra.getUsername()
 	if err != nil {
 		log.Warningf("Error when getting username: %v", err)
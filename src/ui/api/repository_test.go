@@ -14,11 +14,21 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"github.com/astaxie/beego"
+	"github.com/docker/distribution/manifest/schema2"
 	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/common/utils/test"
+	"github.com/vmware/harbor/src/ui/config"
 )
 
 func TestGetRepos(t *testing.T) {
@@ -236,3 +246,65 @@ func TestGetReposTop(t *testing.T) {
 
 	fmt.Printf("\n")
 }
+
+// TestGetTagsWithFakeRegistry lists the tags in pages from a fake registry
+// which doesn't support pagination
+func TestGetTagsWithFakeRegistry(t *testing.T) {
+	fake := test.NewRegistry(&test.RegistryOptions{
+		DisablePagination: true,
+	})
+	defer fake.Close()
+
+	repository := "library/fake-registry"
+	for _, tag := range []string{"t5", "t4", "t3", "t2", "t1"} {
+		layer := fake.AddBlob(repository, []byte("layer of "+tag))
+		fake.AddManifest(repository, tag, schema2.MediaTypeManifest,
+			[]byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":"%s","layers":[{"digest":"%s"}]}`,
+				schema2.MediaTypeManifest, layer)))
+	}
+
+	endpoint, err := config.RegistryURL()
+	if err != nil {
+		t.Fatalf("failed to get registry URL: %v", err)
+	}
+	if err = config.Upload(map[string]interface{}{
+		common.RegistryURL: fake.URL,
+	}); err != nil {
+		t.Fatalf("failed to update registry URL: %v", err)
+	}
+	defer config.Upload(map[string]interface{}{
+		common.RegistryURL: endpoint,
+	})
+
+	getTags := func(url string) ([]string, string) {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.SetBasicAuth(admin.Name, admin.Passwd)
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("unexpected status code of GET %s: %d", url, w.Code)
+		}
+		tags := []string{}
+		if err = json.Unmarshal(w.Body.Bytes(), &tags); err != nil {
+			t.Fatalf("failed to decode tags: %v", err)
+		}
+		return tags, utils.ParseLink(w.Header().Get("Link")).Next()
+	}
+
+	tags, _ := getTags(fmt.Sprintf("/api/repositories/%s/tags", repository))
+	assert.Equal(t, "t1,t2,t3,t4,t5", strings.Join(tags, ","), "unexpected tags")
+
+	pages := []string{}
+	next := fmt.Sprintf("/api/repositories/%s/tags?page_size=2", repository)
+	for len(next) > 0 {
+		if len(pages) > 5 {
+			t.Fatalf("too many pages: %v", pages)
+		}
+		tags, next = getTags(next)
+		pages = append(pages, strings.Join(tags, ","))
+	}
+	assert.Equal(t, "t1,t2|t3,t4|t5", strings.Join(pages, "|"), "unexpected pages")
+}
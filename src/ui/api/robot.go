@@ -0,0 +1,153 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth/robot"
+	"github.com/vmware/harbor/src/ui/service/token"
+)
+
+// RobotAPI handles requests to /api/projects/{}/robots/{}, it manages the
+// robot accounts of the project
+type RobotAPI struct {
+	api.BaseAPI
+	currentUserID int
+	project       *models.Project
+	robot         *models.Robot
+}
+
+type robotReq struct {
+	Description string `json:"description"`
+	Access      string `json:"access"`
+	ExpiresAt   int64  `json:"expires_at"`
+}
+
+// robotResp is returned on creation, it is the only time the secret is shown
+type robotResp struct {
+	*models.Robot
+	Secret string `json:"secret"`
+}
+
+// Prepare validates the project and the robot account in the URL, the user
+// must be a member of the project
+func (r *RobotAPI) Prepare() {
+	r.currentUserID = r.ValidateUser()
+
+	pid, err := strconv.ParseInt(r.Ctx.Input.Param(":pid"), 10, 64)
+	if err != nil {
+		r.CustomAbort(http.StatusBadRequest, "invalid project ID")
+	}
+	p, err := dao.GetProjectByID(pid)
+	if err != nil {
+		log.Errorf("failed to get project %d: %v", pid, err)
+		r.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if p == nil {
+		r.CustomAbort(http.StatusNotFound, "project does not exist")
+	}
+	if !checkProjectPermission(r.currentUserID, p.ProjectID) {
+		r.CustomAbort(http.StatusForbidden, "")
+	}
+	r.project = p
+
+	if len(r.Ctx.Input.Param(":id")) == 0 {
+		return
+	}
+	id, err := strconv.ParseInt(r.Ctx.Input.Param(":id"), 10, 64)
+	if err != nil {
+		r.CustomAbort(http.StatusBadRequest, "invalid robot account ID")
+	}
+	rb, err := dao.GetRobot(id)
+	if err != nil {
+		log.Errorf("failed to get robot account %d: %v", id, err)
+		r.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if rb == nil || rb.ProjectID != p.ProjectID {
+		r.CustomAbort(http.StatusNotFound, "robot account does not exist")
+	}
+	r.robot = rb
+}
+
+// Get returns the robot account if the ID is specified, otherwise it lists
+// the robot accounts of the project
+func (r *RobotAPI) Get() {
+	if r.robot != nil {
+		r.Data["json"] = r.robot
+		r.ServeJSON()
+		return
+	}
+
+	robots, err := dao.ListRobots(r.project.ProjectID)
+	if err != nil {
+		log.Errorf("failed to list robot accounts of project %d: %v", r.project.ProjectID, err)
+		r.CustomAbort(http.StatusInternalServerError, "")
+	}
+	r.Data["json"] = robots
+	r.ServeJSON()
+}
+
+// Post creates a robot account, the generated name and secret are returned
+func (r *RobotAPI) Post() {
+	if !hasProjectAdminRole(r.currentUserID, r.project.ProjectID) {
+		r.CustomAbort(http.StatusForbidden, "")
+	}
+
+	req := &robotReq{}
+	r.DecodeJSONReq(req)
+	if len(req.Access) == 0 {
+		req.Access = models.RobotAccessPull
+	}
+
+	rb, secret, err := robot.Create(r.project, r.currentUserID, req.Description, req.Access, req.ExpiresAt)
+	if err != nil {
+		if err == robot.ErrInvalidAccess || err == robot.ErrInvalidExpiration {
+			r.CustomAbort(http.StatusBadRequest, err.Error())
+		}
+		log.Errorf("failed to create robot account of project %d: %v", r.project.ProjectID, err)
+		r.CustomAbort(http.StatusInternalServerError, "")
+	}
+	log.Infof("robot account %s is created by user %d", rb.Name, r.currentUserID)
+
+	r.Ctx.Output.SetStatus(http.StatusCreated)
+	r.Data["json"] = &robotResp{
+		Robot:  rb,
+		Secret: secret,
+	}
+	r.ServeJSON()
+}
+
+// Delete revokes the robot account
+func (r *RobotAPI) Delete() {
+	if !hasProjectAdminRole(r.currentUserID, r.project.ProjectID) {
+		r.CustomAbort(http.StatusForbidden, "")
+	}
+	if r.robot == nil {
+		r.CustomAbort(http.StatusBadRequest, "robot account ID is required")
+	}
+
+	if err := dao.RevokeRobot(r.robot.ID); err != nil {
+		log.Errorf("failed to revoke robot account %d: %v", r.robot.ID, err)
+		r.CustomAbort(http.StatusInternalServerError, "")
+	}
+	token.InvalidateUserPermissions(r.robot.Name)
+	log.Infof("robot account %s is revoked by user %d", r.robot.Name, r.currentUserID)
+}
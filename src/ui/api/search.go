@@ -22,7 +22,6 @@ import (
 	"github.com/vmware/harbor/src/common/api"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
-	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/ui/config"
 )
@@ -77,7 +76,7 @@ func (s *SearchAPI) Get() {
 		}
 
 		if userID != dao.NonExistUserID {
-			roles, err := dao.GetUserProjectRoles(userID, p.ProjectID)
+			roles, err := dao.GetUserProjectRolesWithInheritance(userID, p.ProjectID)
 			if err != nil {
 				log.Errorf("failed to get user's project role: %v", err)
 				s.CustomAbort(http.StatusInternalServerError, "")
@@ -121,40 +120,38 @@ func filterRepositories(projects []models.Project, keyword string) (
 		return nil, err
 	}
 
-	i, j := 0, 0
-	result := []map[string]interface{}{}
-	for i < len(repositories) && j < len(projects) {
-		r := repositories[i]
-		p, _ := utils.ParseRepository(r.Name)
+	// the project a repository belongs to is the longest matching prefix of
+	// its name, which was resolved when the repository was recorded
+	accessible := map[int64]models.Project{}
+	for _, p := range projects {
+		accessible[p.ProjectID] = p
+	}
 
-		d := strings.Compare(p, projects[j].Name)
-		if d < 0 {
-			i++
+	result := []map[string]interface{}{}
+	for _, r := range repositories {
+		p, ok := accessible[r.ProjectID]
+		if !ok {
+			continue
+		}
+		if len(keyword) != 0 && !strings.Contains(r.Name, keyword) {
 			continue
-		} else if d == 0 {
-			i++
-			if len(keyword) != 0 && !strings.Contains(r.Name, keyword) {
-				continue
-			}
-			entry := make(map[string]interface{})
-			entry["repository_name"] = r.Name
-			entry["project_name"] = projects[j].Name
-			entry["project_id"] = projects[j].ProjectID
-			entry["project_public"] = projects[j].Public
-			entry["pull_count"] = r.PullCount
-			entry["description"] = r.Description
-
-			tags, err := getTags(r.Name)
-			if err != nil {
-				log.Errorf("failed to get tags of %s", r.Name)
-				return nil, err
-			}
-			entry["tags_count"] = len(tags)
-
-			result = append(result, entry)
-		} else {
-			j++
 		}
+		entry := make(map[string]interface{})
+		entry["repository_name"] = r.Name
+		entry["project_name"] = p.Name
+		entry["project_id"] = p.ProjectID
+		entry["project_public"] = p.Public
+		entry["pull_count"] = r.PullCount
+		entry["description"] = r.Description
+
+		tags, err := getTags(r.Name)
+		if err != nil {
+			log.Errorf("failed to get tags of %s", r.Name)
+			return nil, err
+		}
+		entry["tags_count"] = len(tags)
+
+		result = append(result, entry)
 	}
 	return result, nil
 }
@@ -24,7 +24,9 @@ import (
 	"github.com/vmware/harbor/src/common/api"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/common/utils/registry"
 	"github.com/vmware/harbor/src/ui/config"
+	"github.com/vmware/harbor/src/ui/service/token"
 )
 
 //SystemInfoAPI handle requests for getting system info /api/systeminfo
@@ -155,3 +157,27 @@ func (sia *SystemInfoAPI) getVersion() string {
 	}
 	return string(version[:])
 }
+
+// GetRegistryClientMetrics returns the latency and status counters of the
+// requests sent to registries by the UI, keyed by request method
+func (sia *SystemInfoAPI) GetRegistryClientMetrics() {
+	sia.validate()
+	if !sia.isAdmin {
+		sia.RenderError(http.StatusForbidden, "User does not have admin role.")
+		return
+	}
+	sia.Data["json"] = registry.DefaultMetrics.Snapshot()
+	sia.ServeJSON()
+}
+
+// GetPermissionCacheMetrics returns the hit/miss counters of the cache of
+// project permissions used by the token service
+func (sia *SystemInfoAPI) GetPermissionCacheMetrics() {
+	sia.validate()
+	if !sia.isAdmin {
+		sia.RenderError(http.StatusForbidden, "User does not have admin role.")
+		return
+	}
+	sia.Data["json"] = token.GetPermissionCacheMetrics()
+	sia.ServeJSON()
+}
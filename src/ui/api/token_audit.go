@@ -0,0 +1,86 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+// TokenAuditAPI handles requests to /api/token_audits, it lists the access
+// denied or reduced by the token service
+type TokenAuditAPI struct {
+	api.BaseAPI
+}
+
+// Prepare validates that the user has system admin role
+func (t *TokenAuditAPI) Prepare() {
+	uid := t.ValidateUser()
+	isAdmin, err := dao.IsAdminRole(uid)
+	if err != nil {
+		log.Errorf("failed to check whether the user %d is admin: %v", uid, err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if !isAdmin {
+		t.CustomAbort(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+}
+
+// List filters the records according to the username, service and time range
+func (t *TokenAuditAPI) List() {
+	username := t.GetString("username")
+	service := t.GetString("service")
+
+	var startTime *time.Time
+	startTimeStr := t.GetString("start_time")
+	if len(startTimeStr) != 0 {
+		i, err := strconv.ParseInt(startTimeStr, 10, 64)
+		if err != nil {
+			t.CustomAbort(http.StatusBadRequest, "invalid start_time")
+		}
+		st := time.Unix(i, 0)
+		startTime = &st
+	}
+
+	var endTime *time.Time
+	endTimeStr := t.GetString("end_time")
+	if len(endTimeStr) != 0 {
+		i, err := strconv.ParseInt(endTimeStr, 10, 64)
+		if err != nil {
+			t.CustomAbort(http.StatusBadRequest, "invalid end_time")
+		}
+		et := time.Unix(i, 0)
+		endTime = &et
+	}
+
+	page, pageSize := t.GetPaginationParams()
+
+	audits, total, err := dao.FilterTokenAudits(username, service, startTime, endTime,
+		pageSize, pageSize*(page-1))
+	if err != nil {
+		log.Errorf("failed to filter token audits according to username %s, service %s, start time %v, end time %v: %v",
+			username, service, startTime, endTime, err)
+		t.CustomAbort(http.StatusInternalServerError, "")
+	}
+
+	t.SetPaginationHeader(total, page, pageSize)
+	t.Data["json"] = audits
+	t.ServeJSON()
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/vmware/harbor/src/common/api"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/service/token"
+)
+
+// TokenKeyAPI handles requests to /api/token_keys, it manages the key set
+// which the token service signs tokens with
+type TokenKeyAPI struct {
+	api.BaseAPI
+}
+
+type tokenKeyReq struct {
+	Activate bool `json:"activate"`
+}
+
+// Prepare validates that the user has system admin role
+func (t *TokenKeyAPI) Prepare() {
+	uid := t.ValidateUser()
+	isAdmin, err := dao.IsAdminRole(uid)
+	if err != nil {
+		log.Errorf("failed to check whether the user %d is admin: %v", uid, err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	if !isAdmin {
+		t.CustomAbort(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+	}
+}
+
+// List lists the keys in the key set
+func (t *TokenKeyAPI) List() {
+	keys, err := token.ListKeys()
+	if err != nil {
+		log.Errorf("failed to list token keys: %v", err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	t.Data["json"] = keys
+	t.ServeJSON()
+}
+
+// Post generates a key, it becomes the active signing key if "activate" is true
+func (t *TokenKeyAPI) Post() {
+	req := &tokenKeyReq{}
+	t.DecodeJSONReq(req)
+
+	key, err := token.GenerateKey(req.Activate)
+	if err != nil {
+		log.Errorf("failed to generate token key: %v", err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	t.Redirect(http.StatusCreated, key.ID)
+}
+
+// Activate rotates the signing key to the one specified
+func (t *TokenKeyAPI) Activate() {
+	id := t.GetString(":id")
+	if err := token.ActivateKey(id); err != nil {
+		if err == token.ErrKeyNotFound {
+			t.CustomAbort(http.StatusNotFound, fmt.Sprintf("key %s not found", id))
+		}
+		log.Errorf("failed to activate token key %s: %v", id, err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
+
+// Delete removes a key which is not active from the key set
+func (t *TokenKeyAPI) Delete() {
+	id := t.GetString(":id")
+	keys, err := token.ListKeys()
+	if err != nil {
+		log.Errorf("failed to list token keys: %v", err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	for _, key := range keys {
+		if key.ID == id && key.Active {
+			t.CustomAbort(http.StatusPreconditionFailed, "the active key can not be deleted")
+		}
+	}
+
+	if err := token.DeleteKey(id); err != nil {
+		if err == token.ErrKeyNotFound {
+			t.CustomAbort(http.StatusNotFound, fmt.Sprintf("key %s not found", id))
+		}
+		log.Errorf("failed to delete token key %s: %v", id, err)
+		t.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
@@ -21,11 +21,17 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/api"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
+	"github.com/vmware/harbor/src/ui/auth/mfa"
+	"github.com/vmware/harbor/src/ui/auth/pat"
+	"github.com/vmware/harbor/src/ui/auth/oidc"
 	"github.com/vmware/harbor/src/ui/config"
+	"github.com/vmware/harbor/src/ui/service/token"
 )
 
 // UserAPI handles request to /api/users/{}
@@ -36,8 +42,13 @@ type UserAPI struct {
 	SelfRegistration bool
 	IsAdmin          bool
 	AuthMode         string
+	AuthChain        []string
 }
 
+// the max length of the passwords, the other rules are defined by the
+// configurable password policy
+const maxPasswordLen = 128
+
 type passwordReq struct {
 	OldPassword string `json:"old_password"`
 	NewPassword string `json:"new_password"`
@@ -53,6 +64,14 @@ func (ua *UserAPI) Prepare() {
 
 	ua.AuthMode = mode
 
+	chain, err := config.AuthChain()
+	if err != nil {
+		log.Errorf("failed to get auth chain: %v", err)
+		ua.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	ua.AuthChain = chain
+
 	self, err := config.SelfRegistration()
 	if err != nil {
 		log.Errorf("failed to get self registration: %v", err)
@@ -141,7 +160,7 @@ func (ua *UserAPI) Get() {
 func (ua *UserAPI) Put() {
 	ldapAdminUser := (ua.AuthMode == "ldap_auth" && ua.userID == 1 && ua.userID == ua.currentUserID)
 
-	if !(ua.AuthMode == "db_auth" || ldapAdminUser) {
+	if !(ldapAdminUser || ua.isLocalUser(ua.userID)) {
 		ua.CustomAbort(http.StatusForbidden, "")
 	}
 	if !ua.IsAdmin {
@@ -184,12 +203,14 @@ func (ua *UserAPI) Put() {
 		log.Errorf("Failed to update user profile, error: %v", err)
 		ua.CustomAbort(http.StatusInternalServerError, err.Error())
 	}
+	// the email can be used as principal to log in
+	auth.InvalidateCredentialCache(ua.userID)
 }
 
 // Post ...
 func (ua *UserAPI) Post() {
 
-	if !(ua.AuthMode == "db_auth") {
+	if !ua.dbAuthEnabled() {
 		ua.CustomAbort(http.StatusForbidden, "")
 	}
 
@@ -206,6 +227,14 @@ func (ua *UserAPI) Post() {
 		ua.RenderError(http.StatusBadRequest, "register error:"+err.Error())
 		return
 	}
+	if err = auth.ValidatePassword(0, user.Password); err != nil {
+		if _, ok := err.(*auth.PolicyError); !ok {
+			log.Errorf("Error occurred in ValidatePassword: %v", err)
+			ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+		}
+		ua.RenderError(http.StatusBadRequest, "register error:"+err.Error())
+		return
+	}
 	userExist, err := dao.UserExists(user, "username")
 	if err != nil {
 		log.Errorf("Error occurred in Register: %v", err)
@@ -226,6 +255,7 @@ func (ua *UserAPI) Post() {
 		ua.RenderError(http.StatusConflict, "email has already been used!")
 		return
 	}
+	user.AuthSource = common.DBAuth
 	userID, err := dao.Register(user)
 	if err != nil {
 		log.Errorf("Error occurred in Register: %v", err)
@@ -243,28 +273,467 @@ func (ua *UserAPI) Delete() {
 		return
 	}
 
-	if ua.AuthMode == "ldap_auth" {
-		ua.CustomAbort(http.StatusForbidden, "user can not be deleted in LDAP authentication mode")
-	}
-
 	if ua.currentUserID == ua.userID {
 		ua.CustomAbort(http.StatusForbidden, "can not delete yourself")
 	}
 
-	var err error
+	user, err := dao.GetUser(models.User{UserID: ua.userID})
+	if err != nil {
+		log.Errorf("Error occurred in GetUser, error: %v", err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+
+	// the users onboarded from the OIDC provider or the authenticating proxy
+	// are onboarded again once they log in after being deleted
+	if !(ua.isLocalUser(ua.userID) || (user != nil && (user.AuthSource == common.OIDCAuth || user.AuthSource == common.ProxyAuth))) {
+		ua.CustomAbort(http.StatusForbidden, "user authenticated by LDAP can not be deleted")
+	}
+
 	err = dao.DeleteUser(ua.userID)
 	if err != nil {
 		log.Errorf("Failed to delete data from database, error: %v", err)
 		ua.RenderError(http.StatusInternalServerError, "Failed to delete User")
 		return
 	}
+	auth.InvalidateCredentialCache(ua.userID)
+	token.InvalidateUserPermissions(user.Username)
+
+	if _, err = dao.DeleteRefreshTokensByUser(ua.userID); err != nil {
+		log.Errorf("failed to revoke refresh tokens of user %d: %v", ua.userID, err)
+	}
+	if err = mfa.Disable(ua.userID); err != nil {
+		log.Errorf("failed to remove the second factor of user %d: %v", ua.userID, err)
+	}
+	if err = dao.DeleteOIDCUser(ua.userID); err != nil {
+		log.Errorf("failed to delete the OIDC link of user %d: %v", ua.userID, err)
+	}
+	if err = dao.DeletePasswordHistory(ua.userID); err != nil {
+		log.Errorf("failed to delete the password history of user %d: %v", ua.userID, err)
+	}
+	if _, err = dao.DeleteAccessTokensByUser(ua.userID); err != nil {
+		log.Errorf("failed to revoke personal access tokens of user %d: %v", ua.userID, err)
+	}
+	if err = auth.RevokeSessions(ua.userID, ""); err != nil {
+		log.Errorf("failed to revoke sessions of user %d: %v", ua.userID, err)
+	}
+}
+
+// GenerateCLISecret handles POST /api/users/{}/cli_secret, it generates a new
+// CLI secret for the user onboarded from the OIDC provider or the one who has
+// enabled the second factor, which is used as the password by the API and
+// docker clients. The secret is returned only once.
+func (ua *UserAPI) GenerateCLISecret() {
+	// the Prepare skips the validation of the POST requests without
+	// credentials for the self-registration
+	if ua.currentUserID == 0 {
+		ua.CustomAbort(http.StatusUnauthorized, "")
+	}
+	if ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "Users can only generate their own CLI secrets")
+		return
+	}
+
+	user, err := dao.GetUser(models.User{UserID: ua.userID})
+	if err != nil {
+		log.Errorf("Error occurred in GetUser, error: %v", err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if user == nil {
+		ua.CustomAbort(http.StatusNotFound, "")
+	}
+
+	var secret string
+	if user.AuthSource == common.OIDCAuth {
+		secret, err = oidc.GenerateCLISecret(ua.userID)
+	} else {
+		var enabled bool
+		enabled, err = mfa.Enabled(ua.userID)
+		if err != nil {
+			log.Errorf("failed to check the second factor of user %d: %v", ua.userID, err)
+			ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+		}
+		if !enabled {
+			ua.RenderError(http.StatusPreconditionFailed, "The user is neither onboarded from the OIDC provider nor has enabled the second factor")
+			return
+		}
+		secret, err = mfa.GenerateCLISecret(ua.userID)
+	}
+	if err != nil {
+		log.Errorf("failed to generate CLI secret for user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	// the credentials verified with the previous secret are dropped
+	auth.InvalidateCredentialCache(ua.userID)
+
+	ua.Data["json"] = struct {
+		Secret string `json:"secret"`
+	}{
+		Secret: secret,
+	}
+	ua.ServeJSON()
+}
+
+type mfaCodeReq struct {
+	Code string `json:"code"`
+}
+
+// GetMFA handles GET /api/users/{}/mfa, it returns the status of the second
+// factor of the user
+func (ua *UserAPI) GetMFA() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	m, err := dao.GetUserMFA(ua.userID)
+	if err != nil {
+		log.Errorf("failed to get the second factor of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	status := struct {
+		Enabled           bool `json:"enabled"`
+		Pending           bool `json:"pending"`
+		RecoveryCodesLeft int  `json:"recovery_codes_left"`
+	}{}
+	if m != nil {
+		status.Enabled = m.Enabled
+		status.Pending = !m.Enabled
+		status.RecoveryCodesLeft = mfa.RemainingRecoveryCodes(m)
+	}
+	ua.Data["json"] = status
+	ua.ServeJSON()
+}
+
+// EnrollMFA handles POST /api/users/{}/mfa, it generates a TOTP secret for
+// the user in the database, which is enabled once it is confirmed by
+// ActivateMFA. The provisioning URI is shown as a QR code for the
+// authenticator apps to scan.
+func (ua *UserAPI) EnrollMFA() {
+	if ua.currentUserID == 0 {
+		ua.CustomAbort(http.StatusUnauthorized, "")
+	}
+	if ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "Users can only enroll their own second factors")
+		return
+	}
+	if !ua.isLocalUser(ua.userID) {
+		ua.RenderError(http.StatusPreconditionFailed, "The second factor is only supported for the users in the database")
+		return
+	}
+
+	user, err := dao.GetUser(models.User{UserID: ua.userID})
+	if err != nil || user == nil {
+		log.Errorf("Error occurred in GetUser, error: %v", err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	secret, uri, err := mfa.Enroll(user)
+	if err == mfa.ErrEnabled {
+		ua.RenderError(http.StatusConflict, "The second factor is enabled already")
+		return
+	}
+	if err != nil {
+		log.Errorf("failed to enroll the second factor of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+
+	ua.Data["json"] = struct {
+		Secret          string `json:"secret"`
+		ProvisioningURI string `json:"provisioning_uri"`
+	}{
+		Secret:          secret,
+		ProvisioningURI: uri,
+	}
+	ua.ServeJSON()
+}
+
+// ActivateMFA handles POST /api/users/{}/mfa/activate, it enables the second
+// factor enrolled if the code is valid. The recovery codes and the CLI secret
+// are returned only once.
+func (ua *UserAPI) ActivateMFA() {
+	if ua.currentUserID == 0 {
+		ua.CustomAbort(http.StatusUnauthorized, "")
+	}
+	if ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "Users can only enroll their own second factors")
+		return
+	}
+
+	var req mfaCodeReq
+	ua.DecodeJSONReq(&req)
+	codes, secret, err := mfa.Activate(ua.userID, req.Code)
+	switch err {
+	case nil:
+	case mfa.ErrNotPending:
+		ua.RenderError(http.StatusPreconditionFailed, "No pending enrollment of the second factor")
+		return
+	case mfa.ErrInvalidCode:
+		ua.RenderError(http.StatusBadRequest, "Invalid code")
+		return
+	default:
+		log.Errorf("failed to activate the second factor of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	// the session is not restricted to the enrollment any more
+	ua.DelSession(mfa.SessionKey)
+
+	ua.Data["json"] = struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+		CLISecret     string   `json:"cli_secret"`
+	}{
+		RecoveryCodes: codes,
+		CLISecret:     secret,
+	}
+	ua.ServeJSON()
+}
+
+// RegenerateRecoveryCodes handles POST /api/users/{}/mfa/recovery_codes, it
+// replaces the recovery codes of the user after verifying a code
+func (ua *UserAPI) RegenerateRecoveryCodes() {
+	if ua.currentUserID == 0 {
+		ua.CustomAbort(http.StatusUnauthorized, "")
+	}
+	if ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "Users can only regenerate their own recovery codes")
+		return
+	}
+
+	var req mfaCodeReq
+	ua.DecodeJSONReq(&req)
+	ua.verifyMFACode(req.Code)
+
+	codes, err := mfa.RegenerateRecoveryCodes(ua.userID)
+	if err != nil {
+		log.Errorf("failed to regenerate the recovery codes of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	ua.Data["json"] = struct {
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		RecoveryCodes: codes,
+	}
+	ua.ServeJSON()
+}
+
+// DisableMFA handles DELETE /api/users/{}/mfa, admin can disable the second
+// factor of any user, e.g. who lost the device, while the users need to
+// provide a code in the query to disable their own
+func (ua *UserAPI) DisableMFA() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+	if !ua.IsAdmin {
+		ua.verifyMFACode(ua.GetString("code"))
+	}
+
+	if err := mfa.Disable(ua.userID); err != nil {
+		log.Errorf("failed to disable the second factor of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	log.Infof("the second factor of user %d is disabled by user %d", ua.userID, ua.currentUserID)
+}
+
+// verifyMFACode aborts the request if the code or recovery code of the
+// enabled second factor of the user is invalid
+func (ua *UserAPI) verifyMFACode(code string) {
+	enabled, err := mfa.Enabled(ua.userID)
+	if err != nil {
+		log.Errorf("failed to check the second factor of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if !enabled {
+		ua.CustomAbort(http.StatusPreconditionFailed, "The second factor is not enabled")
+	}
+	valid, err := mfa.Verify(ua.userID, code)
+	if err != nil {
+		log.Errorf("failed to verify the second factor of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if !valid {
+		ua.CustomAbort(http.StatusBadRequest, "Invalid code")
+	}
+}
+
+// ListRefreshTokens handles GET /api/users/{}/refresh_tokens, it lists the
+// refresh tokens issued to the user by the token service
+func (ua *UserAPI) ListRefreshTokens() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	tokens, err := dao.GetRefreshTokensByUser(ua.userID)
+	if err != nil {
+		log.Errorf("failed to get refresh tokens of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	ua.Data["json"] = tokens
+	ua.ServeJSON()
+}
+
+// RevokeRefreshTokens handles DELETE /api/users/{}/refresh_tokens, it revokes
+// all refresh tokens issued to the user
+func (ua *UserAPI) RevokeRefreshTokens() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	if _, err := dao.DeleteRefreshTokensByUser(ua.userID); err != nil {
+		log.Errorf("failed to revoke refresh tokens of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+}
+
+// ListAccessTokens handles GET /api/users/{}/tokens, it lists the personal
+// access tokens of the user
+func (ua *UserAPI) ListAccessTokens() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	tokens, err := dao.GetAccessTokensByUser(ua.userID)
+	if err != nil {
+		log.Errorf("failed to get personal access tokens of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	ua.Data["json"] = tokens
+	ua.ServeJSON()
+}
+
+// CreateAccessToken handles POST /api/users/{}/tokens, it mints a personal
+// access token for the user. The token is only returned here.
+func (ua *UserAPI) CreateAccessToken() {
+	if ua.currentUserID == 0 {
+		ua.CustomAbort(http.StatusUnauthorized, "")
+	}
+	if ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "Users can only create their own tokens")
+		return
+	}
+	if ua.AccessToken() != nil {
+		ua.RenderError(http.StatusForbidden, "Tokens can not be created with a token")
+		return
+	}
+
+	req := struct {
+		Name      string `json:"name"`
+		Scope     string `json:"scope"`
+		ExpiresAt int64  `json:"expires_at"`
+	}{}
+	ua.DecodeJSONReq(&req)
+	if len(req.Scope) == 0 {
+		req.Scope = models.AccessTokenScopeRead
+	}
+
+	t, secret, err := pat.Create(ua.userID, req.Name, req.Scope, req.ExpiresAt)
+	if err == pat.ErrNameExists {
+		ua.RenderError(http.StatusConflict, err.Error())
+		return
+	}
+	if err == pat.ErrInvalidName || err == pat.ErrInvalidScope || err == pat.ErrInvalidExpiration {
+		ua.RenderError(http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Errorf("failed to create personal access token of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+
+	ua.Ctx.Output.SetStatus(http.StatusCreated)
+	ua.Data["json"] = struct {
+		*models.AccessToken
+		Token string `json:"token"`
+	}{
+		AccessToken: t,
+		Token:       secret,
+	}
+	ua.ServeJSON()
+}
+
+// RevokeAccessToken handles DELETE /api/users/{}/tokens/{}, it revokes the
+// personal access token of the user
+func (ua *UserAPI) RevokeAccessToken() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	id, err := strconv.ParseInt(ua.Ctx.Input.Param(":tid"), 10, 64)
+	if err != nil {
+		ua.CustomAbort(http.StatusBadRequest, "Invalid token ID")
+	}
+	n, err := dao.DeleteAccessToken(ua.userID, id)
+	if err != nil {
+		log.Errorf("failed to revoke personal access token %d of user %d: %v", id, ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if n == 0 {
+		ua.CustomAbort(http.StatusNotFound, "Token does not exist")
+	}
+}
+
+// ListSessions handles GET /api/users/{}/sessions, it lists the web sessions
+// of the user, the one of the request is marked as current
+func (ua *UserAPI) ListSessions() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	sessions, err := auth.ListSessions(ua.userID, ua.StartSession().SessionID())
+	if err != nil {
+		log.Errorf("failed to get sessions of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	ua.Data["json"] = sessions
+	ua.ServeJSON()
+}
+
+// RevokeSessions handles DELETE /api/users/{}/sessions, it logs the user out
+// of all web sessions
+func (ua *UserAPI) RevokeSessions() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	if err := auth.RevokeSessions(ua.userID, ""); err != nil {
+		log.Errorf("failed to revoke sessions of user %d: %v", ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	log.Infof("the sessions of user %d are revoked by user %d", ua.userID, ua.currentUserID)
+}
+
+// RevokeSession handles DELETE /api/users/{}/sessions/{}, it logs the user
+// out of the web session
+func (ua *UserAPI) RevokeSession() {
+	if !ua.IsAdmin && ua.userID != ua.currentUserID {
+		ua.RenderError(http.StatusForbidden, "User does not have admin role")
+		return
+	}
+
+	id, err := strconv.ParseInt(ua.Ctx.Input.Param(":sid"), 10, 64)
+	if err != nil {
+		ua.CustomAbort(http.StatusBadRequest, "Invalid session ID")
+	}
+	n, err := dao.DeleteUserSession(ua.userID, id)
+	if err != nil {
+		log.Errorf("failed to revoke session %d of user %d: %v", id, ua.userID, err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if n == 0 {
+		ua.CustomAbort(http.StatusNotFound, "Session does not exist")
+	}
+	log.Infof("session %d of user %d is revoked by user %d", id, ua.userID, ua.currentUserID)
 }
 
 // ChangePassword handles PUT to /api/users/{}/password
 func (ua *UserAPI) ChangePassword() {
 	ldapAdminUser := (ua.AuthMode == "ldap_auth" && ua.userID == 1 && ua.userID == ua.currentUserID)
 
-	if !(ua.AuthMode == "db_auth" || ldapAdminUser) {
+	if !(ldapAdminUser || ua.isLocalUser(ua.userID)) {
 		ua.CustomAbort(http.StatusForbidden, "")
 	}
 
@@ -296,12 +765,37 @@ func (ua *UserAPI) ChangePassword() {
 	if req.NewPassword == "" {
 		ua.CustomAbort(http.StatusBadRequest, "please_input_new_password")
 	}
+	if len(req.NewPassword) > maxPasswordLen {
+		ua.CustomAbort(http.StatusBadRequest, "password with illegal length")
+	}
+	if err = auth.ValidatePassword(ua.userID, req.NewPassword); err != nil {
+		if _, ok := err.(*auth.PolicyError); !ok {
+			log.Errorf("Error occurred in ValidatePassword: %v", err)
+			ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+		}
+		ua.CustomAbort(http.StatusBadRequest, err.Error())
+	}
 	updateUser := models.User{UserID: ua.userID, Password: req.NewPassword, Salt: user.Salt}
 	err = dao.ChangeUserPassword(updateUser, req.OldPassword)
 	if err != nil {
 		log.Errorf("Error occurred in ChangeUserPassword: %v", err)
 		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
 	}
+	auth.InvalidateCredentialCache(ua.userID)
+	// the other sessions are logged out, the current one is kept if the user
+	// changes the password of self
+	exceptSID := ""
+	if ua.userID == ua.currentUserID {
+		exceptSID = ua.StartSession().SessionID()
+		// the session is not restricted to changing the password any more
+		ua.DelSession(auth.PasswordExpiredSessionKey)
+	}
+	if err = auth.RevokeSessions(ua.userID, exceptSID); err != nil {
+		log.Errorf("failed to revoke sessions of user %d: %v", ua.userID, err)
+	}
+	if _, err = dao.DeleteRefreshTokensByUser(ua.userID); err != nil {
+		log.Errorf("failed to revoke refresh tokens of user %d: %v", ua.userID, err)
+	}
 }
 
 // ToggleUserAdminRole handles PUT api/users/{}/sysadmin
@@ -317,6 +811,48 @@ func (ua *UserAPI) ToggleUserAdminRole() {
 		log.Errorf("Error occurred in ToggleUserAdminRole: %v", err)
 		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
 	}
+	auth.InvalidateCredentialCache(userQuery.UserID)
+	user, err := dao.GetUser(models.User{UserID: userQuery.UserID})
+	if err != nil {
+		log.Errorf("Error occurred in GetUser, error: %v", err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if user != nil {
+		token.InvalidateUserPermissions(user.Username)
+	}
+}
+
+// dbAuthEnabled returns whether the users can be authenticated against the
+// database, i.e. db_auth is in the auth chain
+func (ua *UserAPI) dbAuthEnabled() bool {
+	for _, mode := range ua.AuthChain {
+		if mode == common.DBAuth {
+			return true
+		}
+	}
+	return false
+}
+
+// isLocalUser returns whether the credentials of the user are managed by
+// Harbor rather than the external backend. The users whose auth source has
+// not been backfilled yet are considered as authenticated by the auth mode.
+func (ua *UserAPI) isLocalUser(userID int) bool {
+	if !ua.dbAuthEnabled() {
+		return false
+	}
+	user, err := dao.GetUser(models.User{UserID: userID})
+	if err != nil {
+		log.Errorf("Error occurred in GetUser, error: %v", err)
+		ua.CustomAbort(http.StatusInternalServerError, "Internal error.")
+	}
+	if user == nil {
+		return false
+	}
+	source := user.AuthSource
+	if source == "" {
+		source = ua.AuthMode
+	}
+	return source == common.DBAuth || source == ""
 }
 
 // validate only validate when user register
@@ -328,7 +864,8 @@ func validate(user models.User) error {
 	if isContainIllegalChar(user.Username, []string{",", "~", "#", "$", "%"}) {
 		return fmt.Errorf("username contains illegal characters")
 	}
-	if isIllegalLength(user.Password, 8, 20) {
+	// the min length is checked by the password policy
+	if isIllegalLength(user.Password, -1, maxPasswordLen) {
 		return fmt.Errorf("password with illegal length")
 	}
 	if err := commonValidate(user); err != nil {
@@ -33,6 +33,10 @@ import (
 	"github.com/vmware/harbor/src/ui/config"
 )
 
+// the max number of repositories handled in one round when syncing
+// repositories from registry to DB
+const catalogPageSize = 1000
+
 func checkProjectPermission(userID int, projectID int64) bool {
 	roles, err := listRoles(userID, projectID)
 	if err != nil {
@@ -76,7 +80,7 @@ func listRoles(userID int, projectID int64) ([]models.Role, error) {
 		return roles, nil
 	}
 
-	rs, err := dao.GetUserProjectRoles(userID, projectID)
+	rs, err := dao.GetUserProjectRolesWithInheritance(userID, projectID)
 	if err != nil {
 		log.Errorf("failed to get user %d 's roles for project %d: %v", userID, projectID, err)
 		return roles, err
@@ -148,7 +152,7 @@ func TriggerReplication(policyID int64, repository string,
 func GetPoliciesByRepository(repository string) ([]*models.RepPolicy, error) {
 	repository = strings.TrimSpace(repository)
 	repository = strings.TrimRight(repository, "/")
-	projectName, _ := utils.ParseRepository(repository)
+	projectName, _ := dao.ParseRepository(repository)
 
 	project, err := dao.GetProjectByName(projectName)
 	if err != nil {
@@ -241,7 +245,7 @@ func SyncRegistry() error {
 
 	log.Infof("Start syncing repositories from registry to DB... ")
 
-	reposInRegistry, err := catalog()
+	rc, err := initRegistryClient()
 	if err != nil {
 		log.Error(err)
 		return err
@@ -258,46 +262,40 @@ func SyncRegistry() error {
 	for _, repoRecordInDB := range repoRecordsInDB {
 		reposInDB = append(reposInDB, repoRecordInDB.Name)
 	}
+	sort.Strings(reposInDB)
 
-	var reposToAdd []string
-	var reposToDel []string
-	reposToAdd, reposToDel, err = diffRepos(reposInRegistry, reposInDB)
-	if err != nil {
-		return err
-	}
+	// the catalog is listed page by page and each page is compared with
+	// the repositories in DB which are in the same range
+	last := ""
+	for {
+		var reposInRegistry []string
+		reposInRegistry, last, err = rc.CatalogWithPagination(catalogPageSize, last)
+		if err != nil {
+			log.Error(err)
+			return err
+		}
 
-	if len(reposToAdd) > 0 {
-		log.Debugf("Start adding repositories into DB... ")
-		for _, repoToAdd := range reposToAdd {
-			project, _ := utils.ParseRepository(repoToAdd)
-			user, err := dao.GetAccessLogCreator(repoToAdd)
-			if err != nil {
-				log.Errorf("Error happens when getting the repository owner from access log: %v", err)
-			}
-			if len(user) == 0 {
-				user = "anonymous"
-			}
-			pullCount, err := dao.CountPull(repoToAdd)
-			if err != nil {
-				log.Errorf("Error happens when counting pull count from access log: %v", err)
-			}
-			repoRecord := models.RepoRecord{Name: repoToAdd, OwnerName: user, ProjectName: project, PullCount: pullCount}
-			if err := dao.AddRepository(repoRecord); err != nil {
-				log.Errorf("Error happens when adding the missing repository: %v", err)
-			} else {
-				log.Debugf("Add repository: %s success.", repoToAdd)
+		n := len(reposInDB)
+		if len(last) > 0 {
+			n = sort.SearchStrings(reposInDB, last)
+			if n < len(reposInDB) && reposInDB[n] == last {
+				n++
 			}
 		}
-	}
 
-	if len(reposToDel) > 0 {
-		log.Debugf("Start deleting repositories from DB... ")
-		for _, repoToDel := range reposToDel {
-			if err := dao.DeleteRepository(repoToDel); err != nil {
-				log.Errorf("Error happens when deleting the repository: %v", err)
-			} else {
-				log.Debugf("Delete repository: %s success.", repoToDel)
-			}
+		var reposToAdd []string
+		var reposToDel []string
+		reposToAdd, reposToDel, err = diffRepos(reposInRegistry, reposInDB[:n])
+		if err != nil {
+			return err
+		}
+		reposInDB = reposInDB[n:]
+
+		addRepos(reposToAdd)
+		deleteRepos(reposToDel)
+
+		if len(last) == 0 {
+			break
 		}
 	}
 
@@ -305,20 +303,45 @@ func SyncRegistry() error {
 	return nil
 }
 
-func catalog() ([]string, error) {
-	repositories := []string{}
-
-	rc, err := initRegistryClient()
-	if err != nil {
-		return repositories, err
+func addRepos(reposToAdd []string) {
+	if len(reposToAdd) == 0 {
+		return
 	}
-
-	repositories, err = rc.Catalog()
-	if err != nil {
-		return repositories, err
+	log.Debugf("Start adding repositories into DB... ")
+	for _, repoToAdd := range reposToAdd {
+		project, _ := dao.ParseRepository(repoToAdd)
+		user, err := dao.GetAccessLogCreator(repoToAdd)
+		if err != nil {
+			log.Errorf("Error happens when getting the repository owner from access log: %v", err)
+		}
+		if len(user) == 0 {
+			user = "anonymous"
+		}
+		pullCount, err := dao.CountPull(repoToAdd)
+		if err != nil {
+			log.Errorf("Error happens when counting pull count from access log: %v", err)
+		}
+		repoRecord := models.RepoRecord{Name: repoToAdd, OwnerName: user, ProjectName: project, PullCount: pullCount}
+		if err := dao.AddRepository(repoRecord); err != nil {
+			log.Errorf("Error happens when adding the missing repository: %v", err)
+		} else {
+			log.Debugf("Add repository: %s success.", repoToAdd)
+		}
 	}
+}
 
-	return repositories, nil
+func deleteRepos(reposToDel []string) {
+	if len(reposToDel) == 0 {
+		return
+	}
+	log.Debugf("Start deleting repositories from DB... ")
+	for _, repoToDel := range reposToDel {
+		if err := dao.DeleteRepository(repoToDel); err != nil {
+			log.Errorf("Error happens when deleting the repository: %v", err)
+		} else {
+			log.Debugf("Delete repository: %s success.", repoToDel)
+		}
+	}
 }
 
 func diffRepos(reposInRegistry []string, reposInDB []string) ([]string, []string, error) {
@@ -420,7 +443,7 @@ func diffRepos(reposInRegistry []string, reposInDB []string) ([]string, []string
 }
 
 func projectExists(repository string) (bool, error) {
-	project, _ := utils.ParseRepository(repository)
+	project, _ := dao.ParseRepository(repository)
 	return dao.ProjectExists(project)
 }
 
@@ -14,26 +14,91 @@
 package auth
 
 import (
+	"errors"
 	"testing"
-	"time"
+
+	"github.com/vmware/harbor/src/common/models"
 )
 
-var l = NewUserLock(2 * time.Second)
+type fakeAuthenticator struct {
+	users map[string]string
+	err   error
+}
+
+func (f *fakeAuthenticator) Authenticate(m models.AuthModel) (*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if password, ok := f.users[m.Principal]; ok && password == m.Password {
+		return &models.User{Username: m.Principal}, nil
+	}
+	return nil, nil
+}
+
+func TestAuthenticate(t *testing.T) {
+	Register("fake_ldap", &fakeAuthenticator{
+		users: map[string]string{"john": "ldap-pass", "svc": "ldap-pass"},
+	})
+	Register("fake_db", &fakeAuthenticator{
+		users: map[string]string{"svc": "db-pass"},
+	})
+	Register("fake_down", &fakeAuthenticator{
+		err: errors.New("server is unreachable"),
+	})
+
+	cases := []struct {
+		chain    []string
+		m        models.AuthModel
+		mode     string
+		hasError bool
+	}{
+		{[]string{"fake_ldap", "fake_db"}, models.AuthModel{Principal: "john", Password: "ldap-pass"}, "fake_ldap", false},
+		{[]string{"fake_ldap", "fake_db"}, models.AuthModel{Principal: "svc", Password: "db-pass"}, "fake_db", false},
+		{[]string{"fake_ldap", "fake_db"}, models.AuthModel{Principal: "svc", Password: "ldap-pass"}, "fake_ldap", false},
+		{[]string{"fake_db", "fake_ldap"}, models.AuthModel{Principal: "svc", Password: "db-pass"}, "fake_db", false},
+		{[]string{"fake_db"}, models.AuthModel{Principal: "john", Password: "ldap-pass"}, "", false},
+		{[]string{"fake_down", "fake_db"}, models.AuthModel{Principal: "svc", Password: "db-pass"}, "fake_db", false},
+		{[]string{"fake_down", "fake_db"}, models.AuthModel{Principal: "john", Password: "ldap-pass"}, "", true},
+		{[]string{"unknown", "fake_db"}, models.AuthModel{Principal: "svc", Password: "db-pass"}, "", true},
+	}
 
-func TestLock(t *testing.T) {
-	t.Log("Locking john")
-	l.Lock("john")
-	if !l.IsLocked("john") {
-		t.Errorf("John should be locked")
+	for _, c := range cases {
+		user, mode, err := authenticate(c.chain, c.m)
+		if c.hasError != (err != nil) {
+			t.Errorf("unexpected error for %v %s: %v", c.chain, c.m.Principal, err)
+			continue
+		}
+		if mode != c.mode {
+			t.Errorf("unexpected auth mode for %v %s: %s != %s", c.chain, c.m.Principal, mode, c.mode)
+		}
+		if (user != nil) != (len(c.mode) > 0) {
+			t.Errorf("unexpected user for %v %s: %v", c.chain, c.m.Principal, user)
+		}
 	}
-	t.Log("Locking jack")
-	l.Lock("jack")
-	t.Log("Sleep for 2 seconds and check...")
-	time.Sleep(2 * time.Second)
-	if l.IsLocked("jack") {
-		t.Errorf("After 2 seconds, jack shouldn't be locked")
+}
+
+func TestRestrictChain(t *testing.T) {
+	chain := []string{"db_auth", "ldap_auth"}
+	cases := []struct {
+		recorded string
+		expected []string
+	}{
+		{"ldap_auth", []string{"ldap_auth"}},
+		{"db_auth", []string{"db_auth"}},
+		{"", nil},
+		{"oidc_auth", nil},
 	}
-	if l.IsLocked("daniel") {
-		t.Errorf("daniel has never been locked, he should not be locked")
+
+	for _, c := range cases {
+		restricted := restrictChain(chain, c.recorded)
+		if len(restricted) != len(c.expected) {
+			t.Errorf("unexpected chain for %q: %v != %v", c.recorded, restricted, c.expected)
+			continue
+		}
+		for i := range restricted {
+			if restricted[i] != c.expected[i] {
+				t.Errorf("unexpected chain for %q: %v != %v", c.recorded, restricted, c.expected)
+			}
+		}
 	}
 }
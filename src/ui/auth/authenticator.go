@@ -16,18 +16,20 @@ package auth
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth/mfa"
 	"github.com/vmware/harbor/src/ui/config"
 )
 
-// 1.5 seconds
+// the delay of the response to a login failure, 1.5 seconds
 const frozenTime time.Duration = 1500 * time.Millisecond
 
-var lock = NewUserLock(frozenTime)
-
 // Authenticator provides interface to authenticate user credentials.
 type Authenticator interface {
 
@@ -46,31 +48,148 @@ func Register(name string, authenticator Authenticator) {
 	registry[name] = authenticator
 }
 
-// Login authenticates user credentials based on setting.
+// Login authenticates user credentials against the auth modes in the auth
+// chain in order. To avoid an account of one backend being taken over by the
+// same username in another one, an existing user is verified only by the
+// auth mode recorded on it, and the admin is always verified against the
+// database. Only the first factor is checked, the failures are recorded but
+// not cleared here, the caller calls ClearFailures once the whole login,
+// including the second factor, succeeds.
 func Login(m models.AuthModel) (*models.User, error) {
 
-	authMode, err := config.AuthMode()
+	chain, err := config.AuthChain()
 	if err != nil {
 		return nil, err
 	}
-	if authMode == "" || m.Principal == "admin" {
-		authMode = "db_auth"
+	if m.Principal == "admin" {
+		chain = []string{common.DBAuth}
 	}
-	log.Debug("Current AUTH_MODE is ", authMode)
-
-	authenticator, ok := registry[authMode]
-	if !ok {
-		return nil, fmt.Errorf("Unrecognized auth_mode: %s", authMode)
+	for i, mode := range chain {
+		if mode == "" {
+			chain[i] = common.DBAuth
+		}
 	}
-	if lock.IsLocked(m.Principal) {
-		log.Debugf("%s is locked due to login failure, login failed", m.Principal)
+	log.Debug("Current AUTH_CHAIN is ", chain)
+
+	if IsLocked(m.Principal) {
+		log.Debugf("%s is locked due to login failures, login failed", m.Principal)
 		return nil, nil
 	}
-	user, err := authenticator.Authenticate(m)
+	key := cacheKey(strings.Join(chain, ","), m.Principal)
+	if user := credCache.get(key, m.Password); user != nil {
+		log.Debugf("credentials of %s are verified by the cache", m.Principal)
+		return user, nil
+	}
+
+	existing, err := dao.GetUser(models.User{Username: m.Principal})
+	if err != nil {
+		return nil, err
+	}
+	// the auth mode recorded on the user, an existing user is verified only by
+	// it and is refused if it is not in the chain, including the users whose
+	// auth mode has not been backfilled yet
+	recorded, userID := "", 0
+	if existing != nil {
+		recorded, userID = existing.AuthSource, existing.UserID
+		chain = restrictChain(chain, recorded)
+		if len(chain) == 0 {
+			log.Warningf("the user %s is refused as it is authenticated by %q which is not in the auth chain", m.Principal, recorded)
+		}
+	}
+	// captured before authenticating, so the result is not cached if the user
+	// is invalidated during the authentication
+	generation := credCache.generation(userID)
+
+	user, mode, err := authenticate(chain, m)
 	if user == nil && err == nil {
-		log.Debugf("Login failed, locking %s, and sleep for %v", m.Principal, frozenTime)
-		lock.Lock(m.Principal)
-		time.Sleep(frozenTime)
+		log.Debugf("Login failed, recording the failure of %s", m.Principal)
+		RecordFailure(m.Principal)
+	}
+	if user != nil && err == nil {
+		if recorded != mode {
+			if err := dao.UpdateUserAuthSource(user.UserID, mode); err != nil {
+				log.Errorf("failed to record the auth mode of user %s: %v", user.Username, err)
+			}
+		}
+		user.AuthSource = mode
+		credCache.put(key, m.Password, user, generation)
 	}
 	return user, err
 }
+
+// LoginCLI authenticates the credentials of the API and docker clients. The
+// users who have enabled the second factor log in with the CLI secret rather
+// than the password, as the clients can not provide the one-time passwords.
+// The CLI secrets of the users onboarded from the OIDC provider are verified
+// by Login as the password. The login failures are cleared only if the login
+// succeeds as a whole.
+func LoginCLI(m models.AuthModel) (*models.User, error) {
+	if IsLocked(m.Principal) {
+		log.Debugf("%s is locked due to login failures, login failed", m.Principal)
+		return nil, nil
+	}
+	user, enabled, err := mfa.VerifyCLISecret(m.Principal, m.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		user, err = Login(m)
+		if err != nil || user == nil {
+			return user, err
+		}
+		expired, err := PasswordExpired(user)
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			log.Warningf("the password of %s has expired, it must be changed in the UI", user.Username)
+			return nil, nil
+		}
+		ClearFailures(m.Principal)
+		return user, nil
+	}
+
+	if user == nil {
+		log.Debugf("Login with CLI secret failed, recording the failure of %s", m.Principal)
+		RecordFailure(m.Principal)
+		return nil, nil
+	}
+	ClearFailures(m.Principal)
+	return user, nil
+}
+
+// restrictChain returns the chain containing only the recorded auth mode, it
+// is empty if the recorded auth mode is empty or not in the chain.
+func restrictChain(chain []string, recorded string) []string {
+	for _, mode := range chain {
+		if recorded != "" && mode == recorded {
+			return []string{recorded}
+		}
+	}
+	return nil
+}
+
+// authenticate tries the auth modes in order and returns the user accepted
+// by the first one along with the auth mode. The error of an authenticator
+// does not break the chain, e.g. the local accounts can still log in while
+// the LDAP server is unreachable, it is returned only if no authenticator
+// accepts the credentials.
+func authenticate(chain []string, m models.AuthModel) (*models.User, string, error) {
+	var lastErr error
+	for _, mode := range chain {
+		authenticator, ok := registry[mode]
+		if !ok {
+			return nil, "", fmt.Errorf("Unrecognized auth_mode: %s", mode)
+		}
+		user, err := authenticator.Authenticate(m)
+		if err != nil {
+			log.Warningf("failed to authenticate %s with %s: %v", m.Principal, mode, err)
+			lastErr = err
+			continue
+		}
+		if user != nil {
+			return user, mode, nil
+		}
+	}
+	return nil, "", lastErr
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/astaxie/beego/cache"
+	// register the redis adapter of beego cache
+	_ "github.com/astaxie/beego/cache/redis"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	defaultCredentialCacheTTL  = 60 * time.Second
+	defaultCredentialCacheSize = 1024
+	generationKeyPrefix        = "harbor:auth:generation:"
+)
+
+// credentialCache holds the successful verifications of credentials for a
+// short time, so the requests sent in a row by docker clients do not hit the
+// authentication backend(e.g. LDAP server) each time. Only the salted hashes
+// of passwords are kept in memory.
+//
+// The entries are invalidated by user ID. Each user has a generation counter
+// and the invalidation increases the counter. The counters are kept in the
+// shared store if it is configured, so the entries cached by all UI instances
+// are invalidated. The generation is captured before the credentials are
+// verified, so the verification which is in progress when the user is
+// invalidated is not cached as a valid one.
+type credentialCache struct {
+	sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]*credentialEntry
+	// generations are the counters used if there is no shared store
+	generations map[int]int
+	// shared is nil if the UI runs without Redis
+	shared cache.Cache
+}
+
+type credentialEntry struct {
+	user       models.User
+	salt       []byte
+	hash       []byte
+	generation string
+	expiration time.Time
+}
+
+var credCache = newCredentialCache(defaultCredentialCacheTTL, defaultCredentialCacheSize, nil)
+
+func newCredentialCache(ttl time.Duration, size int, shared cache.Cache) *credentialCache {
+	return &credentialCache{
+		ttl:         ttl,
+		size:        size,
+		entries:     make(map[string]*credentialEntry),
+		generations: make(map[int]int),
+		shared:      shared,
+	}
+}
+
+// InitCredentialCache sets up the credential cache, the redisURL has the same
+// format as the one configured for the session provider of beego:
+// "host:port,pool_size,password,db_num". If it is empty, the invalidation
+// only takes effect on the current UI instance.
+func InitCredentialCache(redisURL string) error {
+	var shared cache.Cache
+	if len(redisURL) > 0 {
+		conf, err := redisCacheConfig(redisURL)
+		if err != nil {
+			return err
+		}
+		shared, err = cache.NewCache("redis", conf)
+		if err != nil {
+			return err
+		}
+	}
+	credCache = newCredentialCache(defaultCredentialCacheTTL, defaultCredentialCacheSize, shared)
+	return nil
+}
+
+func redisCacheConfig(redisURL string) (string, error) {
+	parts := strings.Split(redisURL, ",")
+	conf := map[string]string{
+		"key":  "harbor:auth",
+		"conn": parts[0],
+	}
+	if len(parts) > 2 {
+		conf["password"] = parts[2]
+	}
+	if len(parts) > 3 {
+		if _, err := strconv.Atoi(parts[3]); err != nil {
+			return "", fmt.Errorf("invalid db number in redis url: %s", parts[3])
+		}
+		conf["dbNum"] = parts[3]
+	}
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// InvalidateCredentialCache drops the cached verifications of the user, it
+// should be called when the password, the role or the existence of the user
+// changes.
+func InvalidateCredentialCache(userID int) {
+	credCache.invalidate(userID)
+}
+
+func cacheKey(authMode, principal string) string {
+	return authMode + ":" + principal
+}
+
+// get returns the cached user if the principal and password were verified
+// within the TTL and the user has not been invalidated since then
+func (c *credentialCache) get(key, password string) *models.User {
+	c.Lock()
+	entry, ok := c.entries[key]
+	c.Unlock()
+	if !ok {
+		return nil
+	}
+
+	if time.Now().After(entry.expiration) ||
+		subtle.ConstantTimeCompare(entry.hash, hashPassword(entry.salt, password)) != 1 {
+		return nil
+	}
+
+	if c.generation(entry.user.UserID) != entry.generation {
+		c.Lock()
+		if c.entries[key] == entry {
+			delete(c.entries, key)
+		}
+		c.Unlock()
+		return nil
+	}
+
+	user := entry.user
+	return &user
+}
+
+// put caches the verification of the credentials, the generation should be
+// the one of the user captured before the verification
+func (c *credentialCache) put(key, password string, user *models.User, generation string) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		log.Errorf("failed to generate salt for credential cache: %v", err)
+		return
+	}
+	entry := &credentialEntry{
+		user:       *user,
+		salt:       salt,
+		hash:       hashPassword(salt, password),
+		generation: generation,
+		expiration: time.Now().Add(c.ttl),
+	}
+	// the password is never cached
+	entry.user.Password = ""
+
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		c.evict()
+	}
+	c.entries[key] = entry
+}
+
+// evict removes the expired entries, if there is none, it removes the one
+// which expires first
+func (c *credentialCache) evict() {
+	now := time.Now()
+	oldest := ""
+	for key, entry := range c.entries {
+		if now.After(entry.expiration) {
+			delete(c.entries, key)
+			continue
+		}
+		if len(oldest) == 0 || entry.expiration.Before(c.entries[oldest].expiration) {
+			oldest = key
+		}
+	}
+	if len(c.entries) >= c.size && len(oldest) > 0 {
+		delete(c.entries, oldest)
+	}
+}
+
+func (c *credentialCache) invalidate(userID int) {
+	c.Lock()
+	for key, entry := range c.entries {
+		if entry.user.UserID == userID {
+			delete(c.entries, key)
+		}
+	}
+	c.generations[userID]++
+	c.Unlock()
+
+	if c.shared != nil {
+		if err := c.shared.Incr(generationKey(userID)); err != nil {
+			log.Errorf("failed to invalidate cached credentials of user %d: %v", userID, err)
+		}
+	}
+}
+
+// generation returns the generation of the user, the user ID is 0 if the
+// user does not exist yet
+func (c *credentialCache) generation(userID int) string {
+	if c.shared != nil {
+		return cache.GetString(c.shared.Get(generationKey(userID)))
+	}
+	c.Lock()
+	defer c.Unlock()
+	return strconv.Itoa(c.generations[userID])
+}
+
+func generationKey(userID int) string {
+	return generationKeyPrefix + strconv.Itoa(userID)
+}
+
+func hashPassword(salt []byte, password string) []byte {
+	h := sha256.New()
+	h.Write(salt)
+	h.Write([]byte(password))
+	return h.Sum(nil)
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/astaxie/beego/cache"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestCredentialCache(t *testing.T) {
+	c := newCredentialCache(time.Second, 2, nil)
+	user := &models.User{UserID: 2, Username: "user", Password: "Harbor12345"}
+
+	c.put("db_auth:user", "Harbor12345", user, c.generation(2))
+	u := c.get("db_auth:user", "Harbor12345")
+	if u == nil || u.UserID != 2 {
+		t.Fatalf("unexpected cached user: %+v", u)
+	}
+	if len(u.Password) != 0 {
+		t.Errorf("the password should not be cached")
+	}
+	if c.get("db_auth:user", "wrong") != nil {
+		t.Errorf("wrong password should not be verified by the cache")
+	}
+
+	c.invalidate(2)
+	if c.get("db_auth:user", "Harbor12345") != nil {
+		t.Errorf("the entry should be invalidated")
+	}
+
+	c.put("db_auth:user", "Harbor12345", user, c.generation(2))
+	time.Sleep(1100 * time.Millisecond)
+	if c.get("db_auth:user", "Harbor12345") != nil {
+		t.Errorf("the entry should be expired")
+	}
+
+	for i := 0; i < 3; i++ {
+		c.put(cacheKey("db_auth", strconv.Itoa(i)), "pass", &models.User{UserID: 10 + i}, c.generation(10+i))
+	}
+	if len(c.entries) != 2 {
+		t.Errorf("unexpected size of cache: %d", len(c.entries))
+	}
+
+	// the user is invalidated while the credentials are being verified
+	generation := c.generation(2)
+	c.invalidate(2)
+	c.put("db_auth:user", "Harbor12345", user, generation)
+	if c.get("db_auth:user", "Harbor12345") != nil {
+		t.Errorf("the verification started before the invalidation should not be valid")
+	}
+}
+
+func TestCredentialCacheShared(t *testing.T) {
+	shared, err := cache.NewCache("memory", `{"interval":60}`)
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if err = shared.Put(generationKey(3), 0, time.Hour); err != nil {
+		t.Fatalf("failed to initialize generation: %v", err)
+	}
+
+	// two UI instances sharing the same store
+	c1 := newCredentialCache(time.Minute, 10, shared)
+	c2 := newCredentialCache(time.Minute, 10, shared)
+	user := &models.User{UserID: 3, Username: "user"}
+	c1.put("ldap_auth:user", "pass", user, c1.generation(3))
+	c2.put("ldap_auth:user", "pass", user, c2.generation(3))
+
+	c2.invalidate(3)
+	if c1.get("ldap_auth:user", "pass") != nil {
+		t.Errorf("the entry should be invalidated by the other instance")
+	}
+	if c2.get("ldap_auth:user", "pass") != nil {
+		t.Errorf("the entry should be invalidated")
+	}
+
+	c1.put("ldap_auth:user", "pass", user, c1.generation(3))
+	if c1.get("ldap_auth:user", "pass") == nil {
+		t.Errorf("the entry cached after invalidation should be valid")
+	}
+
+	generation := c1.generation(3)
+	c2.invalidate(3)
+	c1.put("ldap_auth:user", "pass", user, generation)
+	if c1.get("ldap_auth:user", "pass") != nil {
+		t.Errorf("the verification started before the invalidation should not be valid")
+	}
+}
+
+func TestRedisCacheConfig(t *testing.T) {
+	conf, err := redisCacheConfig("redis:6379,100,pass,1")
+	if err != nil {
+		t.Fatalf("failed to parse redis url: %v", err)
+	}
+	expected := `{"conn":"redis:6379","dbNum":"1","key":"harbor:auth","password":"pass"}`
+	if conf != expected {
+		t.Errorf("unexpected config: %s != %s", conf, expected)
+	}
+	if _, err = redisCacheConfig("redis:6379,100,,db"); err == nil {
+		t.Errorf("invalid db number should be rejected")
+	}
+}
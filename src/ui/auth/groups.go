@@ -0,0 +1,126 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+// GroupRole maps a group of the external identity provider, e.g. the OIDC
+// provider, to a role on a project
+type GroupRole struct {
+	Group   string
+	Project string
+	Role    int
+}
+
+var roles = map[string]int{
+	"projectadmin": models.PROJECTADMIN,
+	"developer":    models.DEVELOPER,
+	"guest":        models.GUEST,
+}
+
+// ParseGroupRoleMapping parses the comma separated mappings in the format
+// group:project:role, e.g. "dev-team:library:developer"
+func ParseGroupRoleMapping(s string) ([]GroupRole, error) {
+	mappings := []GroupRole{}
+	for _, m := range strings.Split(s, ",") {
+		m = strings.TrimSpace(m)
+		if len(m) == 0 {
+			continue
+		}
+		// the group may contain ":", e.g. the DN of LDAP groups
+		i := strings.LastIndex(m, ":")
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid mapping %s, should be group:project:role", m)
+		}
+		j := strings.LastIndex(m[:i], ":")
+		if j <= 0 || j == i-1 {
+			return nil, fmt.Errorf("invalid mapping %s, should be group:project:role", m)
+		}
+		role, ok := roles[strings.ToLower(m[i+1:])]
+		if !ok {
+			return nil, fmt.Errorf("invalid role %s, should be projectAdmin, developer or guest", m[i+1:])
+		}
+		mappings = append(mappings, GroupRole{
+			Group:   m[:j],
+			Project: m[j+1 : i],
+			Role:    role,
+		})
+	}
+	return mappings, nil
+}
+
+// ProjectRoles returns the role on each mapped project for the user in the
+// groups, the highest role is used if the user is in several groups mapped
+// to the project, and 0 means the user is in none of them
+func ProjectRoles(mappings []GroupRole, groups []string) map[string]int {
+	in := make(map[string]bool, len(groups))
+	for _, g := range groups {
+		in[g] = true
+	}
+	result := make(map[string]int)
+	for _, m := range mappings {
+		role := result[m.Project]
+		if in[m.Group] && (role == 0 || m.Role < role) {
+			role = m.Role
+		}
+		result[m.Project] = role
+	}
+	return result
+}
+
+// SyncProjectRoles makes the memberships of the user on the mapped projects
+// consistent with the roles, and returns the projects whose members are
+// changed
+func SyncProjectRoles(userID int, projectRoles map[string]int) ([]string, error) {
+	changed := []string{}
+	for name, role := range projectRoles {
+		project, err := dao.GetProjectByName(name)
+		if err != nil {
+			return changed, err
+		}
+		if project == nil {
+			log.Warningf("project %s in the group role mapping does not exist", name)
+			continue
+		}
+		current, err := dao.GetUserProjectRoles(userID, project.ProjectID)
+		if err != nil {
+			return changed, err
+		}
+		switch {
+		case len(current) == 0 && role == 0:
+			continue
+		case len(current) == 0:
+			err = dao.AddProjectMember(project.ProjectID, userID, role)
+		case role == 0:
+			err = dao.DeleteProjectMember(project.ProjectID, userID)
+		case current[0].RoleID != role:
+			err = dao.UpdateProjectMember(project.ProjectID, userID, role)
+		default:
+			continue
+		}
+		if err != nil {
+			return changed, err
+		}
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestParseGroupRoleMapping(t *testing.T) {
+	mappings, err := ParseGroupRoleMapping(" dev:library:developer, ops:org/team:projectAdmin ,")
+	if err != nil {
+		t.Fatalf("failed to parse mapping: %v", err)
+	}
+	assert.Equal(t, []GroupRole{
+		{Group: "dev", Project: "library", Role: models.DEVELOPER},
+		{Group: "ops", Project: "org/team", Role: models.PROJECTADMIN},
+	}, mappings)
+
+	for _, s := range []string{"dev:library", "dev:library:owner", ":library:guest", "dev::guest"} {
+		if _, err := ParseGroupRoleMapping(s); err == nil {
+			t.Errorf("expected error for %q", s)
+		}
+	}
+
+	mappings, err = ParseGroupRoleMapping("uid=ldap:grp:library:guest")
+	if err != nil {
+		t.Fatalf("failed to parse mapping: %v", err)
+	}
+	assert.Equal(t, "uid=ldap:grp", mappings[0].Group)
+	assert.Equal(t, "library", mappings[0].Project)
+}
+
+func TestProjectRoles(t *testing.T) {
+	mappings := []GroupRole{
+		{Group: "dev", Project: "library", Role: models.DEVELOPER},
+		{Group: "admins", Project: "library", Role: models.PROJECTADMIN},
+		{Group: "guests", Project: "public", Role: models.GUEST},
+	}
+	assert.Equal(t, map[string]int{
+		"library": models.PROJECTADMIN,
+		"public":  0,
+	}, ProjectRoles(mappings, []string{"dev", "admins"}))
+	assert.Equal(t, map[string]int{
+		"library": models.DEVELOPER,
+		"public":  models.GUEST,
+	}, ProjectRoles(mappings, []string{"guests", "dev"}))
+}
@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	ldapUtils "github.com/vmware/harbor/src/common/utils/ldap"
@@ -97,6 +98,10 @@ func (l *Auth) Authenticate(m models.AuthModel) (*models.User, error) {
 		if err != nil {
 			return nil, err
 		}
+		if currentUser.AuthSource != common.LDAPAuth {
+			log.Warningf("the user %s is not mapped to the LDAP entry as it is authenticated by %q", u.Username, currentUser.AuthSource)
+			return nil, nil
+		}
 		u.UserID = currentUser.UserID
 	} else {
 		userID, err := ldapUtils.ImportUser(ldapUsers[0])
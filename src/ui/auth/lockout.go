@@ -0,0 +1,111 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"time"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/config"
+)
+
+// IsLocked returns whether the account is locked due to login failures. The
+// login is not blocked if the lockout can not be read, as the database is
+// needed by the login anyway.
+func IsLocked(principal string) bool {
+	locked, err := dao.IsAccountLocked(principal)
+	if err != nil {
+		log.Errorf("failed to check the lockout of %s: %v", principal, err)
+		return false
+	}
+	return locked
+}
+
+// RecordFailure counts a login failure of the principal, e.g. invalid
+// credentials or second factor, and locks the account once the failures
+// reach the threshold. The response to the failure is delayed. Only the
+// failures of the existing users are counted, so the unauthenticated
+// requests can not grow the lockout records without bound.
+func RecordFailure(principal string) {
+	defer time.Sleep(frozenTime)
+
+	user, err := dao.GetUser(models.User{Username: principal})
+	if err != nil {
+		log.Errorf("failed to get user %s: %v", principal, err)
+		return
+	}
+	if user == nil {
+		return
+	}
+
+	setting, err := config.Lockout()
+	if err != nil {
+		log.Errorf("failed to get the lockout setting: %v", err)
+		return
+	}
+	if setting.Threshold <= 0 {
+		return
+	}
+
+	locked, err := dao.AddLoginFailure(principal, setting.Threshold,
+		time.Duration(setting.Window)*time.Minute,
+		time.Duration(setting.Duration)*time.Minute)
+	if err != nil {
+		log.Errorf("failed to record the login failure of %s: %v", principal, err)
+		return
+	}
+	if !locked {
+		return
+	}
+
+	log.Warningf("%s is locked for %d minutes due to %d login failures in %d minutes",
+		principal, setting.Duration, setting.Threshold, setting.Window)
+	if _, err = dao.AddLockoutAudit(&models.LockoutAudit{
+		Username:  principal,
+		Operation: models.LockoutOpLock,
+	}); err != nil {
+		log.Errorf("failed to audit the lockout of %s: %v", principal, err)
+	}
+}
+
+// ClearFailures discards the login failures of the principal, it is called
+// by the caller of Login once the whole login succeeds, including the second
+// factor
+func ClearFailures(principal string) {
+	if err := dao.ClearLoginFailures(principal); err != nil {
+		log.Errorf("failed to clear the login failures of %s: %v", principal, err)
+	}
+}
+
+// Unlock unlocks the account of the username by the operator and audits it,
+// it returns whether the account was locked
+func Unlock(username, operator string) (bool, error) {
+	locked, err := dao.UnlockAccount(username)
+	if err != nil || !locked {
+		return false, err
+	}
+
+	log.Infof("%s is unlocked by %s", username, operator)
+	if _, err = dao.AddLockoutAudit(&models.LockoutAudit{
+		Username:  username,
+		Operation: models.LockoutOpUnlock,
+		Operator:  operator,
+	}); err != nil {
+		log.Errorf("failed to audit the unlock of %s: %v", username, err)
+	}
+	return true, nil
+}
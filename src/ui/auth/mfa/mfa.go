@@ -0,0 +1,330 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mfa implements the time-based one-time passwords as the second
+// factor of the users in the database. Once it is enabled, the web login
+// requires a code or a recovery code along with the password, and the API
+// and docker clients use the CLI secret instead of the password.
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/common/utils/totp"
+	"github.com/vmware/harbor/src/ui/config"
+)
+
+const (
+	// Header is set in the response to the login which needs the second
+	// factor, the value is either HeaderRequired or HeaderEnrollment
+	Header = "X-Harbor-MFA"
+	// HeaderRequired means the code is missing or invalid
+	HeaderRequired = "required"
+	// HeaderEnrollment means the user must enroll before using Harbor
+	HeaderEnrollment = "enrollment"
+	// SessionKey marks the session of the user who must enroll, it can only
+	// access the APIs of the enrollment
+	SessionKey = "mfaEnrollment"
+
+	issuer            = "Harbor"
+	recoveryCodeCount = 10
+	recoveryCodeBytes = 5
+)
+
+var (
+	// ErrEnabled is returned when enrolling a user who has enabled the
+	// second factor
+	ErrEnabled = errors.New("the second factor is enabled already")
+	// ErrNotPending is returned when confirming an enrollment which does
+	// not exist
+	ErrNotPending = errors.New("no pending enrollment")
+	// ErrInvalidCode is returned when the code does not match
+	ErrInvalidCode = errors.New("invalid code")
+)
+
+// Enabled returns whether the user has enabled the second factor
+func Enabled(userID int) (bool, error) {
+	mfa, err := dao.GetUserMFA(userID)
+	if err != nil {
+		return false, err
+	}
+	return mfa != nil && mfa.Enabled, nil
+}
+
+// EnrollmentRequired returns whether the user logged in must enroll before
+// using Harbor, i.e. the user is a system admin in the database who has not
+// enabled the second factor while it is enforced for the admins
+func EnrollmentRequired(user *models.User) (bool, error) {
+	if !config.MFAEnforceAdmin() || user.AuthSource != common.DBAuth {
+		return false, nil
+	}
+	isAdmin, err := dao.IsAdminRole(user.UserID)
+	if err != nil || !isAdmin {
+		return false, err
+	}
+	enabled, err := Enabled(user.UserID)
+	return !enabled, err
+}
+
+// Enroll generates a new TOTP secret for the user, which is pending until
+// it is confirmed by Activate. It returns the secret and the provisioning
+// URI to be shown as a QR code.
+func Enroll(user *models.User) (string, string, error) {
+	mfa, err := dao.GetUserMFA(user.UserID)
+	if err != nil {
+		return "", "", err
+	}
+	if mfa != nil {
+		if mfa.Enabled {
+			return "", "", ErrEnabled
+		}
+		if err = dao.DeleteUserMFA(user.UserID); err != nil {
+			return "", "", err
+		}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	key, err := config.SecretKey()
+	if err != nil {
+		return "", "", err
+	}
+	encrypted, err := utils.ReversibleEncrypt(secret, key)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err = dao.AddUserMFA(&models.UserMFA{
+		UserID: user.UserID,
+		Secret: encrypted,
+	}); err != nil {
+		return "", "", err
+	}
+	return secret, totp.ProvisioningURI(issuer, user.Username, secret), nil
+}
+
+// Activate confirms the pending enrollment of the user with a code of the
+// secret. It returns the recovery codes and the CLI secret, which are shown
+// only once.
+func Activate(userID int, code string) ([]string, string, error) {
+	mfa, err := dao.GetUserMFA(userID)
+	if err != nil {
+		return nil, "", err
+	}
+	if mfa == nil || mfa.Enabled {
+		return nil, "", ErrNotPending
+	}
+
+	secret, err := decryptSecret(mfa)
+	if err != nil {
+		return nil, "", err
+	}
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, "", ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, "", err
+	}
+	cliSecret, err := generateCLISecret()
+	if err != nil {
+		return nil, "", err
+	}
+	activated, err := dao.EnableUserMFA(userID, step, hashes, hash(cliSecret))
+	if err != nil {
+		return nil, "", err
+	}
+	if !activated {
+		return nil, "", ErrNotPending
+	}
+	return codes, cliSecret, nil
+}
+
+// Verify checks the code against the enabled second factor of the user, a
+// recovery code is accepted as well and it can be used only once
+func Verify(userID int, code string) (bool, error) {
+	mfa, err := dao.GetUserMFA(userID)
+	if err != nil || mfa == nil || !mfa.Enabled {
+		return false, err
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		secret, err := decryptSecret(mfa)
+		if err != nil {
+			return false, err
+		}
+		step, ok := totp.Validate(secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		// the code can not be used again
+		return dao.UpdateUserMFALastStep(userID, step)
+	}
+
+	h := hash(normalizeRecoveryCode(code))
+	remaining := []string{}
+	found := false
+	for _, c := range strings.Split(mfa.RecoveryCodes, ",") {
+		if len(c) == 0 {
+			continue
+		}
+		if !found && subtle.ConstantTimeCompare([]byte(c), []byte(h)) == 1 {
+			found = true
+			continue
+		}
+		remaining = append(remaining, c)
+	}
+	if !found {
+		return false, nil
+	}
+	used, err := dao.UpdateUserMFARecoveryCodes(userID, mfa.RecoveryCodes, strings.Join(remaining, ","))
+	if err != nil || !used {
+		return false, err
+	}
+	log.Infof("a recovery code of user %d is used, %d left", userID, len(remaining))
+	return true, nil
+}
+
+// RemainingRecoveryCodes returns the count of the unused recovery codes
+func RemainingRecoveryCodes(mfa *models.UserMFA) int {
+	if len(mfa.RecoveryCodes) == 0 {
+		return 0
+	}
+	return len(strings.Split(mfa.RecoveryCodes, ","))
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user
+func RegenerateRecoveryCodes(userID int) ([]string, error) {
+	mfa, err := dao.GetUserMFA(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || !mfa.Enabled {
+		return nil, fmt.Errorf("user %d has not enabled the second factor", userID)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	replaced, err := dao.UpdateUserMFARecoveryCodes(userID, mfa.RecoveryCodes, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !replaced {
+		return nil, fmt.Errorf("the recovery codes of user %d are changed concurrently", userID)
+	}
+	return codes, nil
+}
+
+// GenerateCLISecret replaces the CLI secret of the user who has enabled the
+// second factor and returns the new one
+func GenerateCLISecret(userID int) (string, error) {
+	enabled, err := Enabled(userID)
+	if err != nil {
+		return "", err
+	}
+	if !enabled {
+		return "", fmt.Errorf("user %d has not enabled the second factor", userID)
+	}
+	secret, err := generateCLISecret()
+	if err != nil {
+		return "", err
+	}
+	if err = dao.UpdateUserMFACLISecretHash(userID, hash(secret)); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// VerifyCLISecret checks the secret against the CLI secret of the user whose
+// name or email is the principal. The returned bool is false if the user has
+// not enabled the second factor, and the user is nil if the secret does not
+// match.
+func VerifyCLISecret(principal, secret string) (*models.User, bool, error) {
+	mfa, err := dao.GetEnabledUserMFAByUsername(principal)
+	if err != nil || mfa == nil {
+		return nil, false, err
+	}
+	if len(mfa.CLISecretHash) == 0 ||
+		subtle.ConstantTimeCompare([]byte(hash(secret)), []byte(mfa.CLISecretHash)) != 1 {
+		return nil, true, nil
+	}
+	user, err := dao.GetUser(models.User{UserID: mfa.UserID})
+	return user, true, err
+}
+
+// Disable removes the second factor of the user
+func Disable(userID int) error {
+	return dao.DeleteUserMFA(userID)
+}
+
+func decryptSecret(mfa *models.UserMFA) (string, error) {
+	key, err := config.SecretKey()
+	if err != nil {
+		return "", err
+	}
+	return utils.ReversibleDecrypt(mfa.Secret, key)
+}
+
+// generateRecoveryCodes returns the recovery codes and their hashes joined
+// by comma
+func generateRecoveryCodes() ([]string, string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(b); err != nil {
+			return nil, "", err
+		}
+		c := strings.ToLower(strings.TrimRight(base32.StdEncoding.EncodeToString(b), "="))
+		codes[i] = c[:4] + "-" + c[4:]
+		hashes[i] = hash(c)
+	}
+	return codes, strings.Join(hashes, ","), nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.Replace(code, "-", "", -1))
+}
+
+func generateCLISecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
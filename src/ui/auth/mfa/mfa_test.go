@@ -0,0 +1,56 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mfa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("failed to generate recovery codes: %v", err)
+	}
+	assert.Equal(t, recoveryCodeCount, len(codes))
+
+	hs := strings.Split(hashes, ",")
+	assert.Equal(t, recoveryCodeCount, len(hs))
+	seen := map[string]bool{}
+	for i, code := range codes {
+		assert.Equal(t, 9, len(code), code)
+		assert.Equal(t, "-", code[4:5], code)
+		assert.Equal(t, hs[i], hash(normalizeRecoveryCode(code)))
+		// the codes are accepted without dash or in upper case
+		assert.Equal(t, hs[i], hash(normalizeRecoveryCode(strings.ToUpper(strings.Replace(code, "-", "", -1)))))
+		assert.False(t, seen[code], "duplicate recovery code %s", code)
+		seen[code] = true
+	}
+}
+
+func TestGenerateCLISecret(t *testing.T) {
+	s1, err := generateCLISecret()
+	if err != nil {
+		t.Fatalf("failed to generate CLI secret: %v", err)
+	}
+	s2, err := generateCLISecret()
+	if err != nil {
+		t.Fatalf("failed to generate CLI secret: %v", err)
+	}
+	assert.NotEqual(t, s1, s2)
+	assert.Equal(t, 64, len(hash(s1)))
+}
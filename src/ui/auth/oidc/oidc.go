@@ -0,0 +1,425 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	discoveryPath = "/.well-known/openid-configuration"
+	// the metadata and keys of the provider are fetched again after the
+	// interval, so the rotated keys can be picked up
+	metadataTTL = 10 * time.Minute
+	// the keys are fetched at most once in the interval when an ID token
+	// signed by an unknown key is received
+	keysRefreshInterval = time.Minute
+)
+
+// ErrInvalidIDToken is returned if the ID token fails the verification
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+// metadata is the part of the discovery document of the provider used by Harbor
+type metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Token is the response of the token endpoint of the provider
+type Token struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// Claims holds the claims of the ID token used by Harbor
+type Claims struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Email    string
+	Name     string
+	Groups   []string
+}
+
+// provider talks to the OpenID Connect provider, the discovery document and
+// the signing keys are cached
+type provider struct {
+	sync.Mutex
+	setting     models.OIDCSetting
+	client      *http.Client
+	metadata    *metadata
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time
+}
+
+var (
+	current *provider
+	mu      sync.Mutex
+)
+
+// getProvider returns the provider of the setting, it is recreated if the
+// setting has been changed
+func getProvider(setting *models.OIDCSetting) (*provider, error) {
+	if len(setting.Endpoint) == 0 || len(setting.ClientID) == 0 {
+		return nil, errors.New("the OIDC endpoint or client ID is not configured")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil || !sameSetting(&current.setting, setting) {
+		current = newProvider(setting)
+	}
+	return current, nil
+}
+
+func sameSetting(a, b *models.OIDCSetting) bool {
+	return a.Endpoint == b.Endpoint &&
+		a.ClientID == b.ClientID &&
+		a.ClientSecret == b.ClientSecret &&
+		strings.Join(a.Scope, ",") == strings.Join(b.Scope, ",") &&
+		a.GroupsClaim == b.GroupsClaim &&
+		a.VerifyCert == b.VerifyCert
+}
+
+func newProvider(setting *models.OIDCSetting) *provider {
+	return &provider{
+		setting: *setting,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: !setting.VerifyCert,
+				},
+			},
+		},
+	}
+}
+
+// load fetches the discovery document and the signing keys if they are not
+// cached or expired, it must be called with the lock held
+func (p *provider) load(force bool) error {
+	if p.metadata != nil && !force && time.Since(p.fetchedAt) < metadataTTL {
+		return nil
+	}
+
+	md := &metadata{}
+	if err := p.getJSON(strings.TrimRight(p.setting.Endpoint, "/")+discoveryPath, md); err != nil {
+		return fmt.Errorf("failed to get the discovery document: %v", err)
+	}
+	if len(md.AuthorizationEndpoint) == 0 || len(md.TokenEndpoint) == 0 || len(md.JWKSURI) == 0 {
+		return errors.New("the discovery document misses the endpoints")
+	}
+
+	keys, err := p.fetchKeys(md.JWKSURI)
+	if err != nil {
+		return err
+	}
+
+	p.metadata = md
+	p.keys = keys
+	p.fetchedAt = time.Now()
+	p.refreshedAt = p.fetchedAt
+	return nil
+}
+
+func (p *provider) fetchKeys(jwksURI string) (map[string]*rsa.PublicKey, error) {
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := p.getJSON(jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to get the signing keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		key, err := parseRSAKey(k)
+		if err != nil {
+			log.Warningf("failed to parse the signing key %s of OIDC provider: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func parseRSAKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.N, "="))
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(k.E, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(n) == 0 || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("malformed modulus or exponent")
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
+
+func (p *provider) getJSON(u string, v interface{}) error {
+	resp, err := p.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d from %s: %s", resp.StatusCode, u, string(data))
+	}
+	return json.Unmarshal(data, v)
+}
+
+// authCodeURL returns the URL of the provider which the browser is
+// redirected to for the authorization code
+func (p *provider) authCodeURL(redirectURI, state, nonce string) (string, error) {
+	p.Lock()
+	defer p.Unlock()
+	if err := p.load(false); err != nil {
+		return "", err
+	}
+
+	scope := []string{"openid"}
+	for _, s := range p.setting.Scope {
+		if s != "openid" {
+			scope = append(scope, s)
+		}
+	}
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.setting.ClientID)
+	values.Set("redirect_uri", redirectURI)
+	values.Set("scope", strings.Join(scope, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+
+	sep := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return p.metadata.AuthorizationEndpoint + sep + values.Encode(), nil
+}
+
+// exchange redeems the authorization code for the tokens
+func (p *provider) exchange(redirectURI, code string) (*Token, error) {
+	p.Lock()
+	if err := p.load(false); err != nil {
+		p.Unlock()
+		return nil, err
+	}
+	tokenEndpoint := p.metadata.TokenEndpoint
+	p.Unlock()
+
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", redirectURI)
+	req, err := http.NewRequest(http.MethodPost, tokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(p.setting.ClientID), url.QueryEscape(p.setting.ClientSecret))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to exchange the code, status code %d: %s", resp.StatusCode, string(data))
+	}
+
+	token := &Token{}
+	if err = json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	if len(token.IDToken) == 0 {
+		return nil, errors.New("no ID token in the response of the token endpoint")
+	}
+	return token, nil
+}
+
+// key returns the signing key of the kid, the keys are fetched again if the
+// kid is unknown as the provider may have rotated the keys
+func (p *provider) key(kid string) (*rsa.PublicKey, error) {
+	p.Lock()
+	defer p.Unlock()
+	if err := p.load(false); err != nil {
+		return nil, err
+	}
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.refreshedAt) > keysRefreshInterval {
+		p.refreshedAt = time.Now()
+		keys, err := p.fetchKeys(p.metadata.JWKSURI)
+		if err != nil {
+			return nil, err
+		}
+		p.keys = keys
+		if key, ok := p.keys[kid]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key: %s", kid)
+}
+
+// verify checks the signature, issuer, audience, expiration and nonce of
+// the ID token and returns its claims
+func (p *provider) verify(rawIDToken, nonce string) (*Claims, error) {
+	token, err := jwt.Parse(rawIDToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.key(kid)
+	})
+	if err != nil {
+		log.Warningf("failed to verify the ID token: %v", err)
+		return nil, ErrInvalidIDToken
+	}
+	mc, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidIDToken
+	}
+
+	p.Lock()
+	issuer := p.metadata.Issuer
+	p.Unlock()
+	if !mc.VerifyIssuer(issuer, true) {
+		log.Warningf("unexpected issuer of the ID token: %v", mc["iss"])
+		return nil, ErrInvalidIDToken
+	}
+	if !hasAudience(mc["aud"], p.setting.ClientID) {
+		log.Warningf("unexpected audience of the ID token: %v", mc["aud"])
+		return nil, ErrInvalidIDToken
+	}
+	if !mc.VerifyExpiresAt(time.Now().Unix(), true) {
+		log.Warning("the ID token is expired")
+		return nil, ErrInvalidIDToken
+	}
+	if n, _ := mc["nonce"].(string); n != nonce {
+		log.Warning("unexpected nonce of the ID token")
+		return nil, ErrInvalidIDToken
+	}
+
+	claims := &Claims{
+		Issuer: issuer,
+	}
+	claims.Subject, _ = mc["sub"].(string)
+	claims.Username, _ = mc["preferred_username"].(string)
+	claims.Email, _ = mc["email"].(string)
+	claims.Name, _ = mc["name"].(string)
+	if len(claims.Subject) == 0 {
+		log.Warning("no subject in the ID token")
+		return nil, ErrInvalidIDToken
+	}
+	if len(p.setting.GroupsClaim) > 0 {
+		claims.Groups = stringList(mc[p.setting.GroupsClaim])
+	}
+	return claims, nil
+}
+
+func hasAudience(aud interface{}, clientID string) bool {
+	for _, a := range stringList(aud) {
+		if a == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+// stringList converts the claim which is a string or a list of strings
+func stringList(v interface{}) []string {
+	switch value := v.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		list := []string{}
+		for _, e := range value {
+			if s, ok := e.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// AuthCodeURL returns the URL of the provider which the browser is
+// redirected to for logging in
+func AuthCodeURL(setting *models.OIDCSetting, redirectURI, state, nonce string) (string, error) {
+	p, err := getProvider(setting)
+	if err != nil {
+		return "", err
+	}
+	return p.authCodeURL(redirectURI, state, nonce)
+}
+
+// ExchangeToken redeems the authorization code and returns the verified
+// claims of the ID token
+func ExchangeToken(setting *models.OIDCSetting, redirectURI, code, nonce string) (*Claims, error) {
+	p, err := getProvider(setting)
+	if err != nil {
+		return nil, err
+	}
+	token, err := p.exchange(redirectURI, code)
+	if err != nil {
+		return nil, err
+	}
+	return p.verify(token.IDToken, nonce)
+}
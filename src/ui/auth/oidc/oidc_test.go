@@ -0,0 +1,194 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+const testKid = "test-key"
+
+type fakeProvider struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	nonce string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	p := &fakeProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&metadata{
+			Issuer:                p.URL,
+			AuthorizationEndpoint: p.URL + "/auth",
+			TokenEndpoint:         p.URL + "/token",
+			JWKSURI:               p.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		e := big.NewInt(int64(key.PublicKey.E)).Bytes()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []jsonWebKey{{
+				Kty: "RSA",
+				Kid: testKid,
+				N:   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(e),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "harbor" || secret != "secret" || r.PostFormValue("code") != "good-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(&Token{
+			AccessToken: "access",
+			TokenType:   "Bearer",
+			IDToken: p.sign(t, jwt.MapClaims{
+				"iss":                p.URL,
+				"sub":                "subject-1",
+				"aud":                "harbor",
+				"exp":                time.Now().Add(time.Minute).Unix(),
+				"nonce":              p.nonce,
+				"preferred_username": "alice",
+				"email":              "alice@example.com",
+				"groups":             []string{"dev", "ops"},
+			}),
+		})
+	})
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *fakeProvider) sign(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = testKid
+	s, err := token.SignedString(p.key)
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return s
+}
+
+func TestAuthCodeFlow(t *testing.T) {
+	fp := newFakeProvider(t)
+	defer fp.Close()
+	fp.nonce = "nonce-1"
+
+	setting := &models.OIDCSetting{
+		Endpoint:     fp.URL,
+		ClientID:     "harbor",
+		ClientSecret: "secret",
+		Scope:        []string{"openid", "email"},
+		GroupsClaim:  "groups",
+	}
+
+	u, err := AuthCodeURL(setting, "https://harbor/oidc/callback", "state-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("failed to get auth code URL: %v", err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatalf("failed to parse auth code URL: %v", err)
+	}
+	assert.Equal(t, "/auth", parsed.Path)
+	assert.Equal(t, "openid email", parsed.Query().Get("scope"))
+	assert.Equal(t, "state-1", parsed.Query().Get("state"))
+	assert.Equal(t, "harbor", parsed.Query().Get("client_id"))
+
+	claims, err := ExchangeToken(setting, "https://harbor/oidc/callback", "good-code", "nonce-1")
+	if err != nil {
+		t.Fatalf("failed to exchange token: %v", err)
+	}
+	assert.Equal(t, "subject-1", claims.Subject)
+	assert.Equal(t, "alice", claims.Username)
+	assert.Equal(t, fp.URL, claims.Issuer)
+	assert.Equal(t, []string{"dev", "ops"}, claims.Groups)
+
+	_, err = ExchangeToken(setting, "https://harbor/oidc/callback", "good-code", "another-nonce")
+	assert.Equal(t, ErrInvalidIDToken, err)
+
+	_, err = ExchangeToken(setting, "https://harbor/oidc/callback", "bad-code", "nonce-1")
+	assert.NotNil(t, err)
+}
+
+func TestVerify(t *testing.T) {
+	fp := newFakeProvider(t)
+	defer fp.Close()
+
+	p := newProvider(&models.OIDCSetting{
+		Endpoint: fp.URL,
+		ClientID: "harbor",
+	})
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":   fp.URL,
+			"sub":   "subject-1",
+			"aud":   []string{"other", "harbor"},
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"nonce": "n",
+		}
+	}
+
+	claims, err := p.verify(fp.sign(t, valid()), "n")
+	if err != nil {
+		t.Fatalf("failed to verify the ID token: %v", err)
+	}
+	assert.Equal(t, "subject-1", claims.Subject)
+
+	cases := map[string]func(jwt.MapClaims){
+		"issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil" },
+		"audience": func(c jwt.MapClaims) { c["aud"] = "other" },
+		"expired":  func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"no exp":   func(c jwt.MapClaims) { delete(c, "exp") },
+		"subject":  func(c jwt.MapClaims) { delete(c, "sub") },
+	}
+	for name, modify := range cases {
+		c := valid()
+		modify(c)
+		_, err := p.verify(fp.sign(t, c), "n")
+		assert.Equal(t, ErrInvalidIDToken, err, name)
+	}
+
+	// signed by an unknown key
+	fp.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	_, err = p.verify(fp.sign(t, valid()), "n")
+	assert.Equal(t, ErrInvalidIDToken, err)
+
+	// HMAC signed with the public key must be rejected
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, valid())
+	token.Header["kid"] = testKid
+	s, _ := token.SignedString([]byte("secret"))
+	_, err = p.verify(s, "n")
+	assert.Equal(t, ErrInvalidIDToken, err)
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
+)
+
+// ErrUserConflict is returned if the username or email of the user to be
+// onboarded has already been used by another user
+var ErrUserConflict = errors.New("the username or email has already been used by another user")
+
+// Auth implements Authenticator interface to authenticate the users onboarded
+// from the OpenID Connect provider with their CLI secrets, as docker clients
+// can not go through the login flow of the provider in browser.
+type Auth struct{}
+
+// Authenticate checks the CLI secret of the user
+func (a *Auth) Authenticate(m models.AuthModel) (*models.User, error) {
+	if len(m.Principal) == 0 || len(m.Password) == 0 {
+		return nil, nil
+	}
+	user, err := dao.GetUser(models.User{Username: m.Principal})
+	if err != nil || user == nil {
+		return nil, err
+	}
+	oidcUser, err := dao.GetOIDCUserByUserID(user.UserID)
+	if err != nil || oidcUser == nil || len(oidcUser.SecretHash) == 0 {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(m.Password)), []byte(oidcUser.SecretHash)) != 1 {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// GenerateCLISecret generates a new CLI secret for the user onboarded from
+// the provider, the previous one is invalidated. Only the hash is persisted,
+// so the secret is returned only once.
+func GenerateCLISecret(userID int) (string, error) {
+	oidcUser, err := dao.GetOIDCUserByUserID(userID)
+	if err != nil {
+		return "", err
+	}
+	if oidcUser == nil {
+		return "", fmt.Errorf("user %d is not onboarded from the OIDC provider", userID)
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	if err := dao.UpdateOIDCUserSecretHash(userID, hashSecret(secret)); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Onboard returns the user linked to the subject of the claims, the user is
+// created on the first login. An existing user who is not linked to the
+// subject is never taken over, an error is returned instead.
+func Onboard(claims *Claims) (*models.User, error) {
+	oidcUser, err := dao.GetOIDCUserBySubject(claims.Issuer, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if oidcUser != nil {
+		user, err := dao.GetUser(models.User{UserID: oidcUser.UserID})
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, fmt.Errorf("user %d linked to the subject %s has been deleted", oidcUser.UserID, claims.Subject)
+		}
+		return user, nil
+	}
+
+	user := models.User{
+		Username:   username(claims),
+		Email:      claims.Email,
+		Realname:   claims.Name,
+		Comment:    "from OIDC.",
+		AuthSource: common.OIDCAuth,
+	}
+	if len(user.Realname) == 0 {
+		user.Realname = user.Username
+	}
+	if len(user.Email) == 0 {
+		user.Email = user.Username + "@placeholder.com"
+	}
+
+	exist, err := dao.UserExists(user, "username")
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		log.Warningf("failed to onboard subject %s, username %s has already been used", claims.Subject, user.Username)
+		return nil, ErrUserConflict
+	}
+	exist, err = dao.UserExists(user, "email")
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		log.Warningf("failed to onboard subject %s, email %s has already been used", claims.Subject, user.Email)
+		return nil, ErrUserConflict
+	}
+
+	// the password is never used as the user logs in with the provider
+	user.Password = utils.GenerateRandomString()
+	userID, err := dao.Register(user)
+	if err != nil {
+		return nil, err
+	}
+	user.UserID = int(userID)
+	user.Password = ""
+
+	if _, err = dao.AddOIDCUser(&models.OIDCUser{
+		UserID:  user.UserID,
+		Issuer:  claims.Issuer,
+		Subject: claims.Subject,
+	}); err != nil {
+		return nil, err
+	}
+	log.Infof("user %s is onboarded from the OIDC provider", user.Username)
+	return &user, nil
+}
+
+// username returns the preferred username of the claims, the email or the
+// subject is used if it is absent
+func username(claims *Claims) string {
+	if len(claims.Username) > 0 {
+		return claims.Username
+	}
+	if i := strings.Index(claims.Email, "@"); i > 0 {
+		return claims.Email[:i]
+	}
+	return claims.Subject
+}
+
+func init() {
+	auth.Register(common.OIDCAuth, &Auth{})
+}
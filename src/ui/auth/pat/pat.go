@@ -0,0 +1,129 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pat implements the personal access tokens of users. They are minted
+// by the users for the scripts and docker clients and used as the password in
+// basic auth, so the passwords, which may be the LDAP ones, are not stored in
+// the clients.
+package pat
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	// Prefix marks the personal access tokens, so the passwords in basic
+	// auth are not looked up as tokens
+	Prefix = "hpat_"
+
+	maxNameLen = 255
+	tokenBytes = 32
+)
+
+var (
+	// ErrInvalidName is returned when the name is empty or too long
+	ErrInvalidName = errors.New("name should not be empty or longer than 255 characters")
+	// ErrInvalidScope is returned when the scope is neither read nor write
+	ErrInvalidScope = errors.New("scope should be read or write")
+	// ErrInvalidExpiration is returned when the expiration is in the past
+	ErrInvalidExpiration = errors.New("expires_at should be 0 or a time in the future")
+	// ErrNameExists is returned when the user has a token with the name
+	ErrNameExists = errors.New("a token with the name exists")
+)
+
+// Create mints a personal access token for the user. The token is returned
+// only here, as only its hash is persisted.
+func Create(userID int, name, scope string, expiresAt int64) (*models.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 || len(name) > maxNameLen {
+		return nil, "", ErrInvalidName
+	}
+	if scope != models.AccessTokenScopeRead && scope != models.AccessTokenScopeWrite {
+		return nil, "", ErrInvalidScope
+	}
+	if expiresAt < 0 || (expiresAt > 0 && expiresAt <= time.Now().Unix()) {
+		return nil, "", ErrInvalidExpiration
+	}
+	if dao.AccessTokenNameExists(userID, name) {
+		return nil, "", ErrNameExists
+	}
+
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	token := &models.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		TokenHash: hash(secret),
+		Scope:     scope,
+		ExpiresAt: expiresAt,
+	}
+	id, err := dao.AddAccessToken(token)
+	if err != nil {
+		return nil, "", err
+	}
+	token.ID = id
+	return token, secret, nil
+}
+
+// Authenticate returns the user and the token if the password in basic auth
+// is a valid personal access token of the user. Nil is returned if the
+// password is not a token, or the token is unknown, expired or minted by
+// another user.
+func Authenticate(username, password string) (*models.User, *models.AccessToken, error) {
+	if !strings.HasPrefix(password, Prefix) {
+		return nil, nil, nil
+	}
+	token, err := dao.GetAccessTokenByHash(hash(password))
+	if err != nil {
+		return nil, nil, err
+	}
+	if token == nil {
+		return nil, nil, nil
+	}
+	if token.Expired() {
+		log.Warningf("personal access token %d of user %d has expired", token.ID, token.UserID)
+		return nil, nil, nil
+	}
+	user, err := dao.GetUser(models.User{UserID: token.UserID})
+	if err != nil {
+		return nil, nil, err
+	}
+	if user == nil || user.Username != username {
+		log.Warningf("personal access token %d is not minted by %s", token.ID, username)
+		return nil, nil, nil
+	}
+	if err = dao.UpdateAccessTokenLastUsedTime(token.ID); err != nil {
+		log.Errorf("failed to update the last used time of personal access token %d: %v", token.ID, err)
+	}
+	return user, token, nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pat
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestCreateValidation(t *testing.T) {
+	_, _, err := Create(1, " ", models.AccessTokenScopeRead, 0)
+	assert.Equal(t, ErrInvalidName, err)
+
+	_, _, err = Create(1, strings.Repeat("a", maxNameLen+1), models.AccessTokenScopeRead, 0)
+	assert.Equal(t, ErrInvalidName, err)
+
+	_, _, err = Create(1, "ci", "admin", 0)
+	assert.Equal(t, ErrInvalidScope, err)
+
+	_, _, err = Create(1, "ci", models.AccessTokenScopeWrite, time.Now().Add(-time.Minute).Unix())
+	assert.Equal(t, ErrInvalidExpiration, err)
+}
+
+func TestAuthenticateSkipsPasswords(t *testing.T) {
+	user, token, err := Authenticate("admin", "Harbor12345")
+	assert.Nil(t, err)
+	assert.Nil(t, user)
+	assert.Nil(t, token)
+}
+
+func TestScope(t *testing.T) {
+	assert.True(t, (&models.AccessToken{Scope: models.AccessTokenScopeRead}).ReadOnly())
+	assert.False(t, (&models.AccessToken{Scope: models.AccessTokenScopeWrite}).ReadOnly())
+	assert.False(t, (&models.AccessToken{}).Expired())
+	assert.True(t, (&models.AccessToken{ExpiresAt: time.Now().Add(-time.Minute).Unix()}).Expired())
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	pwdhash "github.com/vmware/harbor/src/common/utils/password"
+	"github.com/vmware/harbor/src/ui/config"
+)
+
+const (
+	// PasswordExpiredSessionKey marks the session of the user whose password
+	// is expired, it can only access the APIs to change the password
+	PasswordExpiredSessionKey = "passwordExpired"
+	// PasswordExpiredHeader is set in the response to the login whose
+	// password is expired
+	PasswordExpiredHeader = "X-Harbor-Password-Expired"
+)
+
+// the character classes of the password policy
+var passwordClasses = map[string]func(rune) bool{
+	"lower": unicode.IsLower,
+	"upper": unicode.IsUpper,
+	"digit": unicode.IsDigit,
+	"special": func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+	},
+}
+
+// IsPasswordClass returns whether the class is a character class supported
+// by the password policy
+func IsPasswordClass(class string) bool {
+	_, ok := passwordClasses[class]
+	return ok
+}
+
+// ValidatePassword checks the new password of the user against the password
+// policy, the reuse of the last passwords is checked if the user ID is not 0.
+// The error describes the violation and can be shown to the user.
+func ValidatePassword(userID int, password string) error {
+	policy, err := config.PasswordPolicy()
+	if err != nil {
+		return err
+	}
+	if violation := checkPasswordPolicy(policy, password); violation != nil {
+		return &PolicyError{violation.Error()}
+	}
+
+	if userID == 0 || policy.History <= 0 {
+		return nil
+	}
+	history, err := dao.GetPasswordHistory(userID, policy.History)
+	if err != nil {
+		return err
+	}
+	for _, h := range history {
+		if match, _ := pwdhash.Verify(password, h.PasswordHash, ""); match {
+			return &PolicyError{fmt.Sprintf("password can not be one of the last %d passwords", policy.History)}
+		}
+	}
+	return nil
+}
+
+// PolicyError is returned when the password violates the password policy
+type PolicyError struct {
+	msg string
+}
+
+func (p *PolicyError) Error() string {
+	return p.msg
+}
+
+func checkPasswordPolicy(policy *models.PasswordPolicy, password string) error {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("password should contain at least %d characters", policy.MinLength)
+	}
+	missing := []string{}
+	for _, class := range policy.RequiredClasses {
+		in, ok := passwordClasses[class]
+		if !ok {
+			continue
+		}
+		if strings.IndexFunc(password, in) < 0 {
+			missing = append(missing, class)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("password should contain %s characters", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// PasswordExpired returns whether the password of the user logged in is
+// older than the max age of the password policy, only the passwords of the
+// users in the database expire
+func PasswordExpired(user *models.User) (bool, error) {
+	if user.AuthSource != common.DBAuth {
+		return false, nil
+	}
+	policy, err := config.PasswordPolicy()
+	if err != nil || policy.MaxAge <= 0 {
+		return false, err
+	}
+	changed, err := dao.GetPasswordChangeTime(user.UserID)
+	if err != nil {
+		return false, err
+	}
+	return time.Since(changed) > time.Duration(policy.MaxAge)*24*time.Hour, nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	policy := &models.PasswordPolicy{
+		MinLength:       8,
+		RequiredClasses: []string{"lower", "upper", "digit"},
+	}
+	cases := map[string]bool{
+		"Harbor12345": true,
+		"Harb123":     false,
+		"harbor12345": false,
+		"HARBOR12345": false,
+		"HarborHarbo": false,
+		"密码Harbor123": true,
+	}
+	for password, valid := range cases {
+		err := checkPasswordPolicy(policy, password)
+		assert.Equal(t, valid, err == nil, "%s: %v", password, err)
+	}
+
+	policy.RequiredClasses = []string{"special"}
+	assert.NotNil(t, checkPasswordPolicy(policy, "Harbor12345"))
+	assert.Nil(t, checkPasswordPolicy(policy, "Harbor-12345"))
+
+	// the unknown classes are ignored, they are rejected by the configuration API
+	policy.RequiredClasses = []string{"unknown"}
+	assert.Nil(t, checkPasswordPolicy(policy, "harborharbor"))
+
+	err := checkPasswordPolicy(&models.PasswordPolicy{
+		RequiredClasses: []string{"upper", "special"},
+	}, "harbor")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "password should contain upper, special characters", err.Error())
+	}
+}
+
+func TestIsPasswordClass(t *testing.T) {
+	for _, class := range []string{"lower", "upper", "digit", "special"} {
+		assert.True(t, IsPasswordClass(class))
+	}
+	assert.False(t, IsPasswordClass("symbol"))
+}
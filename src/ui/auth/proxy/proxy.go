@@ -0,0 +1,231 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
+	"github.com/vmware/harbor/src/ui/config"
+)
+
+const (
+	// UserHeader carries the name of the user authenticated by the proxy
+	UserHeader = "X-Forwarded-User"
+	// GroupsHeader carries the comma separated groups of the user
+	GroupsHeader = "X-Forwarded-Groups"
+	// SecretHeader carries the secret shared by the proxy and Harbor
+	SecretHeader = "X-Proxy-Secret"
+
+	maxUsernameLen = 255
+)
+
+// Auth implements Authenticator interface for the users authenticated by the
+// proxy. They have no password in Harbor, so the credentials are always
+// rejected.
+type Auth struct{}
+
+// Authenticate ...
+func (a *Auth) Authenticate(m models.AuthModel) (*models.User, error) {
+	return nil, nil
+}
+
+// syncedGroups records the groups which the project roles of each user are
+// synchronized with, so the roles are synchronized only if they change
+var syncedGroups = struct {
+	sync.Mutex
+	groups map[int]string
+}{
+	groups: make(map[int]string),
+}
+
+// UserFromRequest returns the user in the headers set by the authenticating
+// proxy, the user is created on first sight. Nil is returned if the proxy
+// authentication is not enabled, the request is not from a trusted proxy or
+// it has no user header. The second value reports whether the project roles
+// of the user are changed according to the groups.
+func UserFromRequest(r *http.Request) (*models.User, bool, error) {
+	username := strings.TrimSpace(r.Header.Get(UserHeader))
+	if len(username) == 0 {
+		return nil, false, nil
+	}
+
+	enabled, err := enabled()
+	if err != nil || !enabled {
+		return nil, false, err
+	}
+	setting, err := config.ProxyAuth()
+	if err != nil {
+		return nil, false, err
+	}
+	if !Trusted(r, setting) {
+		log.Warningf("the header %s from untrusted address %s is ignored", UserHeader, clientIP(r))
+		return nil, false, nil
+	}
+
+	user, err := onboard(username)
+	if err != nil || user == nil {
+		return nil, false, err
+	}
+
+	changed, err := syncGroups(user, r.Header.Get(GroupsHeader), setting.GroupRoleMapping)
+	if err != nil {
+		log.Errorf("failed to sync the project roles of user %s: %v", user.Username, err)
+	}
+	return user, changed, nil
+}
+
+func enabled() (bool, error) {
+	chain, err := config.AuthChain()
+	if err != nil {
+		return false, err
+	}
+	for _, mode := range chain {
+		if mode == common.ProxyAuth {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Trusted returns whether the request is sent by a trusted proxy, i.e. it
+// comes from the trusted CIDRs or carries the shared secret
+func Trusted(r *http.Request, setting *models.ProxyAuthSetting) bool {
+	if len(setting.Secret) > 0 {
+		secret := r.Header.Get(SecretHeader)
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(setting.Secret)) == 1 {
+			return true
+		}
+	}
+
+	ip := net.ParseIP(clientIP(r))
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range setting.TrustedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Warningf("invalid trusted CIDR %s: %v", cidr, err)
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the peer connecting to the nginx in front
+// of Harbor, which sets it in X-Real-IP
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); len(ip) > 0 {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// onboard returns the user of the username, it is created if it does not
+// exist. An existing user authenticated by another auth mode is never taken
+// over and the admin is never trusted from the headers.
+func onboard(username string) (*models.User, error) {
+	if username == "admin" || len(username) > maxUsernameLen {
+		log.Warningf("the user %s from the proxy is rejected", username)
+		return nil, nil
+	}
+
+	user, err := dao.GetUser(models.User{Username: username})
+	if err != nil {
+		return nil, err
+	}
+	if user != nil {
+		// the users whose auth source has not been backfilled are foreign too
+		if user.AuthSource != common.ProxyAuth {
+			log.Warningf("the user %s from the proxy is rejected as it is authenticated by %q", username, user.AuthSource)
+			return nil, nil
+		}
+		return user, nil
+	}
+
+	u := models.User{
+		Username:   username,
+		Email:      username + "@placeholder.com",
+		Realname:   username,
+		Comment:    "from proxy.",
+		AuthSource: common.ProxyAuth,
+		// the password is never used as the user is authenticated by the proxy
+		Password: utils.GenerateRandomString(),
+	}
+	userID, err := dao.Register(u)
+	if err != nil {
+		return nil, err
+	}
+	log.Infof("user %s from the proxy is created", username)
+	return dao.GetUser(models.User{UserID: int(userID)})
+}
+
+// syncGroups makes the project roles of the user consistent with the groups
+// if they are changed since the last synchronization
+func syncGroups(user *models.User, header, mapping string) (bool, error) {
+	mappings, err := auth.ParseGroupRoleMapping(mapping)
+	if err != nil || len(mappings) == 0 {
+		return false, err
+	}
+
+	groups := []string{}
+	for _, g := range strings.Split(header, ",") {
+		if g = strings.TrimSpace(g); len(g) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	sort.Strings(groups)
+	key := mapping + "|" + strings.Join(groups, ",")
+
+	syncedGroups.Lock()
+	synced := syncedGroups.groups[user.UserID] == key
+	syncedGroups.Unlock()
+	if synced {
+		return false, nil
+	}
+
+	changed, err := auth.SyncProjectRoles(user.UserID, auth.ProjectRoles(mappings, groups))
+	if err != nil {
+		return len(changed) > 0, err
+	}
+	syncedGroups.Lock()
+	syncedGroups.groups[user.UserID] = key
+	syncedGroups.Unlock()
+	if len(changed) > 0 {
+		log.Infof("the roles of user %s on projects %v are synchronized with the groups", user.Username, changed)
+	}
+	return len(changed) > 0, nil
+}
+
+func init() {
+	auth.Register(common.ProxyAuth, &Auth{})
+}
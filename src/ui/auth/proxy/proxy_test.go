@@ -0,0 +1,66 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestTrusted(t *testing.T) {
+	setting := &models.ProxyAuthSetting{
+		TrustedCIDRs: []string{"10.0.0.0/8", "invalid"},
+		Secret:       "secret",
+	}
+
+	cases := []struct {
+		remoteAddr string
+		headers    map[string]string
+		trusted    bool
+	}{
+		{"10.1.2.3:5000", nil, true},
+		{"192.168.0.1:5000", nil, false},
+		{"192.168.0.1:5000", map[string]string{"X-Real-IP": "10.1.2.3"}, true},
+		{"10.1.2.3:5000", map[string]string{"X-Real-IP": "192.168.0.1"}, false},
+		{"192.168.0.1:5000", map[string]string{SecretHeader: "secret"}, true},
+		{"192.168.0.1:5000", map[string]string{SecretHeader: "wrong"}, false},
+		{"invalid", nil, false},
+	}
+
+	for _, c := range cases {
+		req, err := http.NewRequest(http.MethodGet, "/api/users/current", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.RemoteAddr = c.remoteAddr
+		for k, v := range c.headers {
+			req.Header.Set(k, v)
+		}
+		assert.Equal(t, c.trusted, Trusted(req, setting), "%s %v", c.remoteAddr, c.headers)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/users/current", nil)
+	req.RemoteAddr = "192.168.0.1:5000"
+	assert.False(t, Trusted(req, &models.ProxyAuthSetting{}))
+}
+
+func TestOnboardRejectsAdmin(t *testing.T) {
+	user, err := onboard("admin")
+	assert.Nil(t, err)
+	assert.Nil(t, user)
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package robot implements the robot accounts of projects. They are used by
+// the automation such as CI pipelines to pull and push the repositories of a
+// project with a generated name and secret, rather than the credentials of a
+// user.
+package robot
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	// NamePrefix is the prefix of the names of robot accounts, as "$" is not
+	// allowed in the names of users, they never collide
+	NamePrefix = "robot$"
+
+	nameSuffixBytes = 4
+	secretBytes     = 32
+)
+
+var (
+	// ErrInvalidAccess is returned when the access is neither pull nor push
+	ErrInvalidAccess = errors.New("access should be pull or push")
+	// ErrInvalidExpiration is returned when the expiration is in the past
+	ErrInvalidExpiration = errors.New("expires_at should be 0 or a time in the future")
+)
+
+// IsRobot returns whether the name is the one of a robot account
+func IsRobot(name string) bool {
+	return strings.HasPrefix(name, NamePrefix)
+}
+
+// Create generates the name and the secret of a new robot account of the
+// project. The secret is returned only here, as only its hash is persisted.
+func Create(project *models.Project, creatorID int, description, access string,
+	expiresAt int64) (*models.Robot, string, error) {
+	if access != models.RobotAccessPull && access != models.RobotAccessPush {
+		return nil, "", ErrInvalidAccess
+	}
+	if expiresAt < 0 || (expiresAt > 0 && expiresAt <= time.Now().Unix()) {
+		return nil, "", ErrInvalidExpiration
+	}
+
+	suffix, err := random(nameSuffixBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := random(secretBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	secretStr := base64.RawURLEncoding.EncodeToString(secret)
+
+	robot := &models.Robot{
+		Name:        NamePrefix + project.Name + "+" + hex.EncodeToString(suffix),
+		Description: description,
+		ProjectID:   project.ProjectID,
+		SecretHash:  hash(secretStr),
+		Access:      access,
+		CreatorID:   creatorID,
+		ExpiresAt:   expiresAt,
+	}
+	id, err := dao.AddRobot(robot)
+	if err != nil {
+		return nil, "", err
+	}
+	robot.ID = id
+	return robot, secretStr, nil
+}
+
+// Authenticate returns the robot account whose name and secret are provided.
+// Nil is returned if they do not match, or the robot account is revoked or
+// expired.
+func Authenticate(name, secret string) (*models.Robot, error) {
+	if !IsRobot(name) || len(secret) == 0 {
+		return nil, nil
+	}
+	robot, err := dao.GetRobotByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if robot == nil ||
+		subtle.ConstantTimeCompare([]byte(robot.SecretHash), []byte(hash(secret))) != 1 {
+		log.Warningf("invalid credentials of robot account %s", name)
+		return nil, nil
+	}
+	if robot.Revoked {
+		log.Warningf("robot account %s is revoked", name)
+		return nil, nil
+	}
+	if robot.Expired() {
+		log.Warningf("robot account %s has expired", name)
+		return nil, nil
+	}
+	return robot, nil
+}
+
+// Permission returns the permission of the robot account on the project in
+// the form of the project roles, it is empty if the robot account does not
+// belong to the project
+func Permission(robot *models.Robot, projectID int64) string {
+	if robot.ProjectID != projectID {
+		return ""
+	}
+	if robot.Access == models.RobotAccessPush {
+		return "RW"
+	}
+	return "R"
+}
+
+// Owner returns the name of the user who created the robot account, the
+// repositories pushed by the robot account are owned by the user
+func Owner(name string) (string, error) {
+	robot, err := dao.GetRobotByName(name)
+	if err != nil || robot == nil {
+		return "", err
+	}
+	user, err := dao.GetUser(models.User{UserID: robot.CreatorID})
+	if err != nil || user == nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+func random(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
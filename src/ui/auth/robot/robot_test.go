@@ -0,0 +1,62 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package robot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestIsRobot(t *testing.T) {
+	assert.True(t, IsRobot("robot$library+0a1b2c3d"))
+	assert.False(t, IsRobot("admin"))
+	assert.False(t, IsRobot("robot"))
+}
+
+func TestCreateValidation(t *testing.T) {
+	project := &models.Project{ProjectID: 1, Name: "library"}
+
+	_, _, err := Create(project, 1, "", "delete", 0)
+	assert.Equal(t, ErrInvalidAccess, err)
+
+	_, _, err = Create(project, 1, "", models.RobotAccessPull, -1)
+	assert.Equal(t, ErrInvalidExpiration, err)
+
+	_, _, err = Create(project, 1, "", models.RobotAccessPush, time.Now().Add(-time.Hour).Unix())
+	assert.Equal(t, ErrInvalidExpiration, err)
+}
+
+func TestPermission(t *testing.T) {
+	pull := &models.Robot{ProjectID: 1, Access: models.RobotAccessPull}
+	push := &models.Robot{ProjectID: 1, Access: models.RobotAccessPush}
+
+	assert.Equal(t, "R", Permission(pull, 1))
+	assert.Equal(t, "RW", Permission(push, 1))
+	assert.Equal(t, "", Permission(push, 2))
+}
+
+func TestExpired(t *testing.T) {
+	assert.False(t, (&models.Robot{}).Expired())
+	assert.False(t, (&models.Robot{ExpiresAt: time.Now().Add(time.Hour).Unix()}).Expired())
+	assert.True(t, (&models.Robot{ExpiresAt: time.Now().Add(-time.Hour).Unix()}).Expired())
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, 64, len(hash("secret")))
+	assert.NotEqual(t, hash("secret"), hash("Secret"))
+}
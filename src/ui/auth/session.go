@@ -0,0 +1,127 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/astaxie/beego"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+// the last seen time of a session is updated at most once in the interval,
+// so the requests do not write the database every time
+const lastSeenInterval = time.Minute
+
+// TrackSession records the web session created by the login of the user, so
+// it can be listed and revoked. The sessions which are not tracked are not
+// accepted by the API.
+func TrackSession(sid string, userID int, ip, userAgent string) error {
+	if _, err := dao.DeleteStaleUserSessions(time.Now().Add(-sessionLifetime() - lastSeenInterval)); err != nil {
+		log.Errorf("failed to delete the stale sessions: %v", err)
+	}
+	_, err := dao.AddUserSession(&models.UserSession{
+		UserID:      userID,
+		SessionHash: hashSessionID(sid),
+		IP:          ip,
+		UserAgent:   userAgent,
+	})
+	return err
+}
+
+// SessionValid returns whether the session is tracked for the user, i.e. it
+// is neither revoked nor stale. The last seen time of the session is updated.
+func SessionValid(sid string, userID int) (bool, error) {
+	session, err := dao.GetUserSessionByHash(hashSessionID(sid))
+	if err != nil {
+		return false, err
+	}
+	if session == nil || session.UserID != userID {
+		return false, nil
+	}
+	now := time.Now()
+	if stale(session, now) {
+		return false, nil
+	}
+	if now.Sub(session.LastSeenTime) >= lastSeenInterval {
+		if err = dao.UpdateUserSessionLastSeenTime(session.ID); err != nil {
+			log.Errorf("failed to update the last seen time of session %d: %v", session.ID, err)
+		}
+	}
+	return true, nil
+}
+
+// ListSessions returns the sessions of the user which are not stale, the one
+// whose ID is sid is marked as current
+func ListSessions(userID int, sid string) ([]*models.UserSession, error) {
+	sessions, err := dao.GetUserSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	current := hashSessionID(sid)
+	result := []*models.UserSession{}
+	for _, session := range sessions {
+		if stale(session, now) {
+			continue
+		}
+		session.Current = session.SessionHash == current
+		result = append(result, session)
+	}
+	return result, nil
+}
+
+// EndSession stops tracking the session when the user logs out
+func EndSession(sid string) error {
+	return dao.DeleteUserSessionByHash(hashSessionID(sid))
+}
+
+// RevokeSessions revokes the sessions of the user, except the one whose ID is
+// exceptSID if it is not empty. It should be called when the password of the
+// user is changed or the user is deleted.
+func RevokeSessions(userID int, exceptSID string) error {
+	exceptHash := ""
+	if len(exceptSID) > 0 {
+		exceptHash = hashSessionID(exceptSID)
+	}
+	n, err := dao.DeleteUserSessions(userID, exceptHash)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Infof("%d sessions of user %d are revoked", n, userID)
+	}
+	return nil
+}
+
+// sessionLifetime is the lifetime of the idle sessions configured in beego
+func sessionLifetime() time.Duration {
+	return time.Duration(beego.BConfig.WebConfig.Session.SessionGCMaxLifetime) * time.Second
+}
+
+// stale returns whether the session has expired in beego, the interval of
+// updating the last seen time is tolerated
+func stale(session *models.UserSession, now time.Time) bool {
+	return now.Sub(session.LastSeenTime) > sessionLifetime()+lastSeenInterval
+}
+
+func hashSessionID(sid string) string {
+	sum := sha256.Sum256([]byte(sid))
+	return hex.EncodeToString(sum[:])
+}
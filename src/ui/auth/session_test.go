@@ -0,0 +1,42 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common/models"
+)
+
+func TestStaleSession(t *testing.T) {
+	now := time.Now()
+	session := &models.UserSession{LastSeenTime: now}
+	assert.False(t, stale(session, now))
+
+	// the interval of updating the last seen time is tolerated
+	session.LastSeenTime = now.Add(-sessionLifetime())
+	assert.False(t, stale(session, now))
+
+	session.LastSeenTime = now.Add(-sessionLifetime() - lastSeenInterval - time.Second)
+	assert.True(t, stale(session, now))
+}
+
+func TestHashSessionID(t *testing.T) {
+	assert.Equal(t, 64, len(hashSessionID("sid")))
+	assert.Equal(t, hashSessionID("sid"), hashSessionID("sid"))
+	assert.NotEqual(t, hashSessionID("sid"), hashSessionID("sid2"))
+}
@@ -105,6 +105,33 @@ func AuthMode() (string, error) {
 	return cfg[common.AUTHMode].(string), nil
 }
 
+// AuthChain returns the ordered auth modes which are tried when a user logs
+// in, it contains only the auth mode if the chain is not configured
+func AuthChain() ([]string, error) {
+	cfg, err := mg.Get()
+	if err != nil {
+		return nil, err
+	}
+	s, _ := cfg[common.AUTHChain].(string)
+	chain := SplitList(s)
+	if len(chain) == 0 {
+		chain = append(chain, cfg[common.AUTHMode].(string))
+	}
+	return chain, nil
+}
+
+// SplitList splits the comma separated values, the empty ones are dropped
+func SplitList(s string) []string {
+	chain := []string{}
+	for _, mode := range strings.Split(s, ",") {
+		mode = strings.TrimSpace(mode)
+		if len(mode) > 0 {
+			chain = append(chain, mode)
+		}
+	}
+	return chain
+}
+
 // LDAP returns the setting of ldap server
 func LDAP() (*models.LDAP, error) {
 	cfg, err := mg.Get()
@@ -125,6 +152,106 @@ func LDAP() (*models.LDAP, error) {
 	return ldap, nil
 }
 
+// OIDC returns the setting of the OpenID Connect provider
+func OIDC() (*models.OIDCSetting, error) {
+	cfg, err := mg.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	oidc := &models.OIDCSetting{}
+	oidc.Endpoint, _ = cfg[common.OIDCEndpoint].(string)
+	oidc.ClientID, _ = cfg[common.OIDCClientID].(string)
+	oidc.ClientSecret, _ = cfg[common.OIDCClientSecret].(string)
+	scope, _ := cfg[common.OIDCScope].(string)
+	oidc.Scope = SplitList(scope)
+	oidc.GroupsClaim, _ = cfg[common.OIDCGroupsClaim].(string)
+	oidc.GroupRoleMapping, _ = cfg[common.OIDCGroupRoleMapping].(string)
+	oidc.VerifyCert, _ = cfg[common.OIDCVerifyCert].(bool)
+
+	return oidc, nil
+}
+
+// ProxyAuth returns the setting of the authenticating proxy
+func ProxyAuth() (*models.ProxyAuthSetting, error) {
+	cfg, err := mg.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := &models.ProxyAuthSetting{}
+	cidrs, _ := cfg[common.ProxyAuthTrustedCIDRs].(string)
+	proxy.TrustedCIDRs = SplitList(cidrs)
+	proxy.Secret, _ = cfg[common.ProxyAuthSecret].(string)
+	proxy.GroupRoleMapping, _ = cfg[common.ProxyAuthGroupRoleMapping].(string)
+	proxy.TokenService, _ = cfg[common.ProxyAuthTokenService].(bool)
+
+	return proxy, nil
+}
+
+// Lockout returns the setting of account lockout, the threshold is 0 if the
+// lockout is disabled
+func Lockout() (*models.LockoutSetting, error) {
+	cfg, err := mg.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	lockout := &models.LockoutSetting{}
+	threshold, _ := cfg[common.LockoutThreshold].(float64)
+	lockout.Threshold = int(threshold)
+	window, _ := cfg[common.LockoutWindow].(float64)
+	lockout.Window = int(window)
+	duration, _ := cfg[common.LockoutDuration].(float64)
+	lockout.Duration = int(duration)
+
+	return lockout, nil
+}
+
+// PasswordHashIterations returns the iterations of hashing the passwords of
+// the users in the database
+func PasswordHashIterations() (int, error) {
+	cfg, err := mg.Get()
+	if err != nil {
+		return 0, err
+	}
+	iterations, _ := cfg[common.PasswordHashIterations].(float64)
+	return int(iterations), nil
+}
+
+// MFAEnforceAdmin returns whether the system admins are required to log in
+// with the second factor
+func MFAEnforceAdmin() bool {
+	cfg, err := mg.Get()
+	if err != nil {
+		log.Errorf("Failed to get configuration, will return MFAEnforceAdmin == false")
+		return false
+	}
+	b, _ := cfg[common.MFAEnforceAdmin].(bool)
+	return b
+}
+
+// PasswordPolicy returns the policy of the passwords of the users in the
+// database
+func PasswordPolicy() (*models.PasswordPolicy, error) {
+	cfg, err := mg.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &models.PasswordPolicy{}
+	minLength, _ := cfg[common.PasswordMinLength].(float64)
+	policy.MinLength = int(minLength)
+	classes, _ := cfg[common.PasswordRequiredClasses].(string)
+	policy.RequiredClasses = SplitList(classes)
+	history, _ := cfg[common.PasswordHistory].(float64)
+	policy.History = int(history)
+	maxAge, _ := cfg[common.PasswordMaxAge].(float64)
+	policy.MaxAge = int(maxAge)
+
+	return policy, nil
+}
+
 // TokenExpiration returns the token expiration time (in minute)
 func TokenExpiration() (int, error) {
 	cfg, err := mg.Get()
@@ -285,6 +412,18 @@ func WithNotary() bool {
 	return cfg[common.WithNotary].(bool)
 }
 
+// TokenAuditAccessLog returns whether the access denied by the token service
+// is recorded in the access log too
+func TokenAuditAccessLog() bool {
+	cfg, err := mg.Get()
+	if err != nil {
+		log.Errorf("Failed to get configuration, will return TokenAuditAccessLog == false")
+		return false
+	}
+	b, _ := cfg[common.TokenAuditAccessLog].(bool)
+	return b
+}
+
 // AdmiralEndpoint returns the URL of admiral, if Harbor is not deployed with admiral it should return an empty string.
 func AdmiralEndpoint() string {
 	cfg, err := mg.Get()
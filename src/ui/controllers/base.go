@@ -31,6 +31,7 @@ import (
 	email_util "github.com/vmware/harbor/src/common/utils/email"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/ui/auth"
+	"github.com/vmware/harbor/src/ui/auth/mfa"
 	"github.com/vmware/harbor/src/ui/config"
 )
 
@@ -69,12 +70,72 @@ func (cc *CommonController) Login() {
 		cc.CustomAbort(http.StatusUnauthorized, "")
 	}
 
-	cc.SetSession("userId", user.UserID)
-	cc.SetSession("username", user.Username)
+	enabled, err := mfa.Enabled(user.UserID)
+	if err != nil {
+		log.Errorf("Error occurred in checking the second factor of %s: %v", user.Username, err)
+		cc.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if enabled {
+		otp := cc.GetString("otp")
+		if len(otp) == 0 {
+			cc.Ctx.ResponseWriter.Header().Set(mfa.Header, mfa.HeaderRequired)
+			cc.CustomAbort(http.StatusUnauthorized, "")
+		}
+		valid, err := mfa.Verify(user.UserID, otp)
+		if err != nil {
+			log.Errorf("Error occurred in verifying the second factor of %s: %v", user.Username, err)
+			cc.CustomAbort(http.StatusInternalServerError, "")
+		}
+		if !valid {
+			auth.RecordFailure(principal)
+			cc.Ctx.ResponseWriter.Header().Set(mfa.Header, mfa.HeaderRequired)
+			cc.CustomAbort(http.StatusUnauthorized, "")
+		}
+	}
+	auth.ClearFailures(principal)
+
+	startSession(&cc.Controller, user)
+
+	required, err := mfa.EnrollmentRequired(user)
+	if err != nil {
+		log.Errorf("Error occurred in checking the enrollment of %s: %v", user.Username, err)
+		cc.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if required {
+		cc.SetSession(mfa.SessionKey, true)
+		cc.Ctx.ResponseWriter.Header().Set(mfa.Header, mfa.HeaderEnrollment)
+	}
+
+	expired, err := auth.PasswordExpired(user)
+	if err != nil {
+		log.Errorf("Error occurred in checking the password age of %s: %v", user.Username, err)
+		cc.CustomAbort(http.StatusInternalServerError, "")
+	}
+	if expired {
+		cc.SetSession(auth.PasswordExpiredSessionKey, true)
+		cc.Ctx.ResponseWriter.Header().Set(auth.PasswordExpiredHeader, "true")
+	}
+}
+
+// startSession starts a new web session for the user who logs in. The
+// session ID is regenerated, so the one used before the login is not reused,
+// and the session is tracked, so it can be listed and revoked.
+func startSession(c *beego.Controller, user *models.User) {
+	c.SessionRegenerateID()
+	if err := auth.TrackSession(c.CruSession.SessionID(), user.UserID,
+		c.Ctx.Input.IP(), c.Ctx.Request.UserAgent()); err != nil {
+		log.Errorf("Error occurred in tracking the session of %s: %v", user.Username, err)
+		c.CustomAbort(http.StatusInternalServerError, "")
+	}
+	c.SetSession("userId", user.UserID)
+	c.SetSession("username", user.Username)
 }
 
 // LogOut Habor UI
 func (cc *CommonController) LogOut() {
+	if err := auth.EndSession(cc.StartSession().SessionID()); err != nil {
+		log.Errorf("Error occurred in ending the tracked session: %v", err)
+	}
 	cc.DestroySession()
 }
 
@@ -202,12 +263,26 @@ func (cc *CommonController) ResetPassword() {
 	password := cc.GetString("password")
 
 	if password != "" {
+		if err = auth.ValidatePassword(user.UserID, password); err != nil {
+			if _, ok := err.(*auth.PolicyError); !ok {
+				log.Errorf("Error occurred in ValidatePassword: %v", err)
+				cc.CustomAbort(http.StatusInternalServerError, "Internal error.")
+			}
+			cc.CustomAbort(http.StatusBadRequest, err.Error())
+		}
 		user.Password = password
 		err = dao.ResetUserPassword(*user)
 		if err != nil {
 			log.Errorf("Error occurred in ResetUserPassword: %v", err)
 			cc.CustomAbort(http.StatusInternalServerError, "Internal error.")
 		}
+		auth.InvalidateCredentialCache(user.UserID)
+		if err = auth.RevokeSessions(user.UserID, ""); err != nil {
+			log.Errorf("Error occurred in revoking the sessions of user %d: %v", user.UserID, err)
+		}
+		if _, err = dao.DeleteRefreshTokensByUser(user.UserID); err != nil {
+			log.Errorf("Error occurred in revoking the refresh tokens of user %d: %v", user.UserID, err)
+		}
 	} else {
 		cc.CustomAbort(http.StatusBadRequest, "password_is_required")
 	}
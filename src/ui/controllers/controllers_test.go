@@ -16,10 +16,11 @@ package controllers
 import (
 	"net/http"
 	"net/http/httptest"
-	//"net/url"
+	"net/url"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"fmt"
 	"strings"
@@ -27,7 +28,12 @@ import (
 	"github.com/astaxie/beego"
 	//"github.com/dghubble/sling"
 	"github.com/stretchr/testify/assert"
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/common/utils/totp"
+	"github.com/vmware/harbor/src/ui/auth/mfa"
 	"github.com/vmware/harbor/src/ui/config"
 )
 
@@ -107,3 +113,83 @@ func TestMain(t *testing.T) {
 	assert.Equal(int(400), w.Code, "'/sendEmail' httpStatusCode should be 400")
 
 }
+
+// TestLoginLockoutWithInvalidOTP checks the failures of the second factor are
+// not cleared by the valid password, so the account is locked once the
+// invalid one-time passwords reach the threshold
+func TestLoginLockoutWithInvalidOTP(t *testing.T) {
+	assert := assert.New(t)
+
+	database, err := config.Database()
+	if err != nil {
+		t.Fatalf("failed to get database configuration: %v", err)
+	}
+	if err = dao.InitDatabase(database); err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	setting, err := config.Lockout()
+	if err != nil {
+		t.Fatalf("failed to get lockout setting: %v", err)
+	}
+
+	username := fmt.Sprintf("otp-lockout-%d", time.Now().Unix())
+	password := "Harbor12345"
+	id, err := dao.Register(models.User{
+		Username:   username,
+		Password:   password,
+		Email:      username + "@example.com",
+		Realname:   username,
+		AuthSource: common.DBAuth,
+	})
+	if err != nil {
+		t.Fatalf("failed to register user: %v", err)
+	}
+	user := &models.User{UserID: int(id), Username: username}
+	defer dao.DeleteUser(user.UserID)
+	defer dao.UnlockAccount(username)
+
+	secret, _, err := mfa.Enroll(user)
+	if err != nil {
+		t.Fatalf("failed to enroll the second factor: %v", err)
+	}
+	defer mfa.Disable(user.UserID)
+	code, err := totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	if _, _, err = mfa.Activate(user.UserID, code); err != nil {
+		t.Fatalf("failed to activate the second factor: %v", err)
+	}
+
+	invalid := "000000"
+	if code == invalid {
+		invalid = "111111"
+	}
+	login := func(otp string) int {
+		v := url.Values{}
+		v.Set("principal", username)
+		v.Set("password", password)
+		v.Set("otp", otp)
+		r, _ := http.NewRequest("POST", "/login", strings.NewReader(v.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		beego.BeeApp.Handlers.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < setting.Threshold; i++ {
+		assert.Equal(http.StatusUnauthorized, login(invalid), "login with invalid otp should fail")
+	}
+	locked, err := dao.IsAccountLocked(username)
+	if err != nil {
+		t.Fatalf("failed to check the lockout: %v", err)
+	}
+	assert.True(locked, "the account should be locked by the invalid one-time passwords")
+
+	// the valid code is refused as well once the account is locked
+	code, err = totp.Code(secret, time.Now())
+	if err != nil {
+		t.Fatalf("failed to generate code: %v", err)
+	}
+	assert.Equal(http.StatusUnauthorized, login(code), "login of locked account should fail")
+}
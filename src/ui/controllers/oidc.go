@@ -0,0 +1,156 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/astaxie/beego"
+	"github.com/vmware/harbor/src/common"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
+	"github.com/vmware/harbor/src/ui/auth/oidc"
+	"github.com/vmware/harbor/src/ui/config"
+	"github.com/vmware/harbor/src/ui/service/token"
+)
+
+const (
+	oidcCallbackPath = "/oidc/callback"
+	oidcStateKey     = "oidc_state"
+	oidcNonceKey     = "oidc_nonce"
+)
+
+// OIDCController handles the authorization code flow of OpenID Connect
+type OIDCController struct {
+	beego.Controller
+}
+
+// Prepare checks whether the OIDC authentication is enabled
+func (oc *OIDCController) Prepare() {
+	chain, err := config.AuthChain()
+	if err != nil {
+		log.Errorf("failed to get auth chain: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	for _, mode := range chain {
+		if mode == common.OIDCAuth {
+			return
+		}
+	}
+	oc.CustomAbort(http.StatusPreconditionFailed, "OIDC authentication is not enabled")
+}
+
+// RedirectLogin redirects the browser to the login page of the provider
+func (oc *OIDCController) RedirectLogin() {
+	setting, redirectURI := oc.setting()
+
+	state, err := randomString()
+	if err != nil {
+		log.Errorf("failed to generate the state: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	nonce, err := randomString()
+	if err != nil {
+		log.Errorf("failed to generate the nonce: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	oc.SetSession(oidcStateKey, state)
+	oc.SetSession(oidcNonceKey, nonce)
+
+	u, err := oidc.AuthCodeURL(setting, redirectURI, state, nonce)
+	if err != nil {
+		log.Errorf("failed to get the auth code URL of OIDC provider: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	oc.Redirect(u, http.StatusFound)
+}
+
+// Callback handles the redirection from the provider, the user is onboarded
+// on the first login and the project roles mapped from the groups are applied
+func (oc *OIDCController) Callback() {
+	state, _ := oc.GetSession(oidcStateKey).(string)
+	nonce, _ := oc.GetSession(oidcNonceKey).(string)
+	oc.DelSession(oidcStateKey)
+	oc.DelSession(oidcNonceKey)
+	if len(state) == 0 || oc.GetString("state") != state {
+		oc.CustomAbort(http.StatusBadRequest, "invalid state")
+	}
+	if e := oc.GetString("error"); len(e) > 0 {
+		log.Warningf("OIDC provider returned error: %s %s", e, oc.GetString("error_description"))
+		oc.CustomAbort(http.StatusUnauthorized, "")
+	}
+
+	setting, redirectURI := oc.setting()
+	claims, err := oidc.ExchangeToken(setting, redirectURI, oc.GetString("code"), nonce)
+	if err == oidc.ErrInvalidIDToken {
+		oc.CustomAbort(http.StatusUnauthorized, "")
+	}
+	if err != nil {
+		log.Errorf("failed to exchange the code with OIDC provider: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	user, err := oidc.Onboard(claims)
+	if err == oidc.ErrUserConflict {
+		oc.CustomAbort(http.StatusConflict, err.Error())
+	}
+	if err != nil {
+		log.Errorf("failed to onboard the user of subject %s: %v", claims.Subject, err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	mappings, err := auth.ParseGroupRoleMapping(setting.GroupRoleMapping)
+	if err != nil {
+		log.Errorf("invalid OIDC group role mapping: %v", err)
+	} else if len(mappings) > 0 {
+		changed, err := auth.SyncProjectRoles(user.UserID, auth.ProjectRoles(mappings, claims.Groups))
+		if err != nil {
+			log.Errorf("failed to sync the project roles of user %s: %v", user.Username, err)
+		}
+		if len(changed) > 0 {
+			log.Infof("the roles of user %s on projects %v are synchronized with the groups", user.Username, changed)
+			token.InvalidateUserPermissions(user.Username)
+		}
+	}
+
+	startSession(&oc.Controller, user)
+	oc.Redirect("/", http.StatusFound)
+}
+
+func (oc *OIDCController) setting() (*models.OIDCSetting, string) {
+	setting, err := config.OIDC()
+	if err != nil {
+		log.Errorf("failed to get OIDC configurations: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	endpoint, err := config.ExtEndpoint()
+	if err != nil {
+		log.Errorf("failed to get domain name: %v", err)
+		oc.CustomAbort(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+	return setting, strings.TrimRight(endpoint, "/") + oidcCallbackPath
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -20,15 +20,20 @@ import (
 
 	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
+	pwdhash "github.com/vmware/harbor/src/common/utils/password"
 
 	"github.com/astaxie/beego"
 	_ "github.com/astaxie/beego/session/redis"
 
+	"github.com/vmware/harbor/src/common"
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/ui/api"
+	"github.com/vmware/harbor/src/ui/auth"
 	_ "github.com/vmware/harbor/src/ui/auth/db"
 	_ "github.com/vmware/harbor/src/ui/auth/ldap"
+	_ "github.com/vmware/harbor/src/ui/auth/oidc"
+	_ "github.com/vmware/harbor/src/ui/auth/proxy"
 	"github.com/vmware/harbor/src/ui/config"
 	"github.com/vmware/harbor/src/ui/service/token"
 )
@@ -63,6 +68,27 @@ func updateInitPassword(userID int, password string) error {
 	return nil
 }
 
+// backfillAuthSource records the auth mode on the users created before the
+// auth chain was introduced, the logins of these users are refused until then
+// to avoid them being taken over by another backend.
+func backfillAuthSource() error {
+	mode, err := config.AuthMode()
+	if err != nil {
+		return err
+	}
+	if mode == "" {
+		mode = common.DBAuth
+	}
+	count, err := dao.BackfillUserAuthSource(mode)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Infof("%d users are recorded as authenticated by %s", count, mode)
+	}
+	return nil
+}
+
 func main() {
 	beego.BConfig.WebConfig.Session.SessionOn = true
 	//TODO
@@ -78,7 +104,13 @@ func main() {
 		log.Fatalf("failed to initialize configurations: %v", err)
 	}
 	log.Info("configurations initialization completed")
+	if err := auth.InitCredentialCache(redisURL); err != nil {
+		log.Fatalf("failed to initialize credential cache: %v", err)
+	}
 	token.InitCreators()
+	if err := token.InitKeySet(); err != nil {
+		log.Fatalf("failed to initialize the key set of token service: %v", err)
+	}
 	database, err := config.Database()
 	if err != nil {
 		log.Fatalf("failed to get database configuration: %v", err)
@@ -88,6 +120,12 @@ func main() {
 		log.Fatalf("failed to initialize database: %v", err)
 	}
 
+	iterations, err := config.PasswordHashIterations()
+	if err != nil {
+		log.Fatalf("failed to get the iterations of password hashing: %v", err)
+	}
+	pwdhash.SetDefault(pwdhash.NewPBKDF2(iterations))
+
 	password, err := config.InitialAdminPassword()
 	if err != nil {
 		log.Fatalf("failed to get admin's initia password: %v", err)
@@ -95,6 +133,9 @@ func main() {
 	if err := updateInitPassword(adminUserID, password); err != nil {
 		log.Error(err)
 	}
+	if err := backfillAuthSource(); err != nil {
+		log.Fatalf("failed to backfill the auth source of users: %v", err)
+	}
 	initRouters()
 	if err := api.SyncRegistry(); err != nil {
 		log.Error(err)
@@ -59,10 +59,14 @@ func initRouters() {
 	beego.Router("/reset", &controllers.CommonController{}, "post:ResetPassword")
 	beego.Router("/userExists", &controllers.CommonController{}, "post:UserExists")
 	beego.Router("/sendEmail", &controllers.CommonController{}, "get:SendEmail")
+	beego.Router("/oidc/login", &controllers.OIDCController{}, "get:RedirectLogin")
+	beego.Router("/oidc/callback", &controllers.OIDCController{}, "get:Callback")
 
 	//API:
 	beego.Router("/api/search", &api.SearchAPI{})
 	beego.Router("/api/projects/:pid([0-9]+)/members/?:mid", &api.ProjectMemberAPI{})
+	beego.Router("/api/projects/:pid([0-9]+)/robots", &api.RobotAPI{}, "get:Get;post:Post")
+	beego.Router("/api/projects/:pid([0-9]+)/robots/:id([0-9]+)", &api.RobotAPI{}, "get:Get;delete:Delete")
 	beego.Router("/api/projects/", &api.ProjectAPI{}, "get:List;post:Post;head:Head")
 	beego.Router("/api/projects/:id([0-9]+)", &api.ProjectAPI{})
 	beego.Router("/api/projects/:id([0-9]+)/publicity", &api.ProjectAPI{}, "put:ToggleProjectPublic")
@@ -79,10 +83,13 @@ func initRouters() {
 	beego.Router("/api/jobs/replication/", &api.RepJobAPI{}, "get:List")
 	beego.Router("/api/jobs/replication/:id([0-9]+)", &api.RepJobAPI{})
 	beego.Router("/api/jobs/replication/:id([0-9]+)/log", &api.RepJobAPI{}, "get:GetLog")
+	beego.Router("/api/jobs/replication/:id([0-9]+)/events", &api.RepJobAPI{}, "get:GetEvents")
 	beego.Router("/api/policies/replication/:id([0-9]+)", &api.RepPolicyAPI{})
 	beego.Router("/api/policies/replication", &api.RepPolicyAPI{}, "get:List")
 	beego.Router("/api/policies/replication", &api.RepPolicyAPI{}, "post:Post")
 	beego.Router("/api/policies/replication/:id([0-9]+)/enablement", &api.RepPolicyAPI{}, "put:UpdateEnablement")
+	beego.Router("/api/policies/replication/:id([0-9]+)/drift_reports", &api.DriftReportAPI{}, "get:List;post:Post")
+	beego.Router("/api/policies/replication/:id([0-9]+)/drift_reports/:rid([0-9]+)", &api.DriftReportAPI{}, "get:Get")
 	beego.Router("/api/targets/", &api.TargetAPI{}, "get:List")
 	beego.Router("/api/targets/", &api.TargetAPI{}, "post:Post")
 	beego.Router("/api/targets/:id([0-9]+)", &api.TargetAPI{})
@@ -90,6 +97,15 @@ func initRouters() {
 	beego.Router("/api/targets/ping", &api.TargetAPI{}, "post:Ping")
 	beego.Router("/api/targets/:id([0-9]+)/ping", &api.TargetAPI{}, "post:PingByID")
 	beego.Router("/api/users/:id/sysadmin", &api.UserAPI{}, "put:ToggleUserAdminRole")
+	beego.Router("/api/users/:id/refresh_tokens", &api.UserAPI{}, "get:ListRefreshTokens;delete:RevokeRefreshTokens")
+	beego.Router("/api/users/:id/cli_secret", &api.UserAPI{}, "post:GenerateCLISecret")
+	beego.Router("/api/users/:id/tokens", &api.UserAPI{}, "get:ListAccessTokens;post:CreateAccessToken")
+	beego.Router("/api/users/:id/tokens/:tid([0-9]+)", &api.UserAPI{}, "delete:RevokeAccessToken")
+	beego.Router("/api/users/:id/sessions", &api.UserAPI{}, "get:ListSessions;delete:RevokeSessions")
+	beego.Router("/api/users/:id/sessions/:sid([0-9]+)", &api.UserAPI{}, "delete:RevokeSession")
+	beego.Router("/api/users/:id/mfa", &api.UserAPI{}, "get:GetMFA;post:EnrollMFA;delete:DisableMFA")
+	beego.Router("/api/users/:id/mfa/activate", &api.UserAPI{}, "post:ActivateMFA")
+	beego.Router("/api/users/:id/mfa/recovery_codes", &api.UserAPI{}, "post:RegenerateRecoveryCodes")
 	beego.Router("/api/repositories/top", &api.RepositoryAPI{}, "get:GetTopRepos")
 	beego.Router("/api/logs", &api.LogAPI{})
 	beego.Router("/api/configurations", &api.ConfigAPI{})
@@ -98,6 +114,15 @@ func initRouters() {
 	beego.Router("/api/systeminfo", &api.SystemInfoAPI{}, "get:GetGeneralInfo")
 	beego.Router("/api/systeminfo/volumes", &api.SystemInfoAPI{}, "get:GetVolumeInfo")
 	beego.Router("/api/systeminfo/getcert", &api.SystemInfoAPI{}, "get:GetCert")
+	beego.Router("/api/systeminfo/registry_client/metrics", &api.SystemInfoAPI{}, "get:GetRegistryClientMetrics")
+	beego.Router("/api/systeminfo/permission_cache/metrics", &api.SystemInfoAPI{}, "get:GetPermissionCacheMetrics")
+	beego.Router("/api/token_audits", &api.TokenAuditAPI{}, "get:List")
+	beego.Router("/api/lockouts", &api.LockoutAPI{}, "get:List")
+	beego.Router("/api/lockouts/:username", &api.LockoutAPI{}, "delete:Delete")
+	beego.Router("/api/lockout_audits", &api.LockoutAPI{}, "get:ListAudits")
+	beego.Router("/api/token_keys", &api.TokenKeyAPI{}, "get:List;post:Post")
+	beego.Router("/api/token_keys/:id", &api.TokenKeyAPI{}, "delete:Delete")
+	beego.Router("/api/token_keys/:id/activate", &api.TokenKeyAPI{}, "post:Activate")
 	beego.Router("/api/ldap/ping", &api.LdapAPI{}, "post:Ping")
 	beego.Router("/api/ldap/users/search", &api.LdapAPI{}, "post:Search")
 	beego.Router("/api/ldap/users/import", &api.LdapAPI{}, "post:ImportUser")
@@ -106,6 +131,7 @@ func initRouters() {
 	//external service that hosted on harbor process:
 	beego.Router("/service/notifications", &service.NotificationHandler{})
 	beego.Router("/service/token", &token.Handler{})
+	beego.Router("/service/token/certs", &token.Handler{}, "get:GetCertBundle")
 
 	//Error pages
 	beego.ErrorController(&controllers.ErrorController{})
@@ -21,9 +21,9 @@ import (
 
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/models"
-	"github.com/vmware/harbor/src/common/utils"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/ui/api"
+	"github.com/vmware/harbor/src/ui/auth/robot"
 
 	"github.com/astaxie/beego"
 )
@@ -55,7 +55,7 @@ func (n *NotificationHandler) Post() {
 	for _, event := range events {
 		repository := event.Target.Repository
 
-		project, _ := utils.ParseRepository(repository)
+		project, _ := dao.ParseRepository(repository)
 		tag := event.Target.Tag
 		action := event.Action
 
@@ -76,7 +76,17 @@ func (n *NotificationHandler) Post() {
 					return
 				}
 				log.Debugf("Add repository %s into DB.", repository)
-				repoRecord := models.RepoRecord{Name: repository, OwnerName: user, ProjectName: project}
+				owner := user
+				if robot.IsRobot(user) {
+					// the robot accounts are not users, the repository is owned by the creator
+					o, err := robot.Owner(user)
+					if err != nil {
+						log.Errorf("Error happens when getting the owner of robot account %s: %v", user, err)
+						return
+					}
+					owner = o
+				}
+				repoRecord := models.RepoRecord{Name: repository, OwnerName: owner, ProjectName: project}
 				if err := dao.AddRepository(repoRecord); err != nil {
 					log.Errorf("Error happens when adding repository: %v", err)
 				}
@@ -0,0 +1,149 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution/registry/auth/token"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/config"
+)
+
+// copyAccess returns a deep copy of the access, it is used to keep the
+// requested actions before the access is filtered
+func copyAccess(access []*token.ResourceActions) []*token.ResourceActions {
+	c := make([]*token.ResourceActions, 0, len(access))
+	for _, a := range access {
+		c = append(c, &token.ResourceActions{
+			Type:    a.Type,
+			Name:    a.Name,
+			Actions: append([]string{}, a.Actions...),
+		})
+	}
+	return c
+}
+
+// deniedActions returns the requested actions which are not granted
+func deniedActions(requested, granted []string) []string {
+	denied := []string{}
+	for _, r := range requested {
+		found := false
+		for _, g := range granted {
+			if g == r || g == "*" {
+				found = true
+				break
+			}
+		}
+		if !found {
+			denied = append(denied, r)
+		}
+	}
+	return denied
+}
+
+// buildAudits returns the records of the requested access which is denied or
+// reduced, the requested and granted access must be in the same order
+func buildAudits(username, ip, service string, requested,
+	granted []*token.ResourceActions) []*models.TokenAudit {
+	audits := []*models.TokenAudit{}
+	for i, r := range requested {
+		if i >= len(granted) {
+			break
+		}
+		if len(deniedActions(r.Actions, granted[i].Actions)) == 0 {
+			continue
+		}
+		audits = append(audits, &models.TokenAudit{
+			Username:       username,
+			IP:             ip,
+			Service:        service,
+			Scope:          fmt.Sprintf("%s:%s:%s", r.Type, r.Name, strings.Join(r.Actions, ",")),
+			GrantedActions: strings.Join(granted[i].Actions, ","),
+		})
+	}
+	return audits
+}
+
+// auditAccess records the requested access which is denied or reduced by the
+// filters, and feeds the denied actions on repositories into the access log
+// if it is enabled
+func auditAccess(ip string, user *userInfo, service string, requested,
+	granted []*token.ResourceActions, filters map[string]accessFilter) {
+	audits := buildAudits(user.name, ip, service, requested, granted)
+	if len(audits) == 0 {
+		return
+	}
+
+	for _, audit := range audits {
+		log.Infof("access denied or reduced by token service: user: %s, ip: %s, service: %s, scope: %s, granted: [%s]",
+			audit.Username, audit.IP, audit.Service, audit.Scope, audit.GrantedActions)
+		if _, err := dao.AddTokenAudit(audit); err != nil {
+			log.Errorf("failed to add token audit: %v", err)
+		}
+	}
+
+	// the access log can only be recorded for users and existing projects
+	if len(user.name) == 0 || !config.TokenAuditAccessLog() {
+		return
+	}
+	filter, ok := filters["repository"].(*repositoryFilter)
+	if !ok {
+		return
+	}
+	for i, req := range requested {
+		if req.Type != "repository" || i >= len(granted) {
+			continue
+		}
+		denied := deniedActions(req.Actions, granted[i].Actions)
+		if len(denied) == 0 {
+			continue
+		}
+		img, err := filter.parser.parse(req.Name)
+		if err != nil {
+			continue
+		}
+		repository := img.namespace + "/" + img.repo
+		project, _ := dao.ParseRepository(repository)
+		exist, err := dao.ProjectExists(project)
+		if err != nil || !exist {
+			continue
+		}
+		for _, action := range denied {
+			if err := dao.AccessLog(user.name, project, repository,
+				"N/A", action+"_denied"); err != nil {
+				log.Errorf("failed to add access log: %v", err)
+			}
+		}
+	}
+}
+
+// clientIP returns the IP of the client, the header X-Real-IP is set by the
+// proxy in front of the UI
+func clientIP(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); len(ip) > 0 {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
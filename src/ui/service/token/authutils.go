@@ -25,6 +25,7 @@ import (
 
 	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth/robot"
 	"github.com/vmware/harbor/src/ui/config"
 
 	"github.com/docker/distribution/registry/auth/token"
@@ -103,18 +104,30 @@ func NotaryTokenForUI(username string, service string, scopes []string) (string,
 }
 
 // genTokenForUI is for the UI process to call, so it won't establish a https connection from UI to proxy.
+// The username can be the name of a robot account, the token is then limited
+// to the permission of the robot account.
 func genTokenForUI(username string, service string, scopes []string, filters map[string]accessFilter) (string, int, *time.Time, error) {
-	isAdmin, err := dao.IsAdminRole(username)
-	if err != nil {
-		return "", 0, nil, err
-	}
 	u := userInfo{
-		name:    username,
-		allPerm: isAdmin,
+		name: username,
+	}
+	if robot.IsRobot(username) {
+		rb, err := dao.GetRobotByName(username)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		if rb == nil || rb.Revoked || rb.Expired() {
+			return "", 0, nil, fmt.Errorf("robot account %s is invalid", username)
+		}
+		u.robot = rb
+	} else {
+		isAdmin, err := dao.IsAdminRole(username)
+		if err != nil {
+			return "", 0, nil, err
+		}
+		u.allPerm = isAdmin
 	}
 	access := GetResourceActions(scopes)
-	err = filterAccess(access, u, filters)
-	if err != nil {
+	if err := filterAccess(access, u, filters); err != nil {
 		return "", 0, nil, err
 	}
 	return MakeRawToken(username, service, access)
@@ -122,7 +135,7 @@ func genTokenForUI(username string, service string, scopes []string, filters map
 
 // MakeRawToken makes a valid jwt token based on parms.
 func MakeRawToken(username, service string, access []*token.ResourceActions) (token string, expiresIn int, issuedAt *time.Time, err error) {
-	pk, err := libtrust.LoadKeyFile(privateKey)
+	pk, err := signingKey()
 	if err != nil {
 		return "", 0, nil, err
 	}
@@ -140,9 +153,10 @@ func MakeRawToken(username, service string, access []*token.ResourceActions) (to
 }
 
 type tokenJSON struct {
-	Token     string `json:"token"`
-	ExpiresIn int    `json:"expires_in"`
-	IssuedAt  string `json:"issued_at"`
+	Token        string `json:"token"`
+	ExpiresIn    int    `json:"expires_in"`
+	IssuedAt     string `json:"issued_at"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 }
 
 func makeToken(username, service string, access []*token.ResourceActions) (*tokenJSON, error) {
@@ -150,7 +164,11 @@ func makeToken(username, service string, access []*token.ResourceActions) (*toke
 	if err != nil {
 		return nil, err
 	}
-	return &tokenJSON{raw, expires, issued.Format(time.RFC3339)}, nil
+	return &tokenJSON{
+		Token:     raw,
+		ExpiresIn: expires,
+		IssuedAt:  issued.Format(time.RFC3339),
+	}, nil
 }
 
 func permToActions(p string) []string {
@@ -18,7 +18,9 @@ import (
 	"fmt"
 	"github.com/docker/distribution/registry/auth/token"
 	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth/robot"
 	"github.com/vmware/harbor/src/ui/config"
 	"net/http"
 	"strings"
@@ -55,6 +57,8 @@ func InitCreators() {
 		}
 		creatorMap[notary] = &generalCreator{
 			validators: []ReqValidator{
+				&proxyAuthValidator{},
+				&robotValidator{},
 				&basicAuthValidator{},
 			},
 			service:   notary,
@@ -65,6 +69,8 @@ func InitCreators() {
 	creatorMap[registry] = &generalCreator{
 		validators: []ReqValidator{
 			&secretValidator{config.JobserviceSecret()},
+			&proxyAuthValidator{},
+			&robotValidator{},
 			&basicAuthValidator{},
 		},
 		service:   registry,
@@ -145,6 +151,9 @@ func (reg registryFilter) filter(user userInfo, a *token.ResourceActions) error
 	return nil
 }
 
+// parseRepository resolves the project of a repository, it is replaced in tests
+var parseRepository = dao.ParseRepository
+
 //repositoryFilter filters the access based on Harbor's permission model
 type repositoryFilter struct {
 	parser imageParser
@@ -156,9 +165,21 @@ func (rep repositoryFilter) filter(user userInfo, a *token.ResourceActions) erro
 	if err != nil {
 		return err
 	}
-	project := img.namespace
-	permission := ""
-	if user.allPerm {
+	// the project is the longest matching prefix of the repository
+	project, _ := parseRepository(img.namespace + "/" + img.repo)
+	permission, ok := permCache.get(user.name, project)
+	if ok {
+		a.Actions = permToActions(user.restrict(permission))
+		return nil
+	}
+	if user.robot != nil {
+		permission, err = robotPermission(user.robot, project)
+		if err != nil {
+			log.Errorf("Error occurred in getting the permission of robot account %s: %v", user.name, err)
+			//just leave empty permission
+			return nil
+		}
+	} else if user.allPerm {
 		exist, err := dao.ProjectExists(project)
 		if err != nil {
 			log.Errorf("Error occurred in CheckExistProject: %v", err)
@@ -181,10 +202,26 @@ func (rep repositoryFilter) filter(user userInfo, a *token.ResourceActions) erro
 			permission += "R"
 		}
 	}
-	a.Actions = permToActions(permission)
+	permCache.put(user.name, project, permission)
+	a.Actions = permToActions(user.restrict(permission))
 	return nil
 }
 
+// robotPermission returns the permission of the robot account on the
+// project, the public projects can be pulled by any robot account like the
+// anonymous users
+func robotPermission(rb *models.Robot, project string) (string, error) {
+	p, err := dao.GetProjectByName(project)
+	if err != nil || p == nil {
+		return "", err
+	}
+	permission := robot.Permission(rb, p.ProjectID)
+	if p.Public == 1 {
+		permission += "R"
+	}
+	return permission, nil
+}
+
 type generalCreator struct {
 	validators []ReqValidator
 	service    string
@@ -221,10 +258,38 @@ func (g generalCreator) Create(r *http.Request) (*tokenJSON, error) {
 		}
 		user = &userInfo{}
 	}
-	access := GetResourceActions(scopes)
-	err = filterAccess(access, *user, g.filterMap)
+	token, _, err := g.createForUser(r, user, scopes)
 	if err != nil {
 		return nil, err
 	}
-	return makeToken(user.name, g.service, access)
+
+	// docker login requests a refresh token with offline_token=true, which
+	// is stored as identity token and used in the OAuth2 token flow later
+	if r.URL.Query().Get("offline_token") == "true" && user.userID > 0 {
+		clientID := r.URL.Query().Get("client_id")
+		if len(clientID) == 0 {
+			log.Warningf("no client_id in the request of user %s, refresh token is not issued", user.name)
+			return token, nil
+		}
+		token.RefreshToken, err = issueRefreshToken(user.userID, clientID, g.service)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// createForUser filters the access requested by the scopes according to the
+// permission of the user and makes a token, the filtered access is returned too.
+// The access denied or reduced is audited.
+func (g generalCreator) createForUser(r *http.Request, user *userInfo, scopes []string) (*tokenJSON, []*token.ResourceActions, error) {
+	access := GetResourceActions(scopes)
+	requested := copyAccess(access)
+	err := filterAccess(access, *user, g.filterMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	go auditAccess(clientIP(r), user, g.service, requested, copyAccess(access), g.filterMap)
+	tk, err := makeToken(user.name, g.service, access)
+	return tk, access, err
 }
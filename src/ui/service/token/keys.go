@@ -0,0 +1,380 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/libtrust"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	keySuffix  = ".key"
+	certSuffix = ".crt"
+	// activeKeyFile records the ID of the key used to sign tokens
+	activeKeyFile = "active"
+	// bundleFile contains the certificates of all keys in the key set, it
+	// is configured as the root cert bundle of registry and Notary
+	bundleFile = "bundle.crt"
+)
+
+// keyDir is the directory of the key set. It contains one active signing key
+// and the previous ones which are kept for verifying the tokens issued before
+// the rotation. If it is empty, the tokens are signed by the legacy private key.
+var keyDir = "/etc/ui/keys"
+
+// certDir is the directory the cert bundle is published to, it is shared with
+// registry and Notary, which can not read the private keys in keyDir
+var certDir = "/etc/ui/certs"
+
+// keySize is the size of RSA keys generated
+var keySize = 4096
+
+var keyLock = &sync.Mutex{}
+
+// ErrKeyNotFound is returned when the key does not exist in the key set
+var ErrKeyNotFound = fmt.Errorf("key not found")
+
+// KeyInfo is the public information of a key in the key set, the ID is the
+// one set as "kid" in the header of JWT
+type KeyInfo struct {
+	ID           string    `json:"id"`
+	Active       bool      `json:"active"`
+	CreationTime time.Time `json:"creation_time"`
+}
+
+// signingKey returns the active key of the key set
+func signingKey() (libtrust.PrivateKey, error) {
+	id, err := activeKeyID()
+	if err != nil {
+		return nil, err
+	}
+	if len(id) == 0 {
+		return libtrust.LoadKeyFile(privateKey)
+	}
+	return libtrust.LoadKeyFile(filepath.Join(keyDir, id+keySuffix))
+}
+
+func activeKeyID() (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(keyDir, activeKeyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// ListKeys returns the keys in the key set, the newest first
+func ListKeys() ([]*KeyInfo, error) {
+	keyLock.Lock()
+	defer keyLock.Unlock()
+	return listKeys()
+}
+
+func listKeys() ([]*KeyInfo, error) {
+	active, err := activeKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := filepath.Glob(filepath.Join(keyDir, "*"+certSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*KeyInfo{}
+	for _, file := range files {
+		cert, err := loadCert(file)
+		if err != nil {
+			return nil, err
+		}
+		id := strings.TrimSuffix(filepath.Base(file), certSuffix)
+		keys = append(keys, &KeyInfo{
+			ID:           id,
+			Active:       id == active,
+			CreationTime: cert.NotBefore,
+		})
+	}
+
+	sort.Sort(keysByCreationTime(keys))
+	return keys, nil
+}
+
+// keysByCreationTime sorts the keys by creation time, the newest first
+type keysByCreationTime []*KeyInfo
+
+func (k keysByCreationTime) Len() int {
+	return len(k)
+}
+
+func (k keysByCreationTime) Less(i, j int) bool {
+	return k[i].CreationTime.After(k[j].CreationTime)
+}
+
+func (k keysByCreationTime) Swap(i, j int) {
+	k[i], k[j] = k[j], k[i]
+}
+
+// InitKeySet imports the legacy private key into the key set if it is empty
+// and publishes the cert bundle, so the bundle is ready for registry and
+// Notary before any token is issued
+func InitKeySet() error {
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	if err := importLegacyKey(); err != nil {
+		return err
+	}
+	return writeBundle()
+}
+
+// GenerateKey generates a key and adds it to the key set, the key becomes the
+// active one if activate is true. Otherwise it is only published in the cert
+// bundle, so it can be distributed to registry and Notary before the rotation.
+func GenerateKey(activate bool) (*KeyInfo, error) {
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	if err := importLegacyKey(); err != nil {
+		return nil, err
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, keySize)
+	if err != nil {
+		return nil, err
+	}
+	key, err := libtrust.FromCryptoPrivateKey(rsaKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := addKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if activate {
+		if err = setActiveKey(info.ID); err != nil {
+			return nil, err
+		}
+		info.Active = true
+		log.Infof("the signing key of token service is rotated to %s", info.ID)
+	}
+	return info, writeBundle()
+}
+
+// ActivateKey makes the key the one used to sign tokens, the previous active
+// key is kept for verification until it is deleted
+func ActivateKey(id string) error {
+	if !validKeyID(id) {
+		return ErrKeyNotFound
+	}
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	if _, err := os.Stat(filepath.Join(keyDir, id+keySuffix)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	if err := setActiveKey(id); err != nil {
+		return err
+	}
+	log.Infof("the signing key of token service is rotated to %s", id)
+	return nil
+}
+
+// DeleteKey removes a key from the key set, the tokens signed by it can not
+// be verified any more once the registry and Notary reload the cert bundle.
+// The active key can not be deleted.
+func DeleteKey(id string) error {
+	if !validKeyID(id) {
+		return ErrKeyNotFound
+	}
+	keyLock.Lock()
+	defer keyLock.Unlock()
+
+	active, err := activeKeyID()
+	if err != nil {
+		return err
+	}
+	if id == active {
+		return fmt.Errorf("the active key %s can not be deleted", id)
+	}
+
+	if err = os.Remove(filepath.Join(keyDir, id+keySuffix)); err != nil {
+		if os.IsNotExist(err) {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	if err = os.Remove(filepath.Join(keyDir, id+certSuffix)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return writeBundle()
+}
+
+// CertBundle returns the certificates of all keys in the key set
+func CertBundle() ([]byte, error) {
+	keyLock.Lock()
+	defer keyLock.Unlock()
+	return certBundle()
+}
+
+func certBundle() ([]byte, error) {
+	keys, err := listKeys()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	for _, key := range keys {
+		b, err := ioutil.ReadFile(filepath.Join(keyDir, key.ID+certSuffix))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+func writeBundle() error {
+	b, err := certBundle()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(certDir, 0755); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(certDir, bundleFile), b, 0644)
+}
+
+// importLegacyKey adds the legacy private key to the key set as the active
+// key if the key set is empty, so the tokens signed by it can be verified
+// during the rotation
+func importLegacyKey() error {
+	if err := os.MkdirAll(keyDir, 0700); err != nil {
+		return err
+	}
+	active, err := activeKeyID()
+	if err != nil {
+		return err
+	}
+	if len(active) != 0 {
+		return nil
+	}
+
+	key, err := libtrust.LoadKeyFile(privateKey)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	info, err := addKey(key)
+	if err != nil {
+		return err
+	}
+	log.Infof("the legacy signing key %s is imported into the key set", info.ID)
+	return setActiveKey(info.ID)
+}
+
+// addKey saves the key and its self-signed certificate
+func addKey(key libtrust.PrivateKey) (*KeyInfo, error) {
+	now := time.Now().UTC()
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName: issuer,
+		},
+		NotBefore:             now,
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template,
+		key.CryptoPublicKey(), key.CryptoPrivateKey())
+	if err != nil {
+		return nil, err
+	}
+
+	id := key.KeyID()
+	if err = libtrust.SaveKey(filepath.Join(keyDir, id+keySuffix), key); err != nil {
+		return nil, err
+	}
+	cert := pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: der,
+	})
+	if err = writeFile(filepath.Join(keyDir, id+certSuffix), cert, 0644); err != nil {
+		return nil, err
+	}
+	return &KeyInfo{
+		ID:           id,
+		CreationTime: now,
+	}, nil
+}
+
+// validKeyID guards against the IDs from requests pointing to files outside
+// of the key directory
+func validKeyID(id string) bool {
+	return len(id) != 0 && filepath.Base(id) == id && id != "." && id != ".."
+}
+
+func setActiveKey(id string) error {
+	return writeFile(filepath.Join(keyDir, activeKeyFile), []byte(id), 0600)
+}
+
+func loadCert(file string) (*x509.Certificate, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no certificate found in %s", file)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// writeFile writes to a temporary file first and then renames it, so the
+// other UI instances sharing the directory never read a partial file
+func writeFile(file string, data []byte, perm os.FileMode) error {
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
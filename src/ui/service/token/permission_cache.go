@@ -0,0 +1,194 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"sync"
+	"time"
+
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/utils/log"
+)
+
+const (
+	permissionCacheTTL  = 30 * time.Second
+	permissionCacheSize = 4096
+)
+
+// PermissionCacheMetrics is the statistics of the permission cache
+type PermissionCacheMetrics struct {
+	Hits     int64 `json:"hits"`
+	Misses   int64 `json:"misses"`
+	Entries  int   `json:"entries"`
+	Projects int   `json:"projects"`
+}
+
+// permissionCache holds the permissions of users on projects computed by
+// repositoryFilter, so the bursts of token requests sent by CI jobs do not
+// query the database for every scope. The entries are grouped by project,
+// so they can be invalidated by exact match when the membership, the
+// publicity or the existence of a project changes, and by user when the
+// role of a user changes. The TTL bounds the staleness on the other UI
+// instances.
+type permissionCache struct {
+	sync.Mutex
+	ttl  time.Duration
+	size int
+	// project name -> username -> entry
+	entries map[string]map[string]*permissionEntry
+	count   int
+	hits    int64
+	misses  int64
+}
+
+type permissionEntry struct {
+	permission string
+	expiration time.Time
+}
+
+var permCache = newPermissionCache(permissionCacheTTL, permissionCacheSize)
+
+func newPermissionCache(ttl time.Duration, size int) *permissionCache {
+	return &permissionCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[string]map[string]*permissionEntry),
+	}
+}
+
+func (c *permissionCache) get(username, project string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	entry, ok := c.entries[project][username]
+	if !ok || time.Now().After(entry.expiration) {
+		c.misses++
+		return "", false
+	}
+	c.hits++
+	return entry.permission, true
+}
+
+func (c *permissionCache) put(username, project, permission string) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.entries[project][username]; !ok {
+		if c.count >= c.size {
+			c.evict()
+		}
+		if c.entries[project] == nil {
+			c.entries[project] = make(map[string]*permissionEntry)
+		}
+		c.count++
+	}
+	c.entries[project][username] = &permissionEntry{
+		permission: permission,
+		expiration: time.Now().Add(c.ttl),
+	}
+}
+
+// evict removes the expired entries, if there is none, it removes the one
+// which expires first
+func (c *permissionCache) evict() {
+	now := time.Now()
+	var oldest *permissionEntry
+	var oldestProject, oldestUser string
+	for project, users := range c.entries {
+		for username, entry := range users {
+			if now.After(entry.expiration) {
+				c.remove(project, username)
+				continue
+			}
+			if oldest == nil || entry.expiration.Before(oldest.expiration) {
+				oldest, oldestProject, oldestUser = entry, project, username
+			}
+		}
+	}
+	if c.count >= c.size && oldest != nil {
+		c.remove(oldestProject, oldestUser)
+	}
+}
+
+// remove deletes the entry, the caller must hold the lock
+func (c *permissionCache) remove(project, username string) {
+	users, ok := c.entries[project]
+	if !ok {
+		return
+	}
+	if _, ok = users[username]; !ok {
+		return
+	}
+	delete(users, username)
+	c.count--
+	if len(users) == 0 {
+		delete(c.entries, project)
+	}
+}
+
+func (c *permissionCache) invalidateProjects(projects ...string) {
+	c.Lock()
+	defer c.Unlock()
+	for _, project := range projects {
+		c.count -= len(c.entries[project])
+		delete(c.entries, project)
+	}
+}
+
+func (c *permissionCache) invalidateUser(username string) {
+	c.Lock()
+	defer c.Unlock()
+	for project := range c.entries {
+		c.remove(project, username)
+	}
+}
+
+func (c *permissionCache) metrics() PermissionCacheMetrics {
+	c.Lock()
+	defer c.Unlock()
+	return PermissionCacheMetrics{
+		Hits:     c.hits,
+		Misses:   c.misses,
+		Entries:  c.count,
+		Projects: len(c.entries),
+	}
+}
+
+// InvalidateProjectPermissions drops the cached permissions of all users on
+// the project and its descendants, which inherit its members. It should be
+// called when the project is created or deleted, its publicity is toggled or
+// its members are changed. If the descendants can not be listed, their
+// entries are left to expire.
+func InvalidateProjectPermissions(project string) {
+	projects := []string{project}
+	children, err := dao.GetChildProjects(project)
+	if err != nil {
+		log.Errorf("failed to get the child projects of %s: %v", project, err)
+	}
+	for _, child := range children {
+		projects = append(projects, child.Name)
+	}
+	permCache.invalidateProjects(projects...)
+}
+
+// InvalidateUserPermissions drops the cached permissions of the user on all
+// projects, it should be called when the user is deleted or the admin role
+// of the user is toggled
+func InvalidateUserPermissions(username string) {
+	permCache.invalidateUser(username)
+}
+
+// GetPermissionCacheMetrics returns the hit/miss counters of the permission cache
+func GetPermissionCacheMetrics() PermissionCacheMetrics {
+	return permCache.metrics()
+}
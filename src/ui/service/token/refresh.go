@@ -0,0 +1,143 @@
+// Copyright (c) 2017 VMware, Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution/registry/auth/token"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
+	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
+)
+
+const (
+	grantTypePassword     = "password"
+	grantTypeRefreshToken = "refresh_token"
+	// refresh token is issued in password grant only if the access type is offline
+	accessTypeOffline = "offline"
+)
+
+// oauthTokenJSON is the response of the OAuth2 token request
+type oauthTokenJSON struct {
+	AccessToken  string `json:"access_token"`
+	Scope        string `json:"scope,omitempty"`
+	ExpiresIn    int    `json:"expires_in"`
+	IssuedAt     string `json:"issued_at"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// userCreator creates tokens for users authenticated by the OAuth2 grants
+type userCreator interface {
+	createForUser(r *http.Request, user *userInfo, scopes []string) (*tokenJSON, []*token.ResourceActions, error)
+}
+
+// issueRefreshToken generates a refresh token for the user and persists its hash
+func issueRefreshToken(userID int, clientID, service string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(b)
+
+	if _, err := dao.AddRefreshToken(&models.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ClientID:  clientID,
+		Service:   service,
+	}); err != nil {
+		return "", err
+	}
+	return refreshToken, nil
+}
+
+// validateRefreshToken returns the user the refresh token was issued to,
+// nil is returned if the token is invalid, revoked, issued to another client
+// or service, the user was deleted, or the user can not log in with the
+// password as the account is locked or the password has expired
+func validateRefreshToken(refreshToken, clientID, service string) (*models.User, error) {
+	if len(refreshToken) == 0 {
+		return nil, nil
+	}
+	rt, err := dao.GetRefreshTokenByHash(hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if rt == nil {
+		log.Warning("invalid or revoked refresh token")
+		return nil, nil
+	}
+	if rt.Service != service {
+		log.Warningf("the refresh token was issued for service %s rather than %s", rt.Service, service)
+		return nil, nil
+	}
+	if rt.ClientID != clientID {
+		log.Warningf("the refresh token was issued to client %s rather than %s", rt.ClientID, clientID)
+		return nil, nil
+	}
+
+	user, err := dao.GetUser(models.User{
+		UserID: rt.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		log.Warningf("the user %d which the refresh token was issued to does not exist", rt.UserID)
+		return nil, nil
+	}
+	if auth.IsLocked(user.Username) {
+		log.Warningf("%s is locked due to login failures, the refresh token is refused", user.Username)
+		return nil, nil
+	}
+	expired, err := auth.PasswordExpired(user)
+	if err != nil {
+		return nil, err
+	}
+	if expired {
+		log.Warningf("the password of %s has expired, the refresh token is refused", user.Username)
+		return nil, nil
+	}
+
+	if err = dao.UpdateRefreshTokenLastUsedTime(rt.ID); err != nil {
+		log.Errorf("failed to update the last used time of refresh token %d: %v", rt.ID, err)
+	}
+	return user, nil
+}
+
+func hashRefreshToken(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(sum[:])
+}
+
+// grantedScope converts the access granted to the scope string, the
+// resources without any action are omitted
+func grantedScope(access []*token.ResourceActions) string {
+	scopes := []string{}
+	for _, a := range access {
+		if len(a.Actions) == 0 {
+			continue
+		}
+		scopes = append(scopes, fmt.Sprintf("%s:%s:%s", a.Type, a.Name, strings.Join(a.Actions, ",")))
+	}
+	return strings.Join(scopes, " ")
+}
@@ -17,9 +17,13 @@ package token
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/astaxie/beego"
+	"github.com/vmware/harbor/src/common/dao"
+	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
+	"github.com/vmware/harbor/src/ui/auth"
 )
 
 // Handler handles request on /service/token, which is the auth provider for registry.
@@ -29,7 +33,8 @@ type Handler struct {
 
 // Get handles GET request, it checks the http header for user credentials
 // and parse service and scope based on docker registry v2 standard,
-// checkes the permission agains local DB and generates jwt token.
+// checkes the permission agains local DB and generates jwt token. A refresh
+// token is issued too if offline_token is true and the user logs in with password.
 func (h *Handler) Get() {
 	request := h.Ctx.Request
 	log.Debugf("URL for token request: %s", request.URL.String())
@@ -52,3 +57,100 @@ func (h *Handler) Get() {
 	h.ServeJSON()
 
 }
+
+// Post handles POST request, it implements the OAuth2 token flow supported by
+// docker client. The grant type can be "password" or "refresh_token", and a
+// refresh token is issued in password grant if the access type is "offline",
+// which can be stored by the client as identity token.
+func (h *Handler) Post() {
+	service := h.GetString("service")
+	tokenCreator, ok := creatorMap[service]
+	if !ok {
+		errMsg := fmt.Sprintf("Unable to handle service: %s", service)
+		log.Error(errMsg)
+		h.CustomAbort(http.StatusBadRequest, errMsg)
+	}
+	creator, ok := tokenCreator.(userCreator)
+	if !ok {
+		h.CustomAbort(http.StatusBadRequest, fmt.Sprintf("OAuth2 token flow is not supported by service: %s", service))
+	}
+
+	clientID := h.GetString("client_id")
+	if len(clientID) == 0 {
+		h.CustomAbort(http.StatusBadRequest, "client_id is required")
+	}
+
+	var user *models.User
+	var err error
+	refreshToken := ""
+	grantType := h.GetString("grant_type")
+	switch grantType {
+	case grantTypePassword:
+		user, err = auth.LoginCLI(models.AuthModel{
+			Principal: h.GetString("username"),
+			Password:  h.GetString("password"),
+		})
+		if err != nil {
+			log.Errorf("Error occurred in UserLogin: %v", err)
+			h.CustomAbort(http.StatusInternalServerError, "")
+		}
+		if user == nil {
+			log.Warningf("Invalid credentials for uid: %s", h.GetString("username"))
+			h.CustomAbort(http.StatusUnauthorized, "")
+		}
+		if h.GetString("access_type") == accessTypeOffline {
+			refreshToken, err = issueRefreshToken(user.UserID, clientID, service)
+			if err != nil {
+				log.Errorf("failed to issue refresh token for user %s: %v", user.Username, err)
+				h.CustomAbort(http.StatusInternalServerError, "")
+			}
+		}
+	case grantTypeRefreshToken:
+		refreshToken = h.GetString("refresh_token")
+		user, err = validateRefreshToken(refreshToken, clientID, service)
+		if err != nil {
+			log.Errorf("failed to validate refresh token: %v", err)
+			h.CustomAbort(http.StatusInternalServerError, "")
+		}
+		if user == nil {
+			h.CustomAbort(http.StatusUnauthorized, "")
+		}
+	default:
+		h.CustomAbort(http.StatusBadRequest, fmt.Sprintf("unsupported grant_type: %s", grantType))
+	}
+
+	isAdmin, err := dao.IsAdminRole(user.UserID)
+	if err != nil {
+		log.Errorf("Error occurred in IsAdminRole: %v", err)
+	}
+	token, access, err := creator.createForUser(h.Ctx.Request, &userInfo{
+		name:    user.Username,
+		allPerm: isAdmin,
+	}, strings.Fields(h.GetString("scope")))
+	if err != nil {
+		log.Errorf("Unexpected error when creating the token, error: %v", err)
+		h.CustomAbort(http.StatusInternalServerError, "")
+	}
+
+	h.Data["json"] = &oauthTokenJSON{
+		AccessToken:  token.Token,
+		Scope:        grantedScope(access),
+		ExpiresIn:    token.ExpiresIn,
+		IssuedAt:     token.IssuedAt,
+		RefreshToken: refreshToken,
+	}
+	h.ServeJSON()
+}
+
+// GetCertBundle serves the certificates of all keys in the key set of token
+// service, the registry and Notary should trust all of them to verify the
+// tokens issued before and after a rotation
+func (h *Handler) GetCertBundle() {
+	bundle, err := CertBundle()
+	if err != nil {
+		log.Errorf("failed to get cert bundle: %v", err)
+		h.CustomAbort(http.StatusInternalServerError, "")
+	}
+	h.Ctx.ResponseWriter.Header().Set(http.CanonicalHeaderKey("Content-Type"), "application/x-pem-file")
+	h.Ctx.ResponseWriter.Write(bundle)
+}
@@ -22,13 +22,17 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"github.com/vmware/harbor/src/common/dao"
 	"github.com/vmware/harbor/src/common/utils/test"
 	"github.com/vmware/harbor/src/ui/config"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"path"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMain(m *testing.M) {
@@ -206,8 +210,8 @@ func TestFilterAccess(t *testing.T) {
 	a1 := GetResourceActions(s)
 	a2 := GetResourceActions(s)
 	a3 := GetResourceActions(s)
-	u1 := userInfo{"jack", true}
-	u2 := userInfo{"jack", false}
+	u1 := userInfo{name: "jack", allPerm: true}
+	u2 := userInfo{name: "jack", allPerm: false}
 	ra1 := token.ResourceActions{
 		Type:    "registry",
 		Name:    "catalog",
@@ -228,3 +232,247 @@ func TestFilterAccess(t *testing.T) {
 	assert.Nil(t, err, "Unexpected error: %v", err)
 	assert.Equal(t, ra2, *a3[0], "Mismatch after registry filter Map")
 }
+
+func TestGrantedScope(t *testing.T) {
+	access := []*token.ResourceActions{
+		{
+			Type:    "repository",
+			Name:    "library/hello-world",
+			Actions: []string{"push", "pull"},
+		},
+		{
+			Type:    "registry",
+			Name:    "catalog",
+			Actions: []string{},
+		},
+	}
+	assert.Equal(t, "repository:library/hello-world:push,pull", grantedScope(access))
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token_keys")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pk, _ := getKeyAndCertPath()
+	privateKey = pk
+	origKeyDir, origCertDir, origKeySize := keyDir, certDir, keySize
+	keyDir, certDir, keySize = path.Join(dir, "keys"), path.Join(dir, "certs"), 2048
+	defer func() {
+		keyDir, certDir, keySize = origKeyDir, origCertDir, origKeySize
+	}()
+
+	if err = InitKeySet(); err != nil {
+		t.Fatalf("failed to initialize key set: %v", err)
+	}
+	published, err := ioutil.ReadFile(path.Join(certDir, bundleFile))
+	if err != nil {
+		t.Fatalf("failed to read the published cert bundle: %v", err)
+	}
+	assert.Equal(t, 1, strings.Count(string(published), "BEGIN CERTIFICATE"),
+		"the legacy key should be published in the bundle")
+
+	standby, err := GenerateKey(false)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	assert.False(t, standby.Active)
+	legacy, err := activeKeyID()
+	if err != nil {
+		t.Fatalf("failed to get active key: %v", err)
+	}
+	assert.NotEmpty(t, legacy, "the legacy key should be imported as the active key")
+
+	active, err := GenerateKey(true)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	assert.True(t, active.Active)
+
+	keys, err := ListKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	assert.Equal(t, 3, len(keys))
+	for _, key := range keys {
+		assert.Equal(t, key.ID == active.ID, key.Active, "unexpected active flag of key %s", key.ID)
+	}
+
+	bundle, err := CertBundle()
+	if err != nil {
+		t.Fatalf("failed to get cert bundle: %v", err)
+	}
+	n := 0
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		n++
+	}
+	assert.Equal(t, 3, n, "unexpected number of certs in bundle")
+
+	tk, err := makeToken("tester", "harbor-registry", nil)
+	if err != nil {
+		t.Fatalf("failed to make token: %v", err)
+	}
+	parsed, err := jwt.ParseWithClaims(tk.Token, &harborClaims{}, func(tok *jwt.Token) (interface{}, error) {
+		kid, _ := tok.Header["kid"].(string)
+		return getPublicKey(path.Join(keyDir, kid+certSuffix))
+	})
+	if err != nil {
+		t.Fatalf("failed to verify token with the cert of its key: %v", err)
+	}
+	assert.Equal(t, active.ID, parsed.Header["kid"], "token is not signed by the active key")
+
+	assert.NotNil(t, DeleteKey(active.ID), "the active key should not be deleted")
+	assert.Equal(t, ErrKeyNotFound, ActivateKey("unknown"))
+	assert.Equal(t, ErrKeyNotFound, DeleteKey("../active"))
+
+	if err = ActivateKey(standby.ID); err != nil {
+		t.Fatalf("failed to activate key: %v", err)
+	}
+	if err = DeleteKey(active.ID); err != nil {
+		t.Fatalf("failed to delete key: %v", err)
+	}
+	keys, err = ListKeys()
+	if err != nil {
+		t.Fatalf("failed to list keys: %v", err)
+	}
+	assert.Equal(t, 2, len(keys))
+}
+
+func TestPermissionCache(t *testing.T) {
+	c := newPermissionCache(time.Second, 2)
+	if _, ok := c.get("user", "library"); ok {
+		t.Errorf("unexpected hit on empty cache")
+	}
+	c.put("user", "library", "RW")
+	c.put("user", "org/team", "R")
+	perm, ok := c.get("user", "library")
+	assert.True(t, ok)
+	assert.Equal(t, "RW", perm)
+
+	c.put("another", "library", "R")
+	assert.Equal(t, 2, c.metrics().Entries, "the size of cache should be bounded")
+
+	c.put("user", "library", "RW")
+	c.invalidateProjects("org")
+	_, ok = c.get("another", "library")
+	assert.True(t, ok, "the entries of other projects should be kept")
+	c.invalidateProjects("library")
+	_, ok = c.get("another", "library")
+	assert.False(t, ok, "the entry should be invalidated")
+
+	c.put("user", "library", "RW")
+	time.Sleep(1100 * time.Millisecond)
+	_, ok = c.get("user", "library")
+	assert.False(t, ok, "the entry should be expired")
+
+	metrics := c.metrics()
+	assert.Equal(t, int64(2), metrics.Hits)
+	assert.Equal(t, int64(3), metrics.Misses)
+	assert.Equal(t, 1, metrics.Entries)
+	assert.Equal(t, 1, metrics.Projects)
+}
+
+func TestRepositoryFilterCache(t *testing.T) {
+	origCache := permCache
+	permCache = newPermissionCache(time.Minute, 10)
+	parseRepository = func(repository string) (string, string) {
+		return "org/team", strings.TrimPrefix(repository, "org/team/")
+	}
+	defer func() {
+		permCache = origCache
+		parseRepository = dao.ParseRepository
+	}()
+
+	filter := &repositoryFilter{
+		parser: &basicParser{},
+	}
+	user := userInfo{name: "tester"}
+	permCache.put("tester", "org/team", "RW")
+	permCache.put("another", "org/team", "R")
+	a := &token.ResourceActions{
+		Type:    "repository",
+		Name:    "org/team/ubuntu",
+		Actions: []string{"pull", "push"},
+	}
+	if err := filter.filter(user, a); err != nil {
+		t.Fatalf("failed to filter: %v", err)
+	}
+	assert.Equal(t, []string{"push", "pull"}, a.Actions)
+	assert.Equal(t, int64(1), permCache.metrics().Hits, "the permission should be looked up by project")
+
+	InvalidateUserPermissions("tester")
+	_, ok := permCache.get("tester", "org/team")
+	assert.False(t, ok, "the permissions of the user should be invalidated")
+	_, ok = permCache.get("another", "org/team")
+	assert.True(t, ok, "the permissions of other users should be kept")
+}
+
+func TestBuildAudits(t *testing.T) {
+	requested := []*token.ResourceActions{
+		&token.ResourceActions{
+			Type:    "repository",
+			Name:    "library/ubuntu",
+			Actions: []string{"pull", "push"},
+		},
+		&token.ResourceActions{
+			Type:    "repository",
+			Name:    "library/centos",
+			Actions: []string{"pull"},
+		},
+		&token.ResourceActions{
+			Type:    "repository",
+			Name:    "library/busybox",
+			Actions: []string{"push"},
+		},
+	}
+	granted := copyAccess(requested)
+	granted[0].Actions = []string{"pull"}
+	granted[2].Actions = []string{"*", "push", "pull"}
+
+	audits := buildAudits("tester", "10.0.0.1", "harbor-registry", requested, granted)
+	assert.Equal(t, 1, len(audits))
+	assert.Equal(t, "tester", audits[0].Username)
+	assert.Equal(t, "10.0.0.1", audits[0].IP)
+	assert.Equal(t, "repository:library/ubuntu:pull,push", audits[0].Scope)
+	assert.Equal(t, "pull", audits[0].GrantedActions)
+	assert.Equal(t, []string{"pull", "push"}, requested[0].Actions, "the requested access should not be modified")
+}
+
+func TestClientIP(t *testing.T) {
+	req, err := http.NewRequest("GET", "/service/token", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.RemoteAddr = "172.17.0.2:34567"
+	assert.Equal(t, "172.17.0.2", clientIP(req))
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	assert.Equal(t, "10.0.0.1", clientIP(req))
+}
+
+func TestRobotValidatorSkipsUsers(t *testing.T) {
+	req, err := http.NewRequest("GET", "/service/token", nil)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.SetBasicAuth("admin", "Harbor12345")
+	user, err := robotValidator{}.validate(req)
+	assert.Nil(t, err)
+	assert.Nil(t, user, "the users should be left to the basic auth validator")
+
+	req.SetBasicAuth("robot$library+0a1b2c3d", "secret")
+	user, err = basicAuthValidator{}.validate(req)
+	assert.Nil(t, err)
+	assert.Nil(t, user, "the robot accounts should not be validated as users")
+}
+
+func TestRestrict(t *testing.T) {
+	u := userInfo{name: "jack"}
+	assert.Equal(t, "RWM", u.restrict("RWM"))
+	u.readOnly = true
+	assert.Equal(t, "R", u.restrict("RWM"))
+	assert.Equal(t, "R", u.restrict("R"))
+	assert.Equal(t, "", u.restrict("W"))
+	assert.Equal(t, "", u.restrict(""))
+}
@@ -19,14 +19,40 @@ import (
 	"github.com/vmware/harbor/src/common/models"
 	"github.com/vmware/harbor/src/common/utils/log"
 	"github.com/vmware/harbor/src/ui/auth"
+	"github.com/vmware/harbor/src/ui/auth/pat"
+	"github.com/vmware/harbor/src/ui/auth/proxy"
+	"github.com/vmware/harbor/src/ui/auth/robot"
+	"github.com/vmware/harbor/src/ui/config"
 	svc_utils "github.com/vmware/harbor/src/ui/service/utils"
 	"net/http"
+	"strings"
 )
 
 //For filtering permission by token creators.
 type userInfo struct {
 	name    string
 	allPerm bool
+	// robot is set if the requester is a robot account
+	robot *models.Robot
+	// readOnly limits the access to pull, it is set if the user is
+	// authenticated with a read-only personal access token
+	readOnly bool
+	// userID is set if the user is authenticated with the password, only
+	// such users can get refresh tokens
+	userID int
+}
+
+// restrict reduces the permission to pull if the user is read-only, the
+// cached permissions are not restricted as they are shared by the tokens and
+// passwords of the user
+func (u userInfo) restrict(permission string) string {
+	if !u.readOnly {
+		return permission
+	}
+	if strings.Contains(permission, "R") {
+		return "R"
+	}
+	return ""
 }
 
 //ReqValidator validates request based on different rules and returns userInfo
@@ -54,12 +80,90 @@ func (sv secretValidator) validate(r *http.Request) (*userInfo, error) {
 	return nil, nil
 }
 
+// proxyAuthValidator accepts the user in the headers set by a trusted
+// authenticating proxy, if the proxy is allowed to front the token service
+type proxyAuthValidator struct {
+}
+
+func (pa proxyAuthValidator) validate(r *http.Request) (*userInfo, error) {
+	setting, err := config.ProxyAuth()
+	if err != nil {
+		log.Errorf("Error occurred in getting proxy auth setting: %v", err)
+		return nil, err
+	}
+	if !setting.TokenService {
+		return nil, nil
+	}
+	user, changed, err := proxy.UserFromRequest(r)
+	if err != nil {
+		log.Errorf("Error occurred in getting user from proxy headers: %v", err)
+		return nil, err
+	}
+	if user == nil {
+		return nil, nil
+	}
+	if changed {
+		InvalidateUserPermissions(user.Username)
+	}
+	isAdmin, err := dao.IsAdminRole(user.UserID)
+	if err != nil {
+		log.Errorf("Error occurred in IsAdminRole: %v", err)
+	}
+	return &userInfo{
+		name:    user.Username,
+		allPerm: isAdmin,
+	}, nil
+}
+
+// robotValidator accepts the robot accounts of projects in the basic auth
+// header, the names of other accounts are left to basicAuthValidator
+type robotValidator struct {
+}
+
+func (rv robotValidator) validate(r *http.Request) (*userInfo, error) {
+	name, secret, _ := r.BasicAuth()
+	if !robot.IsRobot(name) {
+		return nil, nil
+	}
+	rb, err := robot.Authenticate(name, secret)
+	if err != nil {
+		log.Errorf("Error occurred in authenticating robot account %s: %v", name, err)
+		return nil, err
+	}
+	if rb == nil {
+		return nil, nil
+	}
+	return &userInfo{
+		name:  rb.Name,
+		robot: rb,
+	}, nil
+}
+
 type basicAuthValidator struct {
 }
 
 func (ba basicAuthValidator) validate(r *http.Request) (*userInfo, error) {
 	uid, password, _ := r.BasicAuth()
-	user, err := auth.Login(models.AuthModel{
+	if robot.IsRobot(uid) {
+		return nil, nil
+	}
+	user, token, err := pat.Authenticate(uid, password)
+	if err != nil {
+		log.Errorf("Error occurred in authenticating personal access token of %s: %v", uid, err)
+		return nil, err
+	}
+	if user != nil {
+		isAdmin, err := dao.IsAdminRole(user.UserID)
+		if err != nil {
+			log.Errorf("Error occurred in IsAdminRole: %v", err)
+		}
+		return &userInfo{
+			name:     user.Username,
+			allPerm:  isAdmin,
+			readOnly: token.ReadOnly(),
+		}, nil
+	}
+	user, err = auth.LoginCLI(models.AuthModel{
 		Principal: uid,
 		Password:  password,
 	})
@@ -78,6 +182,7 @@ func (ba basicAuthValidator) validate(r *http.Request) (*userInfo, error) {
 	info := &userInfo{
 		name:    user.Username,
 		allPerm: isAdmin,
+		userID:  user.UserID,
 	}
 	return info, nil
 }
@@ -139,4 +139,162 @@ class Repository(Base):
     creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
     update_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
 
+class ReplicationDriftReport(Base):
+    __tablename__ = "replication_drift_report"
 
+    id = sa.Column(sa.Integer, primary_key=True)
+    policy_id = sa.Column(sa.Integer, nullable=False)
+    items = sa.Column(mysql.LONGTEXT)
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('drift_policy', "policy_id"),)
+
+class JobEvent(Base):
+    __tablename__ = "job_event"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    job_id = sa.Column(sa.Integer, nullable=False)
+    from_state = sa.Column(sa.String(64), nullable=False)
+    to_state = sa.Column(sa.String(64), nullable=False)
+    message = sa.Column(sa.String(1024))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('job', "job_id"),)
+
+class Job(Base):
+    __tablename__ = "job"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    kind = sa.Column(sa.String(64), nullable=False)
+    status = sa.Column(sa.String(64), nullable=False)
+    parameters = sa.Column(sa.Text)
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    update_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('kind_status', "kind", "status"),)
+
+class RefreshToken(Base):
+    __tablename__ = "refresh_token"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    user_id = sa.Column(sa.Integer, nullable=False)
+    token_hash = sa.Column(sa.String(64), nullable=False, unique=True)
+    client_id = sa.Column(sa.String(256))
+    service = sa.Column(sa.String(256))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    last_used_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('refresh_token_user', "user_id"),)
+
+class TokenAudit(Base):
+    __tablename__ = "token_audit"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    username = sa.Column(sa.String(255))
+    ip = sa.Column(sa.String(64))
+    service = sa.Column(sa.String(256))
+    scope = sa.Column(sa.String(1024))
+    granted_actions = sa.Column(sa.String(256))
+    op_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('token_audit_optime', "op_time"),)
+
+class OIDCUser(Base):
+    __tablename__ = "oidc_user"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    user_id = sa.Column(sa.Integer, nullable=False, unique=True)
+    issuer = sa.Column(sa.String(255), nullable=False)
+    subject = sa.Column(sa.String(255), nullable=False)
+    secret_hash = sa.Column(sa.String(64))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    update_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('oidc_user_subject', "subject"),)
+
+class AccountLockout(Base):
+    __tablename__ = "account_lockout"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    username = sa.Column(sa.String(255), nullable=False, unique=True)
+    failures = sa.Column(sa.Integer, nullable=False, server_default=sa.text("'0'"))
+    first_failure = sa.Column(sa.DateTime, nullable=False)
+    locked_until = sa.Column(sa.DateTime, nullable=False)
+
+class LockoutAudit(Base):
+    __tablename__ = "lockout_audit"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    username = sa.Column(sa.String(255))
+    operation = sa.Column(sa.String(32))
+    operator = sa.Column(sa.String(255))
+    op_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('lockout_audit_optime', "op_time"),)
+
+class UserMFA(Base):
+    __tablename__ = "user_mfa"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    user_id = sa.Column(sa.Integer, nullable=False, unique=True)
+    secret = sa.Column(sa.String(255), nullable=False)
+    enabled = sa.Column(mysql.TINYINT(1), nullable=False, server_default=sa.text("'0'"))
+    last_step = sa.Column(sa.BigInteger, nullable=False, server_default=sa.text("'0'"))
+    recovery_codes = sa.Column(sa.String(1024))
+    cli_secret_hash = sa.Column(sa.String(64))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    update_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
+
+class PasswordHistory(Base):
+    __tablename__ = "password_history"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    user_id = sa.Column(sa.Integer, nullable=False)
+    password_hash = sa.Column(sa.String(128), nullable=False)
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('password_history_user', "user_id"),)
+
+class Robot(Base):
+    __tablename__ = "robot"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    name = sa.Column(sa.String(255), nullable=False, unique=True)
+    description = sa.Column(sa.String(1024))
+    project_id = sa.Column(sa.Integer, nullable=False)
+    secret_hash = sa.Column(sa.String(64), nullable=False)
+    access = sa.Column(sa.String(16), nullable=False)
+    creator_id = sa.Column(sa.Integer, nullable=False)
+    expires_at = sa.Column(sa.BigInteger, nullable=False, server_default=sa.text("'0'"))
+    revoked = sa.Column(mysql.TINYINT(1), nullable=False, server_default=sa.text("'0'"))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    update_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('robot_project', "project_id"),)
+
+class AccessToken(Base):
+    __tablename__ = "access_token"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    user_id = sa.Column(sa.Integer, nullable=False)
+    name = sa.Column(sa.String(255), nullable=False)
+    token_hash = sa.Column(sa.String(64), nullable=False, unique=True)
+    scope = sa.Column(sa.String(16), nullable=False)
+    expires_at = sa.Column(sa.BigInteger, nullable=False, server_default=sa.text("'0'"))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    last_used_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.UniqueConstraint("user_id", "name"),)
+
+class UserSession(Base):
+    __tablename__ = "user_session"
+
+    id = sa.Column(sa.Integer, primary_key=True)
+    user_id = sa.Column(sa.Integer, nullable=False)
+    session_hash = sa.Column(sa.String(64), nullable=False, unique=True)
+    ip = sa.Column(sa.String(64))
+    user_agent = sa.Column(sa.String(512))
+    creation_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+    last_seen_time = sa.Column(mysql.TIMESTAMP, server_default = sa.text("CURRENT_TIMESTAMP"))
+
+    __table_args__ = (sa.Index('user_session_user', "user_id"),)
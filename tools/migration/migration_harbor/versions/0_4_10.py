@@ -0,0 +1,49 @@
+# Copyright (c) 2008-2016 VMware, Inc. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""0.4.9 to 0.4.10
+
+Revision ID: 0.4.10
+Revises: 0.4.9
+
+"""
+
+# revision identifiers, used by Alembic.
+revision = '0.4.10'
+down_revision = '0.4.9'
+branch_labels = None
+depends_on = None
+
+from alembic import op
+from db_meta import *
+
+from sqlalchemy.dialects import mysql
+
+def upgrade():
+    """
+    update schema&data
+    """
+    bind = op.get_bind()
+    #alter column user.password and tenx_users.password, the pbkdf2 hashes are longer than md5 ones
+    op.alter_column('user', 'password', type_=sa.String(128), existing_type=sa.String(40), existing_nullable=False)
+    #tenx_users is shared with other services, keep its nullability and never narrow it
+    for column in sa.inspect(bind).get_columns('tenx_users'):
+        if column['name'] == 'password' and (getattr(column['type'], 'length', None) or 0) < 128:
+            op.alter_column('tenx_users', 'password', type_=sa.String(128), existing_type=column['type'], existing_nullable=column['nullable'])
+
+def downgrade():
+    """
+    Downgrade has been disabled.
+    """
+    pass
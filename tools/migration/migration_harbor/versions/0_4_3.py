@@ -0,0 +1,51 @@
+# Copyright (c) 2008-2016 VMware, Inc. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""0.4.2 to 0.4.3
+
+Revision ID: 0.4.3
+Revises: 0.4.2
+
+"""
+
+# revision identifiers, used by Alembic.
+revision = '0.4.3'
+down_revision = '0.4.2'
+branch_labels = None
+depends_on = None
+
+from alembic import op
+from db_meta import *
+
+from sqlalchemy.dialects import mysql
+
+def upgrade():
+    """
+    update schema&data
+    """
+    bind = op.get_bind()
+    #create tables: job
+    Job.__table__.create(bind)
+    #add column job_event.kind, the events recorded before are all of replication jobs
+    op.add_column('job_event', sa.Column('kind', sa.String(64), nullable=False, server_default=sa.text("'replication'")))
+    op.alter_column('job_event', 'kind', server_default=None, existing_type=sa.String(64), existing_nullable=False)
+    #replace index job (job_id) with kind_job (kind, job_id) on table job_event
+    op.drop_index('job', 'job_event')
+    op.create_index('kind_job', 'job_event', ['kind', 'job_id'])
+
+def downgrade():
+    """
+    Downgrade has been disabled.
+    """
+    pass
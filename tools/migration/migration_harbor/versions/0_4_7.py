@@ -0,0 +1,60 @@
+# Copyright (c) 2008-2016 VMware, Inc. All Rights Reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+"""0.4.6 to 0.4.7
+
+Revision ID: 0.4.7
+Revises: 0.4.6
+
+"""
+
+# revision identifiers, used by Alembic.
+revision = '0.4.7'
+down_revision = '0.4.6'
+branch_labels = None
+depends_on = None
+
+import os
+
+from alembic import op
+from db_meta import *
+
+from sqlalchemy.dialects import mysql
+
+def upgrade():
+    """
+    update schema&data
+    """
+    bind = op.get_bind()
+    #add column tenx_users.auth_source, the users are authenticated by the auth chain
+    try:
+        op.add_column('tenx_users', sa.Column('auth_source', sa.String(20)))
+    except Exception as e:
+        if str(e).find("Duplicate column") >=0:
+            print "ignore dup column error for auth_source"
+        else:
+            raise e
+    #backfill the auth source, the admin is always authenticated against the database,
+    #the others by the auth_mode in effect before the upgrade, which is passed in by
+    #AUTH_MODE. The UI backfills the rest with the configured auth_mode on startup.
+    op.execute("update tenx_users set auth_source = 'db_auth' where user_id = 1 and (auth_source is null or auth_source = '')")
+    auth_mode = os.environ.get("AUTH_MODE", "")
+    if auth_mode in ("db_auth", "ldap_auth"):
+        bind.execute(sa.text("update tenx_users set auth_source = :mode where auth_source is null or auth_source = ''"), mode=auth_mode)
+
+def downgrade():
+    """
+    Downgrade has been disabled.
+    """
+    pass